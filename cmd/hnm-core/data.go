@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/AMathur20/Home_Network/internal/storage"
+)
+
+// runExport writes every table of the database to a directory, e.g. for a
+// backup or to analyse the data elsewhere.
+func runExport(args []string) int {
+	var dbPath string
+	flags := newFlagSet("export", "[flags] DIR")
+	addDBFlag(flags, &dbPath)
+	format := flags.String("format", storage.FormatParquet, "file format, parquet or csv")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	store, err := openStore(dbPath, false)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer store.Close()
+	counts, err := store.Export(flags.Arg(0), *format)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	printCounts("Exported", counts)
+	return 0
+}
+
+// runImport appends the tables in a directory written by export to the
+// database.
+func runImport(args []string) int {
+	var dbPath string
+	flags := newFlagSet("import", "[flags] DIR")
+	addDBFlag(flags, &dbPath)
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	store, err := openStore(dbPath, true)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer store.Close()
+	counts, err := store.Import(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	printCounts("Imported", counts)
+	return 0
+}
+
+// runDB runs database maintenance. "db migrate" brings the schema of the
+// database up to date, which hnm-core otherwise does when it starts serving.
+func runDB(args []string) int {
+	var dbPath string
+	flags := newFlagSet("db", "migrate [flags]")
+	addDBFlag(flags, &dbPath)
+	if len(args) == 0 || args[0] != "migrate" {
+		flags.Usage()
+		return 2
+	}
+	flags.Parse(args[1:])
+	if flags.NArg() != 0 {
+		flags.Usage()
+		return 2
+	}
+
+	store, err := openStore(dbPath, true)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer store.Close()
+	tables, err := store.Tables()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Printf("Schema of %s is up to date (%d tables)\n", dbPath, len(tables))
+	return 0
+}
+
+// openStore opens the database, creating it and its directory if create is
+// set. Opening it brings its schema up to date.
+func openStore(path string, create bool) (*storage.DuckDBStorage, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) && !create {
+		return nil, fmt.Errorf("database %s does not exist", path)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	store, err := storage.NewDuckDBStorage(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %v", path, err)
+	}
+	return store, nil
+}
+
+func printCounts(verb string, counts map[string]int64) {
+	tables := make([]string, 0, len(counts))
+	for t := range counts {
+		tables = append(tables, t)
+	}
+	sort.Strings(tables)
+	for _, t := range tables {
+		fmt.Printf("%s %d rows of %s\n", verb, counts[t], t)
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/AMathur20/Home_Network/internal/topology"
+	"gopkg.in/yaml.v3"
+)
+
+// runDiscover crawls the devices in config.yaml and prints the topology found,
+// or merges it into topology.yaml with -write.
+func runDiscover(args []string) int {
+	var configPath, topoPath string
+	flags := newFlagSet("discover", "[flags]")
+	addConfigFlag(flags, &configPath)
+	addTopologyFlag(flags, &topoPath)
+	write := flags.Bool("write", false, "merge the discovered links into topology.yaml instead of printing them")
+	asJSON := flags.Bool("json", false, "print JSON instead of YAML")
+	flags.Parse(args)
+	if flags.NArg() != 0 {
+		flags.Usage()
+		return 2
+	}
+
+	cfg, _, err := loadConfig(configPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	classifier, err := topology.NewClassifier(cfg.Classification)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: classification: %v\n", configPath, err)
+		return 1
+	}
+	crawler := topology.NewCrawler(cfg.Devices, classifier)
+	crawler.SetCredentials(cfg.Profiles(cfg.Discovery.Credentials))
+	discovered, err := crawler.Discover()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Discovery failed: %v\n", err)
+		return 1
+	}
+
+	if *write {
+		// Same as at startup: a new file gets the discovered links, an
+		// existing one keeps its nodes and manual edits.
+		current, err := topology.LoadTopology(topoPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to load topology: %v\n", err)
+			return 1
+		}
+		if len(current.Links) == 0 {
+			discovered.Nodes = current.Nodes
+			err = topology.SaveTopology(topoPath, discovered)
+		} else {
+			discovered = topology.MergeTopology(current, discovered)
+			err = topology.UpdateTopologyFile(topoPath, discovered)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to save topology: %v\n", err)
+			return 1
+		}
+		log.Printf("Topology now has %d links, saved to %s", len(discovered.Links), topoPath)
+		return 0
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(discovered)
+	} else {
+		enc := yaml.NewEncoder(os.Stdout)
+		enc.SetIndent(2)
+		err = enc.Encode(discovered)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
@@ -1,131 +1,100 @@
 package main
 
 import (
+	"flag"
+	"fmt"
 	"log"
-	"net/http"
 	"os"
-	"path/filepath"
+	"strings"
 
-	"github.com/AMathur20/Home_Network/internal/api"
 	"github.com/AMathur20/Home_Network/internal/config"
-	"github.com/AMathur20/Home_Network/internal/poller"
-	"github.com/AMathur20/Home_Network/internal/storage"
-	"github.com/AMathur20/Home_Network/internal/topology"
-
-	"os/signal"
-	"syscall"
+	"github.com/AMathur20/Home_Network/internal/models"
 )
 
-func main() {
-	log.Println("Starting Home Network Monitor (HNM) v2...")
+type command struct {
+	name  string
+	usage string
+	run   func(args []string) int
+}
+
+// commands are the subcommands of hnm-core. Without one it serves.
+var commands = []command{
+	{"serve", "Run the monitor, API and web UI (the default)", runServe},
+	{"discover", "Crawl the network and print or write the topology", runDiscover},
+	{"poll-once", "Poll one device and print its interface metrics", runPollOnce},
+	{"validate", "Check config.yaml and topology.yaml without starting HNM", runValidate},
+	{"export", "Write every database table to Parquet or CSV files", runExport},
+	{"import", "Load tables written by export into the database", runImport},
+	{"db", "Database maintenance: db migrate", runDB},
+}
 
-	// 1. Environment Variables & Paths
-	configPath := os.Getenv("HNM_CONFIG_PATH")
-	if configPath == "" {
-		configPath = "config/config.yaml"
+func main() {
+	args := os.Args[1:]
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		os.Exit(runServe(args))
 	}
-	dbPath := os.Getenv("HNM_DB_PATH")
-	if dbPath == "" {
-		dbPath = "data/hnm.db"
+	for _, c := range commands {
+		if c.name == args[0] {
+			os.Exit(c.run(args[1:]))
+		}
 	}
-	topoPath := "config/topology.yaml" // Typically in the same config dir
-	uiPath := os.Getenv("HNM_UI_PATH")
-	if uiPath == "" {
-		uiPath = "ui/dist"
+	if args[0] != "help" {
+		fmt.Fprintf(os.Stderr, "hnm-core: unknown command %q\n\n", args[0])
 	}
-
-	// Ensure data directory exists
-	dataDir := filepath.Dir(dbPath)
-	if err := os.MkdirAll(dataDir, 0755); err != nil {
-		log.Fatalf("Failed to create data directory: %v", err)
+	usage()
+	if args[0] != "help" {
+		os.Exit(2)
 	}
+}
 
-	// 2. Load Configuration
-	cfg, err := config.LoadConfig(configPath)
-	if err != nil {
-		log.Fatalf("Failed to load config: %v", err)
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: hnm-core [command] [flags]")
+	fmt.Fprintln(os.Stderr, "\nCommands:")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", c.name, c.usage)
 	}
-	log.Printf("Configuration loaded from %s", configPath)
-
-	// 3. Initialize Storage (DuckDB)
-	store, err := storage.NewDuckDBStorage(dbPath)
-	if err != nil {
-		log.Fatalf("Failed to initialize storage: %v", err)
-	}
-	defer store.Close()
-	log.Printf("Storage initialized at %s", dbPath)
+	fmt.Fprintln(os.Stderr, "\nRun hnm-core <command> -h for the flags of a command. Flags override the\nenvironment variables named in their help.")
+}
 
-	// 4. Load Topology
-	topo, err := topology.LoadTopology(topoPath)
-	if err != nil {
-		log.Fatalf("Failed to load topology: %v", err)
+func newFlagSet(name, args string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: hnm-core %s %s\n\nFlags:\n", name, args)
+		flags.PrintDefaults()
 	}
+	return flags
+}
 
-	// Trigger Auto-Discovery if topology is empty
-	if len(topo.Links) == 0 {
-		log.Println("Topology is empty. Running auto-discovery...")
-		crawler := topology.NewCrawler(cfg.Devices)
-		discoveredTopo, err := crawler.Discover()
-		if err != nil {
-			log.Printf("Auto-discovery failed: %v", err)
-		} else {
-			topo = discoveredTopo
-			if err := topology.SaveTopology(topoPath, topo); err != nil {
-				log.Printf("Failed to save discovered topology: %v", err)
-			} else {
-				log.Printf("Auto-discovery complete. Discovered %d links and saved to %s", len(topo.Links), topoPath)
-			}
-		}
-	} else {
-		log.Printf("Topology loaded with %d links", len(topo.Links))
+func envOr(name, fallback string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
 	}
+	return fallback
+}
 
-	// 5. Initialize Polling Engine
-	engine := poller.NewPollingEngine(cfg, store, topo)
-
-	// 6. Graceful Shutdown Setup
-	stop := make(chan os.Signal, 1)
-	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
-
-	go func() {
-		sig := <-stop
-		log.Printf("Received signal %v. Shutting down gracefully...", sig)
-		store.Close()
-		log.Println("Storage closed. Exiting.")
-		os.Exit(0)
-	}()
-
-	// 7. Watch Topology for Hot-Reload
-	topology.WatchTopology(topoPath, func() {
-		newTopo, err := topology.LoadTopology(topoPath)
-		if err != nil {
-			log.Printf("Error reloading topology: %v", err)
-			return
-		}
-		engine.ReloadTopology(newTopo)
-	})
-
-	// 7. Start Polling Engine
-	go engine.Start()
-
-	// 8. Setup HTTP Server
-	handler := api.NewAPIHandler(topoPath, store)
+// Path flags default to the environment variables HNM has always read.
 
-	http.HandleFunc("/api/topology", handler.GetTopology)
-	http.HandleFunc("/api/metrics/live", handler.GetLiveMetrics)
-	http.HandleFunc("/api/metrics/history", handler.GetMetricHistory)
+func addConfigFlag(flags *flag.FlagSet, path *string) {
+	flags.StringVar(path, "config", envOr("HNM_CONFIG_PATH", "config/config.yaml"), "path to config.yaml (HNM_CONFIG_PATH)")
+}
 
-	// Serve Static UI Files
-	fs := http.FileServer(http.Dir(uiPath))
-	http.Handle("/", fs)
+func addTopologyFlag(flags *flag.FlagSet, path *string) {
+	flags.StringVar(path, "topology", envOr("HNM_TOPOLOGY_PATH", "config/topology.yaml"), "path to topology.yaml (HNM_TOPOLOGY_PATH)")
+}
 
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
-	}
+func addDBFlag(flags *flag.FlagSet, path *string) {
+	flags.StringVar(path, "db", envOr("HNM_DB_PATH", "data/hnm.db"), "path to the DuckDB database (HNM_DB_PATH)")
+}
 
-	log.Printf("Server listening on port %s", port)
-	if err := http.ListenAndServe(":"+port, nil); err != nil {
-		log.Fatalf("Server failed: %v", err)
+// loadConfig loads config.yaml and masks its credentials in everything logged
+// from then on.
+func loadConfig(path string) (*models.Config, *config.Redactor, error) {
+	cfg, err := config.LoadConfig(path)
+	if err != nil {
+		return nil, nil, err
 	}
+	redactor := config.NewRedactor(os.Stderr)
+	redactor.SetConfig(cfg)
+	log.SetOutput(redactor)
+	return cfg, redactor, nil
 }
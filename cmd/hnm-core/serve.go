@@ -0,0 +1,300 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/AMathur20/Home_Network/internal/api"
+	"github.com/AMathur20/Home_Network/internal/config"
+	"github.com/AMathur20/Home_Network/internal/flows"
+	"github.com/AMathur20/Home_Network/internal/models"
+	"github.com/AMathur20/Home_Network/internal/notify"
+	"github.com/AMathur20/Home_Network/internal/oui"
+	"github.com/AMathur20/Home_Network/internal/poller"
+	"github.com/AMathur20/Home_Network/internal/probe"
+	"github.com/AMathur20/Home_Network/internal/security"
+	"github.com/AMathur20/Home_Network/internal/storage"
+	"github.com/AMathur20/Home_Network/internal/syslog"
+	"github.com/AMathur20/Home_Network/internal/topology"
+	"github.com/AMathur20/Home_Network/internal/traps"
+	"github.com/AMathur20/Home_Network/internal/watch"
+
+	"os/signal"
+	"syscall"
+)
+
+// runServe runs the monitor: polling, discovery, the trap, syslog and flow
+// receivers, and the web UI and API. It is what hnm-core does without a
+// subcommand.
+func runServe(args []string) int {
+	var configPath, topoPath, dbPath, uiPath, port string
+	flags := newFlagSet("serve", "[flags]")
+	addConfigFlag(flags, &configPath)
+	addTopologyFlag(flags, &topoPath)
+	addDBFlag(flags, &dbPath)
+	flags.StringVar(&uiPath, "ui", envOr("HNM_UI_PATH", "ui/dist"), "directory of the web UI (HNM_UI_PATH)")
+	flags.StringVar(&port, "port", envOr("PORT", "8080"), "HTTP port (PORT)")
+	flags.Parse(args)
+	if flags.NArg() != 0 {
+		flags.Usage()
+		return 2
+	}
+
+	log.Println("Starting Home Network Monitor (HNM) v2...")
+
+	// Ensure data directory exists
+	dataDir := filepath.Dir(dbPath)
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		log.Fatalf("Failed to create data directory: %v", err)
+	}
+
+	// 2. Load Configuration
+	cfg, redactor, err := loadConfig(configPath)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	log.Printf("Configuration loaded from %s", configPath)
+
+	if cfg.Endpoints.OUIFile != "" {
+		if err := oui.Load(cfg.Endpoints.OUIFile); err != nil {
+			log.Printf("Failed to load OUI database %s: %v", cfg.Endpoints.OUIFile, err)
+		}
+	}
+
+	// 3. Initialize Storage (DuckDB)
+	store, err := storage.NewDuckDBStorage(dbPath)
+	if err != nil {
+		log.Fatalf("Failed to initialize storage: %v", err)
+	}
+	defer store.Close()
+	log.Printf("Storage initialized at %s", dbPath)
+
+	classifier, err := topology.NewClassifier(cfg.Classification)
+	if err != nil {
+		log.Fatalf("Invalid link classification rules: %v", err)
+	}
+	crawler := topology.NewCrawler(cfg.Devices, classifier)
+	crawler.SetCredentials(cfg.Profiles(cfg.Discovery.Credentials))
+
+	// 4. Load Topology
+	topo, err := topology.LoadTopology(topoPath)
+	if err != nil {
+		log.Fatalf("Failed to load topology: %v", err)
+	}
+
+	// Trigger Auto-Discovery if topology is empty
+	if len(topo.Links) == 0 {
+		log.Println("Topology is empty. Running auto-discovery...")
+		discoveredTopo, err := crawler.Discover()
+		if err != nil {
+			log.Printf("Auto-discovery failed: %v", err)
+		} else {
+			discoveredTopo.Nodes = topo.Nodes
+			topo = discoveredTopo
+			if err := topology.SaveTopology(topoPath, topo); err != nil {
+				log.Printf("Failed to save discovered topology: %v", err)
+			} else {
+				log.Printf("Auto-discovery complete. Discovered %d links and saved to %s", len(topo.Links), topoPath)
+			}
+		}
+	} else {
+		log.Printf("Topology loaded with %d links", len(topo.Links))
+
+		if cfg.Discovery.Mode == models.DiscoveryModeMerge {
+			log.Println("Discovery mode is merge. Re-running discovery...")
+			discoveredTopo, err := crawler.Discover()
+			if err != nil {
+				log.Printf("Re-discovery failed: %v", err)
+			} else {
+				topo = topology.MergeTopology(topo, discoveredTopo)
+				if err := topology.UpdateTopologyFile(topoPath, topo); err != nil {
+					log.Printf("Failed to save merged topology: %v", err)
+				} else {
+					log.Printf("Re-discovery complete. Topology now has %d links", len(topo.Links))
+				}
+			}
+		}
+	}
+
+	// 5. Initialize Polling Engine
+	engine := poller.NewPollingEngine(cfg, store, topo)
+
+	var notifier notify.Notifier
+	if cfg.Notifications.Webhook != "" {
+		notifier = notify.NewWebhook(cfg.Notifications.Webhook)
+	}
+	knownPath := cfg.Security.KnownDevices
+	if knownPath == "" {
+		knownPath = "known_devices.yaml"
+	}
+	if !filepath.IsAbs(knownPath) {
+		knownPath = filepath.Join(filepath.Dir(configPath), knownPath)
+	}
+	known, err := security.LoadKnownDevices(knownPath)
+	if err != nil {
+		log.Fatalf("Failed to load known devices from %s: %v", knownPath, err)
+	}
+	engine.SetDetector(security.NewDetector(cfg.Security, known, store, notifier))
+	log.Printf("Loaded %d known devices from %s", len(known), knownPath)
+
+	// 6. Graceful Shutdown Setup
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+
+	go func() {
+		sig := <-stop
+		log.Printf("Received signal %v. Shutting down gracefully...", sig)
+		store.Close()
+		log.Println("Storage closed. Exiting.")
+		os.Exit(0)
+	}()
+
+	// 7. Start Polling Engine
+	go engine.Start()
+
+	// Components that pick up a reloaded config.yaml
+	reloaders := []func(*models.Config){
+		redactor.SetConfig,
+		engine.Reload,
+		func(c *models.Config) {
+			crawler.SetDevices(c.Devices)
+			crawler.SetCredentials(c.Profiles(c.Discovery.Credentials))
+		},
+	}
+
+	// Reachability Probes
+	if !cfg.Probes.Disabled {
+		prober := probe.NewProber(cfg, store)
+		reloaders = append(reloaders, prober.Reload)
+		go prober.Start()
+	}
+
+	// Scheduled Rediscovery
+	rediscovery := topology.NewRediscovery(topoPath, crawler, cfg.Discovery, store)
+	go rediscovery.Start()
+
+	// SNMP Trap Receiver
+	if cfg.Traps.Enabled {
+		receiver, err := traps.NewReceiver(cfg.Traps, cfg.Devices, store)
+		if err != nil {
+			log.Fatalf("Invalid trap receiver config: %v", err)
+		}
+		reloaders = append(reloaders, func(c *models.Config) { receiver.SetDevices(c.Devices) })
+		receiver.Repoll = engine.PollNow
+		receiver.Rediscover = func() {
+			if err := rediscovery.RunOnce(); err != nil {
+				log.Printf("Trap-triggered rediscovery failed: %v", err)
+			}
+		}
+		go func() {
+			if err := receiver.Start(); err != nil {
+				log.Printf("SNMP trap receiver stopped: %v", err)
+			}
+		}()
+	}
+
+	// Syslog Receiver
+	if cfg.Syslog.Enabled {
+		syslogServer := syslog.NewServer(cfg.Syslog, cfg.Devices, store)
+		reloaders = append(reloaders, func(c *models.Config) { syslogServer.SetDevices(c.Devices) })
+		go func() {
+			if err := syslogServer.Start(); err != nil {
+				log.Printf("Syslog receiver stopped: %v", err)
+			}
+		}()
+	}
+
+	// Flow Collector
+	if cfg.Flows.Enabled {
+		collector := flows.NewCollector(cfg.Flows, cfg.Devices, store)
+		reloaders = append(reloaders, func(c *models.Config) { collector.SetDevices(c.Devices) })
+		go func() {
+			if err := collector.Start(); err != nil {
+				log.Printf("Flow collector stopped: %v", err)
+			}
+		}()
+	}
+
+	// 8. Setup HTTP Server
+	handler := api.NewAPIHandler(cfg, topoPath, store, rediscovery, engine)
+	reloaders = append(reloaders, handler.SetConfig)
+
+	// Watch Config and Topology for Hot-Reload. A file that fails to load or
+	// validate is rejected and reported via the API; the running config or
+	// topology stays in effect.
+	configStatus := watch.NewStatus(configPath)
+	handler.AddReloadStatus(configStatus)
+	err = watch.File(configPath, watch.Delay, func() {
+		newCfg, err := config.LoadConfig(configPath)
+		if err != nil {
+			log.Printf("Rejected config %s, keeping the running config: %v", configPath, err)
+			configStatus.Failed(err)
+			return
+		}
+		for _, reload := range reloaders {
+			reload(newCfg)
+		}
+		restart := config.RestartRequired(cfg, newCfg)
+		configStatus.Loaded(restart)
+		if len(restart) > 0 {
+			log.Printf("Config reloaded; changes to %s take effect after a restart", strings.Join(restart, ", "))
+		} else {
+			log.Println("Config reloaded.")
+		}
+	})
+	if err != nil {
+		log.Printf("Warning: Could not watch config file %s: %v", configPath, err)
+	}
+
+	topoStatus := watch.NewStatus(topoPath)
+	handler.AddReloadStatus(topoStatus)
+	err = watch.File(topoPath, watch.Delay, func() {
+		newTopo, err := topology.Reload(topoPath)
+		if err != nil {
+			log.Printf("Rejected topology %s, keeping the running topology: %v", topoPath, err)
+			topoStatus.Failed(err)
+			return
+		}
+		engine.ReloadTopology(newTopo)
+		topoStatus.Loaded(nil)
+	})
+	if err != nil {
+		log.Printf("Warning: Could not watch topology file %s: %v", topoPath, err)
+	}
+
+	http.HandleFunc("/api/topology", handler.GetTopology)
+	http.HandleFunc("/api/topology/changes", handler.GetTopologyChanges)
+	http.HandleFunc("/api/topology/changes/approve", handler.ApproveTopologyChanges)
+	http.HandleFunc("/api/topology/changes/reject", handler.RejectTopologyChanges)
+	http.HandleFunc("/api/reload", handler.GetReloadStatus)
+	http.HandleFunc("/api/config", handler.GetConfig)
+	http.HandleFunc("/api/metrics/live", handler.GetLiveMetrics)
+	http.HandleFunc("/api/metrics/history", handler.GetMetricHistory)
+	http.HandleFunc("/api/devices/health", handler.GetDeviceHealth)
+	http.HandleFunc("/api/events", handler.GetEvents)
+	http.HandleFunc("/api/endpoints", handler.GetEndpoints)
+	http.HandleFunc("/api/wireless/clients", handler.GetWirelessClients)
+	http.HandleFunc("/api/wireless/clients/history", handler.GetWirelessClientHistory)
+	http.HandleFunc("/api/wireless/aps", handler.GetWirelessInterfaces)
+	http.HandleFunc("/api/optics", handler.GetOptics)
+	http.HandleFunc("/api/optics/history", handler.GetOpticsHistory)
+	http.HandleFunc("/api/poe", handler.GetPoE)
+	http.HandleFunc("/api/poe/history", handler.GetPoEHistory)
+	http.HandleFunc("/api/probes", handler.GetProbes)
+	http.HandleFunc("/api/probes/history", handler.GetProbeHistory)
+	http.HandleFunc("/api/logs", handler.GetLogs)
+	http.HandleFunc("/api/timeline", handler.GetTimeline)
+	http.HandleFunc("/api/flows/top", handler.GetTopFlows)
+
+	// Serve Static UI Files
+	fs := http.FileServer(http.Dir(uiPath))
+	http.Handle("/", fs)
+
+	log.Printf("Server listening on port %s", port)
+	err = http.ListenAndServe(":"+port, nil)
+	log.Printf("Server failed: %v", err)
+	return 1
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/AMathur20/Home_Network/internal/config"
+	"github.com/AMathur20/Home_Network/internal/topology"
+)
+
+// runValidate checks config.yaml and topology.yaml offline, without touching
+// the database or the network. The paths may also be given as arguments, as
+// in hnm-core validate config.yaml topology.yaml.
+func runValidate(args []string) int {
+	var configPath, topoPath string
+	flags := newFlagSet("validate", "[flags] [CONFIG [TOPOLOGY]]")
+	addConfigFlag(flags, &configPath)
+	addTopologyFlag(flags, &topoPath)
+	flags.Parse(args)
+	switch flags.NArg() {
+	case 2:
+		topoPath = flags.Arg(1)
+		fallthrough
+	case 1:
+		configPath = flags.Arg(0)
+	case 0:
+	default:
+		flags.Usage()
+		return 2
+	}
+	status := 0
+
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		status = 1
+	} else if _, err := topology.NewClassifier(cfg.Classification); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: classification: %v\n", configPath, err)
+		status = 1
+	} else {
+		fmt.Printf("%s: OK (%d devices)\n", configPath, len(cfg.Devices))
+	}
+
+	if _, err := os.Stat(topoPath); os.IsNotExist(err) {
+		fmt.Printf("%s: not found, it will be created by discovery\n", topoPath)
+		return status
+	}
+	topo, err := topology.Reload(topoPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Printf("%s: OK (%d nodes, %d links)\n", topoPath, len(topo.Nodes), len(topo.Links))
+	return status
+}
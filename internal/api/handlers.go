@@ -2,23 +2,84 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/AMathur20/Home_Network/internal/config"
+	"github.com/AMathur20/Home_Network/internal/flows"
 	"github.com/AMathur20/Home_Network/internal/models"
+	"github.com/AMathur20/Home_Network/internal/poller"
+	"github.com/AMathur20/Home_Network/internal/probe"
 	"github.com/AMathur20/Home_Network/internal/storage"
+	"github.com/AMathur20/Home_Network/internal/syslog"
 	"github.com/AMathur20/Home_Network/internal/topology"
+	"github.com/AMathur20/Home_Network/internal/watch"
 )
 
 type APIHandler struct {
-	topoPath string
-	storage  *storage.DuckDBStorage
+	current     atomic.Pointer[models.Config]
+	topoPath    string
+	storage     *storage.DuckDBStorage
+	rediscovery *topology.Rediscovery
+	engine      *poller.PollingEngine
+
+	mu      sync.Mutex
+	reloads []*watch.Status
 }
 
-func NewAPIHandler(topoPath string, s *storage.DuckDBStorage) *APIHandler {
-	return &APIHandler{
-		topoPath: topoPath,
-		storage:  s,
+func NewAPIHandler(cfg *models.Config, topoPath string, s *storage.DuckDBStorage, r *topology.Rediscovery, e *poller.PollingEngine) *APIHandler {
+	h := &APIHandler{
+		topoPath:    topoPath,
+		storage:     s,
+		rediscovery: r,
+		engine:      e,
 	}
+	h.current.Store(cfg)
+	return h
+}
+
+func (h *APIHandler) config() *models.Config {
+	return h.current.Load()
+}
+
+// SetConfig switches the handler to a reloaded config.
+func (h *APIHandler) SetConfig(cfg *models.Config) {
+	h.current.Store(cfg)
+}
+
+// AddReloadStatus reports the reload status of a watched file in
+// GetReloadStatus.
+func (h *APIHandler) AddReloadStatus(s *watch.Status) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.reloads = append(h.reloads, s)
+}
+
+// GetConfig returns the running config, after defaults and secret references
+// are applied, with its credentials masked.
+func (h *APIHandler) GetConfig(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(config.Redact(h.config()))
+}
+
+// GetReloadStatus returns when each watched file was last loaded and, if the
+// latest change was rejected, why.
+func (h *APIHandler) GetReloadStatus(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	statuses := make([]models.ReloadStatus, 0, len(h.reloads))
+	for _, s := range h.reloads {
+		statuses = append(statuses, s.Get())
+	}
+	h.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(statuses)
 }
 
 func (h *APIHandler) GetTopology(w http.ResponseWriter, r *http.Request) {
@@ -27,8 +88,52 @@ func (h *APIHandler) GetTopology(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+
+	// Overlay live spanning-tree state from the poller
+	if metrics, err := h.storage.GetLatestMetrics(); err == nil {
+		states := make(map[string]string)
+		for _, m := range metrics {
+			if m.STPState != "" {
+				states[m.DeviceName+"/"+m.InterfaceName] = m.STPState
+			}
+		}
+		for i, l := range topo.Links {
+			if state, ok := states[l.SourceDevice+"/"+l.SourceInterface]; ok {
+				topo.Links[i].STPState = state
+			}
+		}
+	}
+
+	// Per-VLAN overlay
+	if v := r.URL.Query().Get("vlan"); v != "" {
+		vlan, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, "vlan must be a number", http.StatusBadRequest)
+			return
+		}
+		topo = topo.FilterVLAN(vlan)
+	}
+
+	graph := topology.BuildGraph(topo, h.config().Devices)
+
+	// Show which PoE port powers each node
+	if ports, err := h.storage.GetLatestPoEPorts(); err == nil {
+		delivering := make(map[string]bool)
+		for _, p := range ports {
+			if p.Status == models.PoEStatusDelivering {
+				delivering[p.DeviceName+"/"+p.InterfaceName] = true
+			}
+		}
+		graph.SetPoweredBy(topology.PoweringPorts(topo, delivering))
+	}
+
+	// Mark nodes up or down from the active probes
+	if results, err := h.storage.GetLatestProbeResults(); err == nil {
+		graph.SetReachability(probe.DeviceReachability(results))
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(topo)
+	json.NewEncoder(w).Encode(graph)
 }
 
 func (h *APIHandler) GetLiveMetrics(w http.ResponseWriter, r *http.Request) {
@@ -40,10 +145,49 @@ func (h *APIHandler) GetLiveMetrics(w http.ResponseWriter, r *http.Request) {
 	if metrics == nil {
 		metrics = []models.InterfaceMetric{}
 	}
+
+	// Flag metrics the device's last poll didn't refresh: the device is
+	// failing, or no longer reports that interface.
+	health := make(map[string]models.DeviceHealth)
+	for _, dh := range h.engine.Health() {
+		health[dh.DeviceName] = dh
+	}
+	for i, m := range metrics {
+		dh, ok := health[m.DeviceName]
+		if ok && dh.State != models.DeviceStateUnknown {
+			metrics[i].Stale = dh.State != models.DeviceStateUp || m.Timestamp.Before(dh.LastSuccess)
+		}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(metrics)
 }
 
+// GetDeviceHealth returns the polling state of every device alongside its
+// reachability probes. Devices that aren't polled over SNMP take their state
+// from the probes.
+func (h *APIHandler) GetDeviceHealth(w http.ResponseWriter, r *http.Request) {
+	health := h.engine.Health()
+	if results, err := h.storage.GetLatestProbeResults(); err == nil {
+		reachability := probe.DeviceReachability(results)
+		for i, dh := range health {
+			result, ok := reachability[dh.DeviceName]
+			if !ok {
+				continue
+			}
+			health[i].Probe = result.Status
+			if dh.State == models.DeviceStateUnknown {
+				health[i].State = models.DeviceStateUp
+				if result.Status != "up" {
+					health[i].State = models.DeviceStateUnreachable
+				}
+			}
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(health)
+}
+
 func (h *APIHandler) GetMetricHistory(w http.ResponseWriter, r *http.Request) {
 	device := r.URL.Query().Get("device")
 	iface := r.URL.Query().Get("interface")
@@ -60,3 +204,370 @@ func (h *APIHandler) GetMetricHistory(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(metrics)
 }
+
+func (h *APIHandler) GetEvents(w http.ResponseWriter, r *http.Request) {
+	device := r.URL.Query().Get("device")
+	kind := r.URL.Query().Get("kind")
+	mac := strings.ToLower(r.URL.Query().Get("mac"))
+	events, err := h.storage.GetEvents(device, kind, mac, 100)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if events == nil {
+		events = []models.Event{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(events)
+}
+
+// GetEndpoints searches the endpoint inventory, e.g. ?q=nas to find which
+// switch port a host is plugged into.
+func (h *APIHandler) GetEndpoints(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query().Get("q")
+	device := r.URL.Query().Get("device")
+	vlan := 0
+	if v := r.URL.Query().Get("vlan"); v != "" {
+		var err error
+		if vlan, err = strconv.Atoi(v); err != nil {
+			http.Error(w, "vlan must be a number", http.StatusBadRequest)
+			return
+		}
+	}
+
+	endpoints, err := h.storage.SearchEndpoints(q, device, vlan, 500)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if endpoints == nil {
+		endpoints = []models.Endpoint{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(endpoints)
+}
+
+// GetWirelessClients returns the clients currently associated with each AP.
+// A client counts as current if it was seen in the last two collections.
+func (h *APIHandler) GetWirelessClients(w http.ResponseWriter, r *http.Request) {
+	device := r.URL.Query().Get("device")
+	since := time.Now().Add(-2 * time.Duration(h.config().Poller.History) * time.Second)
+
+	clients, err := h.storage.GetWirelessClients(device, since)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if clients == nil {
+		clients = []models.WirelessClient{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(clients)
+}
+
+// GetWirelessClientHistory returns the signal and AP history of one client,
+// e.g. to see where a device roams to and when its signal degrades.
+func (h *APIHandler) GetWirelessClientHistory(w http.ResponseWriter, r *http.Request) {
+	mac := strings.ToLower(r.URL.Query().Get("mac"))
+	if mac == "" {
+		http.Error(w, "mac parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	clients, err := h.storage.GetWirelessClientHistory(mac, 500)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if clients == nil {
+		clients = []models.WirelessClient{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(clients)
+}
+
+// GetWirelessInterfaces returns client counts and channel state per AP radio.
+func (h *APIHandler) GetWirelessInterfaces(w http.ResponseWriter, r *http.Request) {
+	ifaces, err := h.storage.GetWirelessInterfaces()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if ifaces == nil {
+		ifaces = []models.WirelessInterface{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ifaces)
+}
+
+// GetOptics returns the latest SFP diagnostics and active alarms per port.
+func (h *APIHandler) GetOptics(w http.ResponseWriter, r *http.Request) {
+	metrics, err := h.storage.GetLatestOpticalMetrics()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if metrics == nil {
+		metrics = []models.OpticalMetric{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(metrics)
+}
+
+func (h *APIHandler) GetOpticsHistory(w http.ResponseWriter, r *http.Request) {
+	device := r.URL.Query().Get("device")
+	iface := r.URL.Query().Get("interface")
+	if device == "" || iface == "" {
+		http.Error(w, "device and interface parameters are required", http.StatusBadRequest)
+		return
+	}
+
+	metrics, err := h.storage.GetOpticalHistory(device, iface, 500)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if metrics == nil {
+		metrics = []models.OpticalMetric{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(metrics)
+}
+
+// GetPoE returns the latest state of every PoE port and each device's PSE
+// budget.
+func (h *APIHandler) GetPoE(w http.ResponseWriter, r *http.Request) {
+	ports, err := h.storage.GetLatestPoEPorts()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	budgets, err := h.storage.GetLatestPoEBudgets()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if ports == nil {
+		ports = []models.PoEPort{}
+	}
+	if budgets == nil {
+		budgets = []models.PoEBudget{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"ports":   ports,
+		"budgets": budgets,
+	})
+}
+
+func (h *APIHandler) GetPoEHistory(w http.ResponseWriter, r *http.Request) {
+	device := r.URL.Query().Get("device")
+	iface := r.URL.Query().Get("interface")
+	if device == "" || iface == "" {
+		http.Error(w, "device and interface parameters are required", http.StatusBadRequest)
+		return
+	}
+
+	ports, err := h.storage.GetPoEHistory(device, iface, 500)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if ports == nil {
+		ports = []models.PoEPort{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ports)
+}
+
+// GetProbes returns the latest result of every reachability probe.
+func (h *APIHandler) GetProbes(w http.ResponseWriter, r *http.Request) {
+	results, err := h.storage.GetLatestProbeResults()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if results == nil {
+		results = []models.ProbeResult{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(results)
+}
+
+func (h *APIHandler) GetProbeHistory(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		http.Error(w, "name parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	results, err := h.storage.GetProbeHistory(name, 500)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if results == nil {
+		results = []models.ProbeResult{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(results)
+}
+
+// GetLogs searches syslog messages. since is an RFC 3339 time or a duration
+// such as 1h, and defaults to the last 24 hours.
+func (h *APIHandler) GetLogs(w http.ResponseWriter, r *http.Request) {
+	since, err := parseSince(r, "since", 24*time.Hour)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	logs, err := h.storage.GetLogs(r.URL.Query().Get("device"), r.URL.Query().Get("q"), since, 500)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if logs == nil {
+		logs = []models.LogMessage{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(logs)
+}
+
+// GetTimeline interleaves a device's events and syslog messages, e.g. to see
+// what the switch logged around a link_down.
+func (h *APIHandler) GetTimeline(w http.ResponseWriter, r *http.Request) {
+	device := r.URL.Query().Get("device")
+	if device == "" {
+		http.Error(w, "device parameter is required", http.StatusBadRequest)
+		return
+	}
+	since, err := parseSince(r, "since", 24*time.Hour)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	events, err := h.storage.GetEventsSince(device, since, 500)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	logs, err := h.storage.GetLogs(device, "", since, 500)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	timeline := []models.TimelineEntry{}
+	for _, e := range events {
+		timeline = append(timeline, models.TimelineEntry{
+			Timestamp: e.Timestamp, DeviceName: e.DeviceName, InterfaceName: e.InterfaceName,
+			Source: "event", Kind: e.Kind, Message: e.Message,
+		})
+	}
+	for _, l := range logs {
+		timeline = append(timeline, models.TimelineEntry{
+			Timestamp: l.Timestamp, DeviceName: l.DeviceName,
+			Source: "log", Kind: syslog.SeverityName(l.Severity), Message: l.Message,
+		})
+	}
+	sort.SliceStable(timeline, func(i, j int) bool {
+		return timeline[i].Timestamp.After(timeline[j].Timestamp)
+	})
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(timeline)
+}
+
+// GetTopFlows returns the top talkers, protocols and service ports seen in
+// flow exports, e.g. to find out who is saturating an uplink. from defaults to
+// the last hour and to to now.
+func (h *APIHandler) GetTopFlows(w http.ResponseWriter, r *http.Request) {
+	from, err := parseSince(r, "from", time.Hour)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	to := time.Now()
+	if v := r.URL.Query().Get("to"); v != "" {
+		if to, err = time.Parse(time.RFC3339, v); err != nil {
+			http.Error(w, "to must be an RFC 3339 time", http.StatusBadRequest)
+			return
+		}
+	}
+	limit := 10
+	if v := r.URL.Query().Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit <= 0 {
+			http.Error(w, "limit must be a positive number", http.StatusBadRequest)
+			return
+		}
+	}
+
+	top, err := h.storage.GetTopFlows(r.URL.Query().Get("device"), r.URL.Query().Get("interface"), from, to, limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	for i := range top.Protocols {
+		top.Protocols[i].Name = flows.ProtocolName(top.Protocols[i].Protocol)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(top)
+}
+
+// parseSince reads a query parameter holding an RFC 3339 time or a duration
+// back from now, falling back to def ago when it is missing.
+func parseSince(r *http.Request, param string, def time.Duration) (time.Time, error) {
+	v := r.URL.Query().Get(param)
+	if v == "" {
+		return time.Now().Add(-def), nil
+	}
+	if d, err := time.ParseDuration(v); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s must be an RFC 3339 time or a duration such as 1h", param)
+	}
+	return t, nil
+}
+
+func (h *APIHandler) GetTopologyChanges(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	events, err := h.storage.GetTopologyEvents(status, 100)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if events == nil {
+		events = []models.TopologyEvent{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"pending": h.rediscovery.HasPending(),
+		"events":  events,
+	})
+}
+
+func (h *APIHandler) ApproveTopologyChanges(w http.ResponseWriter, r *http.Request) {
+	h.resolveTopologyChanges(w, r, h.rediscovery.Approve)
+}
+
+func (h *APIHandler) RejectTopologyChanges(w http.ResponseWriter, r *http.Request) {
+	h.resolveTopologyChanges(w, r, h.rediscovery.Reject)
+}
+
+func (h *APIHandler) resolveTopologyChanges(w http.ResponseWriter, r *http.Request, resolve func() error) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := resolve(); err != nil {
+		if errors.Is(err, topology.ErrNoPendingChanges) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -1,23 +1,100 @@
 package config
 
 import (
-	"os"
 	"github.com/AMathur20/Home_Network/internal/models"
-	"gopkg.in/yaml.v3"
 )
 
+// LoadConfig reads, defaults and validates a config file and resolves its
+// secret references. Unknown keys are rejected, and every problem found is
+// reported as *Errors with its line.
 func LoadConfig(path string) (*models.Config, error) {
-	file, err := os.Open(path)
+	var cfg models.Config
+	root, err := Decode(path, &cfg)
 	if err != nil {
 		return nil, err
 	}
-	defer file.Close()
 
-	var cfg models.Config
-	decoder := yaml.NewDecoder(file)
-	if err := decoder.Decode(&cfg); err != nil {
+	errs := &Errors{}
+	resolveSecrets(&cfg, errs)
+	applyDefaults(&cfg)
+	errs.Errors = append(errs.Errors, check(&cfg).Errors...)
+	errs.File = path
+	errs.Locate(root)
+	if err := errs.Err(); err != nil {
 		return nil, err
 	}
-
 	return &cfg, nil
 }
+
+// applyDefaults fills in settings that are required but have an obvious
+// default, so they don't have to be repeated in every config.
+func applyDefaults(cfg *models.Config) {
+	if cfg.Poller.Live == 0 {
+		cfg.Poller.Live = 5
+	}
+	for i := range cfg.Devices {
+		dev := &cfg.Devices[i]
+		if dev.Type == "" {
+			dev.Type = models.DeviceTypeGeneric
+		}
+		applyProfiles(cfg, dev)
+		if dev.PolledBySNMP() {
+			if dev.SNMP.Version == "" {
+				dev.SNMP.Version = "v2c"
+			}
+			if dev.SNMP.Port == 0 {
+				dev.SNMP.Port = 161
+			}
+		}
+	}
+}
+
+// applyProfiles fills in the settings a device leaves out, field by field,
+// from its credential profiles in order, then the defaults for its type and
+// their profiles. Unknown profile names are left for check to report.
+func applyProfiles(cfg *models.Config, dev *models.DeviceConfig) {
+	for _, p := range cfg.Profiles(dev.Credentials) {
+		fillSNMP(&dev.SNMP, p.SNMP)
+		fillAuth(&dev.Auth, p.Auth)
+	}
+	defaults := cfg.Defaults[dev.Type]
+	if defaults == nil {
+		return
+	}
+	fillSNMP(&dev.SNMP, defaults.SNMP)
+	fillAuth(&dev.Auth, defaults.Auth)
+	for _, p := range cfg.Profiles(defaults.Credentials) {
+		fillSNMP(&dev.SNMP, p.SNMP)
+		fillAuth(&dev.Auth, p.Auth)
+	}
+	// A device's own interval or priority replaces the default schedule as
+	// a whole.
+	if dev.Interval == 0 && dev.Priority == "" {
+		dev.Interval = defaults.Interval
+		dev.Priority = defaults.Priority
+	}
+}
+
+func fill[T comparable](dst *T, src T) {
+	var zero T
+	if *dst == zero {
+		*dst = src
+	}
+}
+
+func fillSNMP(dst *models.SNMPConfig, src models.SNMPConfig) {
+	fill(&dst.Version, src.Version)
+	fill(&dst.Community, src.Community)
+	fill(&dst.Port, src.Port)
+	fill(&dst.Username, src.Username)
+	fill(&dst.AuthProtocol, src.AuthProtocol)
+	fill(&dst.AuthPassphrase, src.AuthPassphrase)
+	fill(&dst.PrivProtocol, src.PrivProtocol)
+	fill(&dst.PrivPassphrase, src.PrivPassphrase)
+}
+
+func fillAuth(dst *models.AuthConfig, src models.AuthConfig) {
+	fill(&dst.Username, src.Username)
+	fill(&dst.Password, src.Password)
+	fill(&dst.Port, src.Port)
+}
@@ -0,0 +1,176 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+
+	write := func(content string) {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("devices:\n  - name: router\n    host: 192.168.1.1\n  - name: switch\n    host: 192.168.1.2\n")
+	cfg, err := LoadConfig(path)
+	if err != nil || len(cfg.Devices) != 2 {
+		t.Fatalf("expected a valid config, got %v, %v", cfg, err)
+	}
+
+	write("devices:\n  - name: router\n    host: 192.168.1.1\n  - name: router\n    host: 192.168.1.2\n")
+	if _, err := LoadConfig(path); err == nil || !strings.Contains(err.Error(), "duplicate") {
+		t.Errorf("expected a duplicate name error, got %v", err)
+	}
+
+	write("devices:\n  - name: router\n    host: [\n")
+	if _, err := LoadConfig(path); err == nil {
+		t.Error("expected a YAML error")
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := `poller:
+  live: -1
+devices:
+  - name: router
+    host: 192.168.1.1
+    type: mikrotik
+    snmp:
+      community: public
+      port: 70000
+  - name: switch
+    hostname: 192.168.1.2
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := LoadConfig(path)
+	if err == nil {
+		t.Fatal("expected errors")
+	}
+	for _, want := range []string{
+		path + ":11: field hostname not found",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in:\n%v", want, err)
+		}
+	}
+
+	// Without the unknown key the config decodes and is checked field by field.
+	content = strings.Replace(content, "hostname", "type", 1)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err = LoadConfig(path)
+	if err == nil {
+		t.Fatal("expected errors")
+	}
+	for _, want := range []string{
+		path + ":2: poller.live: must be at least 1",
+		path + ":9: devices[0].snmp.port: must be a port between 1 and 65535",
+		path + ":10: devices[1].host: is required",
+		path + ":11: devices[1].type: unknown device type \"192.168.1.2\"",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in:\n%v", want, err)
+		}
+	}
+}
+
+func TestLoadConfigDefaults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := "devices:\n  - name: router\n    host: router.lan\n    type: mikrotik\n    snmp:\n      community: public\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snmp := cfg.Devices[0].SNMP
+	if cfg.Poller.Live != 5 || snmp.Version != "v2c" || snmp.Port != 161 {
+		t.Errorf("expected defaults, got live %d and %+v", cfg.Poller.Live, snmp)
+	}
+}
+
+func TestLoadConfigProfiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := `credentials:
+  snmp-v3:
+    snmp:
+      version: v3
+      username: hnm
+      auth_protocol: sha
+      auth_passphrase: authpass
+      priv_protocol: aes
+      priv_passphrase: privpass
+  routeros:
+    auth:
+      username: hnm
+      password: apipass
+  public:
+    snmp:
+      community: public
+defaults:
+  mikrotik:
+    credentials: [routeros]
+    priority: high
+devices:
+  - name: router
+    host: 192.168.1.1
+    type: mikrotik
+    credentials: [snmp-v3]
+    snmp:
+      port: 1161
+  - name: switch
+    host: 192.168.1.2
+    type: mikrotik
+    credentials: [public]
+    interval: 10
+    auth:
+      password: switchpass
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := cfg.Devices[0]
+	if router.SNMP.Version != "v3" || router.SNMP.Username != "hnm" || router.SNMP.PrivPassphrase != "privpass" || router.SNMP.Port != 1161 {
+		t.Errorf("expected v3 credentials with the device's port, got %+v", router.SNMP)
+	}
+	if router.Auth.Username != "hnm" || router.Auth.Password != "apipass" || router.Priority != "high" {
+		t.Errorf("expected the mikrotik defaults, got %+v, priority %q", router.Auth, router.Priority)
+	}
+
+	sw := cfg.Devices[1]
+	if sw.SNMP.Version != "v2c" || sw.SNMP.Community != "public" || sw.SNMP.Port != 161 {
+		t.Errorf("expected v2c credentials, got %+v", sw.SNMP)
+	}
+	if sw.Auth.Username != "hnm" || sw.Auth.Password != "switchpass" || sw.Interval != 10 || sw.Priority != "" {
+		t.Errorf("expected the device's own password and interval, got %+v, %d, %q", sw.Auth, sw.Interval, sw.Priority)
+	}
+
+	content = strings.Replace(content, "[public]", "[pubilc]", 1)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err = LoadConfig(path)
+	for _, want := range []string{
+		path + `:31: devices[1].credentials[0]: unknown credential profile "pubilc"`,
+		path + `:28: devices[1].snmp.community: is required`,
+	} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in:\n%v", want, err)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FieldError is a problem with one setting. Line is its position in the file,
+// 0 if unknown.
+type FieldError struct {
+	Line    int
+	Path    string // e.g. devices[1].snmp.port
+	Message string
+}
+
+// Errors collects every problem found in a file so they can all be fixed in
+// one go. It prints one "file:line: path: message" line per problem.
+type Errors struct {
+	File   string
+	Errors []FieldError
+}
+
+func (e *Errors) Add(path, format string, args ...interface{}) {
+	e.Errors = append(e.Errors, FieldError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// Err returns e if it holds any problems and nil otherwise.
+func (e *Errors) Err() error {
+	if len(e.Errors) == 0 {
+		return nil
+	}
+	return e
+}
+
+func (e *Errors) Error() string {
+	lines := make([]string, 0, len(e.Errors))
+	for _, fe := range e.Errors {
+		var b strings.Builder
+		b.WriteString(e.File)
+		if fe.Line > 0 {
+			fmt.Fprintf(&b, ":%d", fe.Line)
+		}
+		if e.File != "" || fe.Line > 0 {
+			b.WriteString(": ")
+		}
+		if fe.Path != "" {
+			b.WriteString(fe.Path + ": ")
+		}
+		b.WriteString(fe.Message)
+		lines = append(lines, b.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Locate fills in line numbers from the parsed document and sorts the
+// problems by position.
+func (e *Errors) Locate(root *yaml.Node) {
+	for i := range e.Errors {
+		if e.Errors[i].Line == 0 {
+			e.Errors[i].Line = Line(root, e.Errors[i].Path)
+		}
+	}
+	sort.SliceStable(e.Errors, func(i, j int) bool { return e.Errors[i].Line < e.Errors[j].Line })
+}
+
+var pathSegment = regexp.MustCompile(`[^.\[\]]+|\[\d+\]`)
+
+// Line returns the line of the node at path, such as "devices[1].snmp.port".
+// If the path doesn't exist, e.g. for a missing required field, the line of
+// the deepest node that does is returned.
+func Line(root *yaml.Node, path string) int {
+	if root == nil {
+		return 0
+	}
+	node := root
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	line := node.Line
+	for _, seg := range pathSegment.FindAllString(path, -1) {
+		var next *yaml.Node
+		if strings.HasPrefix(seg, "[") {
+			i, _ := strconv.Atoi(seg[1 : len(seg)-1])
+			if node.Kind == yaml.SequenceNode && i < len(node.Content) {
+				next = node.Content[i]
+			}
+		} else if node.Kind == yaml.MappingNode {
+			for k := 0; k+1 < len(node.Content); k += 2 {
+				if node.Content[k].Value == seg {
+					next = node.Content[k+1]
+					break
+				}
+			}
+		}
+		if next == nil {
+			break
+		}
+		node = next
+		line = node.Line
+	}
+	return line
+}
+
+var yamlLine = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+
+// Decode parses a YAML file into out, rejecting keys that out doesn't have.
+// The parsed document is returned so validation errors can be located.
+// Syntax and type errors are returned as *Errors.
+func Decode(path string, out interface{}) (*yaml.Node, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	errs := &Errors{File: path}
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		errs.addYAML(err)
+		return nil, errs
+	}
+	if root.Kind == 0 {
+		errs.Add("", "file is empty")
+		return nil, errs
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(out); err != nil && err != io.EOF {
+		errs.addYAML(err)
+		return nil, errs
+	}
+	return &root, nil
+}
+
+// addYAML splits a yaml.v3 error into one problem per line it reports.
+func (e *Errors) addYAML(err error) {
+	var messages []string
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		messages = typeErr.Errors
+	} else {
+		messages = []string{err.Error()}
+	}
+	for _, msg := range messages {
+		fe := FieldError{Message: strings.TrimPrefix(msg, "yaml: ")}
+		if m := yamlLine.FindStringSubmatch(msg); m != nil {
+			fe.Line, _ = strconv.Atoi(m[1])
+			fe.Message = m[2]
+		}
+		e.Errors = append(e.Errors, fe)
+	}
+}
@@ -0,0 +1,37 @@
+package config
+
+import (
+	"reflect"
+
+	"github.com/AMathur20/Home_Network/internal/models"
+)
+
+// RestartRequired lists the sections that changed between two configs but are
+// only read at startup. Devices, credentials, poller intervals and probes are
+// applied live.
+func RestartRequired(old, new *models.Config) []string {
+	// Discovery credentials are passed to the crawler on reload.
+	oldDiscovery, newDiscovery := old.Discovery, new.Discovery
+	oldDiscovery.Credentials, newDiscovery.Credentials = nil, nil
+	sections := []struct {
+		name     string
+		old, new interface{}
+	}{
+		{"discovery", oldDiscovery, newDiscovery},
+		{"classification", old.Classification, new.Classification},
+		{"endpoints.oui_file", old.Endpoints.OUIFile, new.Endpoints.OUIFile},
+		{"traps", old.Traps, new.Traps},
+		{"syslog", old.Syslog, new.Syslog},
+		{"flows", old.Flows, new.Flows},
+		{"probes.disabled", old.Probes.Disabled, new.Probes.Disabled},
+		{"security", old.Security, new.Security},
+		{"notifications", old.Notifications, new.Notifications},
+	}
+	var changed []string
+	for _, s := range sections {
+		if !reflect.DeepEqual(s.old, s.new) {
+			changed = append(changed, s.name)
+		}
+	}
+	return changed
+}
@@ -0,0 +1,20 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/AMathur20/Home_Network/internal/models"
+)
+
+func TestRestartRequired(t *testing.T) {
+	old := &models.Config{Poller: models.IntervalConfig{Live: 5}}
+	new := &models.Config{
+		Poller:  models.IntervalConfig{Live: 2},
+		Devices: []models.DeviceConfig{{Name: "router", Host: "192.168.1.1"}},
+		Syslog:  models.SyslogConfig{Enabled: true},
+	}
+	if got := RestartRequired(old, new); !reflect.DeepEqual(got, []string{"syslog"}) {
+		t.Errorf("expected only syslog to need a restart, got %v", got)
+	}
+}
@@ -0,0 +1,196 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"github.com/AMathur20/Home_Network/internal/models"
+)
+
+// Masked replaces secrets in logs and API responses.
+const Masked = "********"
+
+// SecretsDir is where ${secret:name} references are read from, the directory
+// Docker mounts secrets in.
+var SecretsDir = "/run/secrets"
+
+// secret is a config field holding a credential. Usernames may come from
+// references too but aren't masked.
+type secret struct {
+	path   string
+	value  *string
+	masked bool
+}
+
+// secrets returns the credential fields of a config. Only these may use
+// secret references.
+func secrets(cfg *models.Config) []secret {
+	var fields []secret
+	for _, name := range slices.Sorted(maps.Keys(cfg.Credentials)) {
+		if p := cfg.Credentials[name]; p != nil {
+			fields = credentialSecrets(fields, "credentials."+name, &p.SNMP, &p.Auth)
+		}
+	}
+	for _, t := range slices.Sorted(maps.Keys(cfg.Defaults)) {
+		if d := cfg.Defaults[t]; d != nil {
+			fields = credentialSecrets(fields, "defaults."+string(t), &d.SNMP, &d.Auth)
+		}
+	}
+	for i := range cfg.Devices {
+		dev := &cfg.Devices[i]
+		fields = credentialSecrets(fields, fmt.Sprintf("devices[%d]", i), &dev.SNMP, &dev.Auth)
+	}
+	fields = append(fields, secret{"traps.community", &cfg.Traps.Community, true})
+	if v3 := cfg.Traps.V3; v3 != nil {
+		fields = usmSecrets(fields, "traps.v3", &v3.USMUser)
+	}
+	return append(fields, secret{"notifications.webhook", &cfg.Notifications.Webhook, true})
+}
+
+func credentialSecrets(fields []secret, path string, snmp *models.SNMPConfig, auth *models.AuthConfig) []secret {
+	fields = append(fields, secret{path + ".snmp.community", &snmp.Community, true})
+	fields = usmSecrets(fields, path+".snmp", &snmp.USMUser)
+	return append(fields,
+		secret{path + ".auth.username", &auth.Username, false},
+		secret{path + ".auth.password", &auth.Password, true},
+	)
+}
+
+func usmSecrets(fields []secret, path string, user *models.USMUser) []secret {
+	return append(fields,
+		secret{path + ".username", &user.Username, false},
+		secret{path + ".auth_passphrase", &user.AuthPassphrase, true},
+		secret{path + ".priv_passphrase", &user.PrivPassphrase, true},
+	)
+}
+
+var secretRef = regexp.MustCompile(`\$\{(env|file|secret):([^}]*)\}`)
+
+// resolveSecrets replaces secret references in the credential fields with
+// their values: ${env:NAME} with an environment variable, ${file:/path} or a
+// value of just file:/path with the contents of a file, and ${secret:name}
+// with a Docker secret.
+func resolveSecrets(cfg *models.Config, errs *Errors) {
+	for _, s := range secrets(cfg) {
+		value := *s.value
+		if path, ok := strings.CutPrefix(value, "file:"); ok {
+			value = "${file:" + path + "}"
+		}
+		*s.value = secretRef.ReplaceAllStringFunc(value, func(ref string) string {
+			m := secretRef.FindStringSubmatch(ref)
+			resolved, err := lookupSecret(m[1], m[2])
+			if err != nil {
+				errs.Add(s.path, "%v", err)
+			}
+			return resolved
+		})
+	}
+}
+
+func lookupSecret(kind, name string) (string, error) {
+	if name == "" {
+		return "", fmt.Errorf("${%s:} needs a name", kind)
+	}
+	path := name
+	switch kind {
+	case "env":
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return value, nil
+	case "secret":
+		if strings.ContainsAny(name, `/\`) {
+			return "", fmt.Errorf("invalid secret name %q", name)
+		}
+		path = filepath.Join(SecretsDir, name)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("reading secret: %v", err)
+	}
+	// Files written by editors and echo end with a newline.
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// Redact returns a copy of cfg with its credentials masked, for APIs that
+// show the config.
+func Redact(cfg *models.Config) *models.Config {
+	c := *cfg
+	c.Devices = append([]models.DeviceConfig(nil), cfg.Devices...)
+	if cfg.Traps.V3 != nil {
+		v3 := *cfg.Traps.V3
+		c.Traps.V3 = &v3
+	}
+	c.Credentials = make(map[string]*models.CredentialProfile, len(cfg.Credentials))
+	for name, p := range cfg.Credentials {
+		if p != nil {
+			profile := *p
+			c.Credentials[name] = &profile
+		}
+	}
+	c.Defaults = make(map[models.DeviceType]*models.DeviceDefaults, len(cfg.Defaults))
+	for t, d := range cfg.Defaults {
+		if d != nil {
+			defaults := *d
+			c.Defaults[t] = &defaults
+		}
+	}
+	for _, s := range secrets(&c) {
+		if s.masked && *s.value != "" {
+			*s.value = Masked
+		}
+	}
+	return &c
+}
+
+// Redactor masks the credentials of the current config in everything written
+// through it. It is installed as the log output so that errors which quote a
+// secret, such as a failed webhook POST, don't leak it.
+type Redactor struct {
+	w        io.Writer
+	replacer atomic.Pointer[strings.Replacer]
+}
+
+func NewRedactor(w io.Writer) *Redactor {
+	r := &Redactor{w: w}
+	r.replacer.Store(strings.NewReplacer())
+	return r
+}
+
+// SetConfig switches to masking the credentials of cfg.
+func (r *Redactor) SetConfig(cfg *models.Config) {
+	var values []string
+	for _, s := range secrets(cfg) {
+		// Masking a very short value would garble unrelated log lines.
+		if s.masked && len(*s.value) >= 4 {
+			values = append(values, *s.value)
+		}
+	}
+	// Longest first, so a secret that contains another is masked whole.
+	sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+	pairs := make([]string, 0, 2*len(values))
+	for _, v := range values {
+		pairs = append(pairs, v, Masked)
+	}
+	r.replacer.Store(strings.NewReplacer(pairs...))
+}
+
+func (r *Redactor) Write(p []byte) (int, error) {
+	masked := r.replacer.Load().Replace(string(p))
+	if masked == string(p) {
+		return r.w.Write(p)
+	}
+	if _, err := io.WriteString(r.w, masked); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,91 @@
+package config
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/AMathur20/Home_Network/internal/models"
+)
+
+func TestLoadConfigSecrets(t *testing.T) {
+	dir := t.TempDir()
+	SecretsDir = dir
+	defer func() { SecretsDir = "/run/secrets" }()
+	t.Setenv("HNM_TEST_PASSWORD", "s3cret-pass")
+	t.Setenv("HNM_TEST_TOPIC", "alerts-1234")
+	if err := os.WriteFile(filepath.Join(dir, "snmp_community"), []byte("c0mmunity\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "trap_community"), []byte("tr4ps"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "config.yaml")
+	content := `devices:
+  - name: router
+    host: 192.168.1.1
+    type: mikrotik
+    auth:
+      username: admin
+      password: "${env:HNM_TEST_PASSWORD}"
+    snmp:
+      community: "file:` + filepath.Join(dir, "snmp_community") + `"
+traps:
+  community: "${secret:trap_community}"
+notifications:
+  webhook: "https://ntfy.sh/${env:HNM_TEST_TOPIC}"
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dev := cfg.Devices[0]
+	if dev.Auth.Password != "s3cret-pass" || dev.SNMP.Community != "c0mmunity" || cfg.Traps.Community != "tr4ps" ||
+		cfg.Notifications.Webhook != "https://ntfy.sh/alerts-1234" {
+		t.Errorf("secrets not resolved: %+v, %+v, %+v", dev, cfg.Traps, cfg.Notifications)
+	}
+
+	redacted := Redact(cfg)
+	if r := redacted.Devices[0]; r.Auth.Password != Masked || r.SNMP.Community != Masked || r.Auth.Username != "admin" ||
+		redacted.Notifications.Webhook != Masked {
+		t.Errorf("expected credentials to be masked: %+v, %+v", r, redacted.Notifications)
+	}
+	if cfg.Devices[0].Auth.Password != "s3cret-pass" {
+		t.Error("Redact modified the original config")
+	}
+
+	var out bytes.Buffer
+	redactor := NewRedactor(&out)
+	redactor.SetConfig(cfg)
+	logger := log.New(redactor, "", 0)
+	logger.Printf(`Post "%s": connection refused`, cfg.Notifications.Webhook)
+	if got := out.String(); got != `Post "`+Masked+`": connection refused`+"\n" {
+		t.Errorf("unexpected log output %q", got)
+	}
+
+	content = strings.Replace(content, "HNM_TEST_PASSWORD", "HNM_TEST_UNSET", 1)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err = LoadConfig(path)
+	if want := path + ":7: devices[0].auth.password: environment variable HNM_TEST_UNSET is not set"; err == nil || err.Error() != want {
+		t.Errorf("expected %q, got %v", want, err)
+	}
+}
+
+func TestRedactorShortValues(t *testing.T) {
+	var out bytes.Buffer
+	redactor := NewRedactor(&out)
+	redactor.SetConfig(&models.Config{Traps: models.TrapConfig{Community: "ab"}})
+	redactor.Write([]byte("about tabs\n"))
+	if out.String() != "about tabs\n" {
+		t.Errorf("expected short secrets to be left alone, got %q", out.String())
+	}
+}
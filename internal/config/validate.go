@@ -0,0 +1,202 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"net"
+	"net/url"
+	"regexp"
+	"slices"
+
+	"github.com/AMathur20/Home_Network/internal/models"
+	"github.com/AMathur20/Home_Network/internal/snmp"
+)
+
+var hostname = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9-]{0,61}[A-Za-z0-9])?(\.[A-Za-z0-9]([A-Za-z0-9-]{0,61}[A-Za-z0-9])?)*\.?$`)
+
+func validHost(host string) bool {
+	return net.ParseIP(host) != nil || (len(host) <= 253 && hostname.MatchString(host))
+}
+
+// check reports settings that would break polling, such as devices without a
+// host or two devices sharing a name.
+func check(cfg *models.Config) *Errors {
+	errs := &Errors{}
+	atLeast := func(path string, value, min int) {
+		if value < min {
+			errs.Add(path, "must be at least %d", min)
+		}
+	}
+	port := func(path string, value int, required bool) {
+		if value < 0 || value > 65535 || (required && value == 0) {
+			errs.Add(path, "must be a port between 1 and 65535")
+		}
+	}
+	priority := func(path, class string) {
+		switch class {
+		case "", models.PriorityHigh, models.PriorityNormal, models.PriorityLow:
+		default:
+			errs.Add(path, "unknown priority class %q (expected high, normal or low)", class)
+		}
+	}
+	profiles := func(path string, names []string) {
+		for i, name := range names {
+			if cfg.Credentials[name] == nil {
+				errs.Add(fmt.Sprintf("%s[%d]", path, i), "unknown credential profile %q", name)
+			}
+		}
+	}
+	// Profiles and defaults may hold part of an snmp block; a polled device
+	// needs a complete one.
+	snmpBlock := func(path string, c models.SNMPConfig, complete bool) {
+		switch c.Version {
+		case "":
+		case "v2c":
+			if complete && c.Community == "" {
+				errs.Add(path+".community", "is required")
+			}
+		case "v3":
+			if complete && c.Username == "" {
+				errs.Add(path+".username", "is required")
+			}
+		default:
+			errs.Add(path+".version", "unknown SNMP version %q (expected v2c or v3)", c.Version)
+		}
+		if _, _, err := snmp.USM(c.USMUser); err != nil {
+			errs.Add(path, "%v", err)
+		}
+		port(path+".port", c.Port, complete)
+	}
+
+	atLeast("poller.live", cfg.Poller.Live, 1)
+	atLeast("poller.history", cfg.Poller.History, 0)
+	atLeast("poller.failure_threshold", cfg.Poller.FailureThreshold, 0)
+	atLeast("poller.max_backoff", cfg.Poller.MaxBackoff, 0)
+	for class, seconds := range cfg.Poller.Priorities {
+		path := "poller.priorities." + class
+		switch class {
+		case models.PriorityHigh, models.PriorityNormal, models.PriorityLow:
+			atLeast(path, seconds, 1)
+		default:
+			errs.Add(path, "unknown priority class %q (expected high, normal or low)", class)
+		}
+	}
+
+	switch cfg.Discovery.Mode {
+	case "", models.DiscoveryModeInitial, models.DiscoveryModeMerge:
+	default:
+		errs.Add("discovery.mode", "unknown mode %q (expected initial or merge)", cfg.Discovery.Mode)
+	}
+	atLeast("discovery.interval", cfg.Discovery.Interval, 0)
+	profiles("discovery.credentials", cfg.Discovery.Credentials)
+
+	for _, name := range slices.Sorted(maps.Keys(cfg.Credentials)) {
+		path := "credentials." + name
+		p := cfg.Credentials[name]
+		if p == nil {
+			errs.Add(path, "is empty")
+			continue
+		}
+		snmpBlock(path+".snmp", p.SNMP, false)
+		port(path+".auth.port", p.Auth.Port, false)
+	}
+	for _, t := range slices.Sorted(maps.Keys(cfg.Defaults)) {
+		path := "defaults." + string(t)
+		d := cfg.Defaults[t]
+		switch t {
+		case models.DeviceTypeMikroTik, models.DeviceTypeUniFi, models.DeviceTypeEdgeRouter, models.DeviceTypeGeneric:
+		default:
+			errs.Add(path, "unknown device type %q (expected mikrotik, unifi, edgerouter or generic)", t)
+		}
+		if d == nil {
+			continue
+		}
+		profiles(path+".credentials", d.Credentials)
+		snmpBlock(path+".snmp", d.SNMP, false)
+		port(path+".auth.port", d.Auth.Port, false)
+		atLeast(path+".interval", d.Interval, 0)
+		priority(path+".priority", d.Priority)
+	}
+
+	names := make(map[string]bool)
+	for i, dev := range cfg.Devices {
+		path := fmt.Sprintf("devices[%d]", i)
+		switch {
+		case dev.Name == "":
+			errs.Add(path+".name", "is required")
+		case names[dev.Name]:
+			errs.Add(path+".name", "duplicate device name %q", dev.Name)
+		}
+		names[dev.Name] = true
+
+		switch {
+		case dev.Host == "":
+			errs.Add(path+".host", "is required")
+		case !validHost(dev.Host):
+			errs.Add(path+".host", "%q is not an IP address or hostname", dev.Host)
+		}
+
+		switch dev.Type {
+		case models.DeviceTypeMikroTik, models.DeviceTypeUniFi, models.DeviceTypeEdgeRouter, models.DeviceTypeGeneric:
+		default:
+			errs.Add(path+".type", "unknown device type %q (expected mikrotik, unifi, edgerouter or generic)", dev.Type)
+		}
+
+		profiles(path+".credentials", dev.Credentials)
+		snmpBlock(path+".snmp", dev.SNMP, dev.PolledBySNMP())
+		port(path+".auth.port", dev.Auth.Port, false)
+		atLeast(path+".interval", dev.Interval, 0)
+		priority(path+".priority", dev.Priority)
+		if dev.PoEBudget < 0 {
+			errs.Add(path+".poe_budget", "must not be negative")
+		}
+	}
+
+	atLeast("probes.interval", cfg.Probes.Interval, 0)
+	atLeast("probes.count", cfg.Probes.Count, 0)
+	atLeast("probes.timeout", cfg.Probes.Timeout, 0)
+	for i, t := range cfg.Probes.Targets {
+		path := fmt.Sprintf("probes.targets[%d]", i)
+		if t.Device != "" && !names[t.Device] {
+			errs.Add(path+".device", "unknown device %q", t.Device)
+		}
+		if t.Host != "" && !validHost(t.Host) {
+			errs.Add(path+".host", "%q is not an IP address or hostname", t.Host)
+		}
+		switch t.Type {
+		case models.ProbeICMP, models.ProbeTCP:
+			if t.Host == "" && t.Device == "" {
+				errs.Add(path+".host", "is required without a device")
+			}
+			if t.Type == models.ProbeTCP {
+				port(path+".port", t.Port, true)
+			}
+		case models.ProbeHTTP:
+			if t.URL == "" && t.Host == "" && t.Device == "" {
+				errs.Add(path+".url", "is required without a host or device")
+			}
+			if t.URL != "" {
+				if u, err := url.Parse(t.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+					errs.Add(path+".url", "%q is not an http or https URL", t.URL)
+				}
+			}
+			if t.ExpectStatus != 0 && (t.ExpectStatus < 100 || t.ExpectStatus > 599) {
+				errs.Add(path+".expect_status", "must be an HTTP status code")
+			}
+		default:
+			errs.Add(path+".type", "unknown probe type %q (expected icmp, tcp or http)", t.Type)
+		}
+	}
+
+	atLeast("syslog.retention", cfg.Syslog.Retention, 0)
+	atLeast("flows.interval", cfg.Flows.Interval, 0)
+	atLeast("flows.retention", cfg.Flows.Retention, 0)
+	atLeast("security.flap_count", cfg.Security.FlapCount, 0)
+	atLeast("security.flap_window", cfg.Security.FlapWindow, 0)
+	if cfg.Notifications.Webhook != "" {
+		if u, err := url.Parse(cfg.Notifications.Webhook); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs.Add("notifications.webhook", "is not an http or https URL")
+		}
+	}
+	return errs
+}
@@ -0,0 +1,349 @@
+package flows
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/AMathur20/Home_Network/internal/models"
+	"github.com/AMathur20/Home_Network/internal/netutil"
+	"github.com/AMathur20/Home_Network/internal/poller"
+	"github.com/AMathur20/Home_Network/internal/storage"
+)
+
+var protocolNames = map[int]string{
+	1: "icmp", 2: "igmp", 6: "tcp", 17: "udp", 41: "ipv6", 47: "gre",
+	50: "esp", 51: "ah", 58: "ipv6-icmp", 89: "ospf", 112: "vrrp", 132: "sctp",
+}
+
+// ProtocolName returns the keyword for an IANA protocol number, e.g. "tcp".
+func ProtocolName(protocol int) string {
+	if name, ok := protocolNames[protocol]; ok {
+		return name
+	}
+	return fmt.Sprintf("proto-%d", protocol)
+}
+
+type summaryKey struct {
+	exporter       string
+	inIf, outIf    int
+	src, dst       string
+	protocol, port int
+}
+
+// Collector receives flow exports and writes one summary row per host pair,
+// protocol and service port for every aggregation interval. Ephemeral client
+// ports are folded away so a busy interval stays a manageable number of rows.
+// sFlow counter samples are stored as interface metrics.
+type Collector struct {
+	addr      string
+	sflowAddr string
+	interval  time.Duration
+	retention time.Duration
+	storage   *storage.DuckDBStorage
+	devices   *models.DeviceAddresses // exporter IP -> device
+	decoder   *decoder
+
+	mu       sync.Mutex
+	current  map[summaryKey]*models.FlowSummary
+	counters map[string]ifCounterState // device/ifIndex -> last counter sample
+
+	namesMu sync.Mutex
+	ifNames map[string]map[int]string // device -> ifIndex -> ifName
+}
+
+type ifCounterState struct {
+	inOctets, outOctets uint64
+	time                time.Time
+}
+
+func NewCollector(cfg models.FlowConfig, devices []models.DeviceConfig, s *storage.DuckDBStorage) *Collector {
+	addr := cfg.Listen
+	if addr == "" {
+		addr = ":2055"
+	}
+	sflowAddr := cfg.SFlowListen
+	if sflowAddr == "" {
+		sflowAddr = ":6343"
+	}
+	interval := cfg.Interval
+	if interval <= 0 {
+		interval = 60
+	}
+	days := cfg.Retention
+	if days <= 0 {
+		days = 7
+	}
+	return &Collector{
+		addr:      addr,
+		sflowAddr: sflowAddr,
+		interval:  time.Duration(interval) * time.Second,
+		retention: time.Duration(days) * 24 * time.Hour,
+		storage:   s,
+		devices:   models.NewDeviceAddresses(devices),
+		decoder:   newDecoder(),
+		current:   make(map[summaryKey]*models.FlowSummary),
+		counters:  make(map[string]ifCounterState),
+		ifNames:   make(map[string]map[int]string),
+	}
+}
+
+// SetDevices replaces the devices exports are attributed to. Cached interface
+// names are dropped since a device's credentials may have changed.
+func (c *Collector) SetDevices(devices []models.DeviceConfig) {
+	c.devices.Set(devices)
+	c.namesMu.Lock()
+	c.ifNames = make(map[string]map[int]string)
+	c.namesMu.Unlock()
+}
+
+// Start listens for NetFlow/IPFIX and sFlow exports. It only returns if a
+// listener cannot be opened or is closed.
+func (c *Collector) Start() error {
+	conn, err := net.ListenPacket("udp", c.addr)
+	if err != nil {
+		return err
+	}
+	sflow, err := net.ListenPacket("udp", c.sflowAddr)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	log.Printf("Flow collector listening on udp %s, sFlow on udp %s (interval %s, retention %s)", c.addr, c.sflowAddr, c.interval, c.retention)
+
+	go c.flushLoop()
+	go c.serveSFlow(sflow)
+
+	buf := make([]byte, 65535)
+	var backoff netutil.Backoff
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if errors.Is(err, net.ErrClosed) {
+			sflow.Close()
+			return nil
+		}
+		if err != nil {
+			log.Printf("Flow collector read error: %v", err)
+			backoff.Wait()
+			continue
+		}
+		backoff.Reset()
+		exporter := addr.(*net.UDPAddr).IP.String()
+		flows, err := c.decoder.decode(buf[:n], exporter)
+		if err != nil {
+			log.Printf("Dropping flow export from %s: %v", exporter, err)
+		}
+		c.add(exporter, flows)
+	}
+}
+
+func (c *Collector) serveSFlow(conn net.PacketConn) {
+	buf := make([]byte, 65535)
+	var backoff netutil.Backoff
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if errors.Is(err, net.ErrClosed) {
+			return
+		}
+		if err != nil {
+			log.Printf("sFlow read error: %v", err)
+			backoff.Wait()
+			continue
+		}
+		backoff.Reset()
+		d, err := decodeSFlow(buf[:n])
+		if err != nil {
+			log.Printf("Dropping sFlow datagram from %s: %v", addr, err)
+			if d == nil {
+				continue
+			}
+		}
+		c.add(d.agent, d.flows)
+
+		for _, m := range c.counterMetrics(d.agent, d.counters, time.Now()) {
+			if err := c.storage.SaveMetric(m); err != nil {
+				log.Printf("Error saving metric for %s/%s: %v", m.DeviceName, m.InterfaceName, err)
+			}
+		}
+	}
+}
+
+// counterMetrics turns sFlow counter samples into interface metrics, with
+// rates computed against the previous sample. Devices that are polled over
+// SNMP keep their SNMP metrics and their counter samples are ignored.
+func (c *Collector) counterMetrics(agent string, counters []ifCounters, now time.Time) []models.InterfaceMetric {
+	if len(counters) == 0 {
+		return nil
+	}
+	dev, known := c.devices.Lookup(agent)
+	if known && dev.PolledBySNMP() {
+		return nil
+	}
+	device := agent
+	if known {
+		device = dev.Name
+	}
+
+	metrics := make([]models.InterfaceMetric, 0, len(counters))
+	c.mu.Lock()
+	for _, ctr := range counters {
+		m := models.InterfaceMetric{
+			DeviceName: device,
+			Timestamp:  now,
+			InOctets:   ctr.inOctets,
+			OutOctets:  ctr.outOctets,
+			Status:     "down",
+		}
+		if ctr.operUp {
+			m.Status = "up"
+		}
+		key := fmt.Sprintf("%s/%d", device, ctr.index)
+		if last, ok := c.counters[key]; ok {
+			if duration := now.Sub(last.time).Seconds(); duration > 0 {
+				if m.InOctets >= last.inOctets {
+					m.InSpeed = float64(m.InOctets-last.inOctets) * 8 / duration
+				}
+				if m.OutOctets >= last.outOctets {
+					m.OutSpeed = float64(m.OutOctets-last.outOctets) * 8 / duration
+				}
+			}
+		}
+		c.counters[key] = ifCounterState{inOctets: m.InOctets, outOctets: m.OutOctets, time: now}
+		metrics = append(metrics, m)
+	}
+	c.mu.Unlock()
+
+	for i, ctr := range counters {
+		if known {
+			metrics[i].InterfaceName = c.interfaceName(dev, ctr.index)
+		} else {
+			metrics[i].InterfaceName = indexName(ctr.index)
+		}
+	}
+	return metrics
+}
+
+func (c *Collector) add(exporter string, flows []flow) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, f := range flows {
+		key := summaryKey{
+			exporter: exporter,
+			inIf:     f.inIf,
+			outIf:    f.outIf,
+			src:      f.src.String(),
+			dst:      f.dst.String(),
+			protocol: int(f.protocol),
+			port:     servicePort(f),
+		}
+		s, ok := c.current[key]
+		if !ok {
+			s = &models.FlowSummary{SrcAddr: key.src, DstAddr: key.dst, Protocol: key.protocol, Port: key.port}
+			c.current[key] = s
+		}
+		s.Bytes += f.bytes
+		s.Packets += f.packets
+	}
+}
+
+// servicePort guesses the server side of a TCP/UDP flow as the lower port.
+func servicePort(f flow) int {
+	if f.protocol != 6 && f.protocol != 17 && f.protocol != 132 {
+		return 0
+	}
+	if f.srcPort != 0 && f.srcPort < f.dstPort {
+		return int(f.srcPort)
+	}
+	return int(f.dstPort)
+}
+
+func (c *Collector) flushLoop() {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	lastPurge := time.Time{}
+
+	start := time.Now()
+	for now := range ticker.C {
+		c.flush(start)
+		start = now
+
+		if now.Sub(lastPurge) >= time.Hour {
+			lastPurge = now
+			c.namesMu.Lock()
+			c.ifNames = make(map[string]map[int]string)
+			c.namesMu.Unlock()
+			deleted, err := c.storage.DeleteFlowsBefore(now.Add(-c.retention))
+			if err != nil {
+				log.Printf("Error purging flow summaries: %v", err)
+			} else if deleted > 0 {
+				log.Printf("Purged %d flow summaries older than %s", deleted, c.retention)
+			}
+		}
+	}
+}
+
+func (c *Collector) flush(start time.Time) {
+	c.mu.Lock()
+	current := c.current
+	c.current = make(map[summaryKey]*models.FlowSummary)
+	c.mu.Unlock()
+	if len(current) == 0 {
+		return
+	}
+
+	summaries := make([]models.FlowSummary, 0, len(current))
+	for key, s := range current {
+		s.Timestamp = start
+		s.DeviceName = key.exporter
+		if dev, ok := c.devices.Lookup(key.exporter); ok {
+			s.DeviceName = dev.Name
+			s.InInterface = c.interfaceName(dev, key.inIf)
+			s.OutInterface = c.interfaceName(dev, key.outIf)
+		} else {
+			s.InInterface = indexName(key.inIf)
+			s.OutInterface = indexName(key.outIf)
+		}
+		summaries = append(summaries, *s)
+	}
+	if err := c.storage.SaveFlowSummaries(summaries); err != nil {
+		log.Printf("Error saving %d flow summaries: %v", len(summaries), err)
+	}
+}
+
+// interfaceName resolves an exporter's ifIndex over SNMP. Results, including
+// failures, are cached until the next hourly purge.
+func (c *Collector) interfaceName(dev models.DeviceConfig, index int) string {
+	if index == 0 {
+		return ""
+	}
+	if !dev.PolledBySNMP() {
+		return indexName(index)
+	}
+	c.namesMu.Lock()
+	defer c.namesMu.Unlock()
+	names, ok := c.ifNames[dev.Name]
+	if !ok {
+		names = make(map[int]string)
+		c.ifNames[dev.Name] = names
+	}
+	if name, ok := names[index]; ok {
+		return name
+	}
+	name, err := poller.NewSNMPPoller(dev).InterfaceName(index)
+	if err != nil {
+		log.Printf("Failed to resolve ifIndex %d on %s: %v", index, dev.Name, err)
+		name = indexName(index)
+	}
+	names[index] = name
+	return name
+}
+
+func indexName(index int) string {
+	if index == 0 {
+		return ""
+	}
+	return fmt.Sprintf("ifIndex %d", index)
+}
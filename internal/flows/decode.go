@@ -0,0 +1,292 @@
+// Package flows collects NetFlow v5/v9 and IPFIX exports, e.g. from MikroTik
+// Traffic-Flow, and aggregates them into per-interval traffic summaries.
+package flows
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// flow is a single decoded flow record, with bytes and packets already scaled
+// up by the sampling interval.
+type flow struct {
+	inIf, outIf      int
+	src, dst         net.IP
+	protocol         uint8
+	srcPort, dstPort uint16
+	bytes, packets   uint64
+}
+
+// Information elements shared by NetFlow v9 and IPFIX.
+const (
+	fieldInBytes          = 1
+	fieldInPkts           = 2
+	fieldProtocol         = 4
+	fieldL4SrcPort        = 7
+	fieldIPv4SrcAddr      = 8
+	fieldInputSNMP        = 10
+	fieldL4DstPort        = 11
+	fieldIPv4DstAddr      = 12
+	fieldOutputSNMP       = 14
+	fieldOutBytes         = 23
+	fieldOutPkts          = 24
+	fieldIPv6SrcAddr      = 27
+	fieldIPv6DstAddr      = 28
+	fieldSamplingInterval = 34
+	fieldOctetTotalCount  = 85
+	fieldPacketTotalCount = 86
+)
+
+type templateField struct {
+	id     uint16
+	length uint16 // 65535 marks an IPFIX variable-length field
+}
+
+type templateKey struct {
+	exporter string
+	domain   uint32 // v9 source ID or IPFIX observation domain
+	id       uint16
+}
+
+// decoder keeps the v9/IPFIX templates announced by each exporter. Data
+// records that arrive before their template are dropped.
+type decoder struct {
+	templates map[templateKey][]templateField
+}
+
+func newDecoder() *decoder {
+	return &decoder{templates: make(map[templateKey][]templateField)}
+}
+
+var errShortPacket = errors.New("packet too short")
+
+func (d *decoder) decode(data []byte, exporter string) ([]flow, error) {
+	if len(data) < 4 {
+		return nil, errShortPacket
+	}
+	switch version := binary.BigEndian.Uint16(data); version {
+	case 5:
+		return decodeV5(data)
+	case 9:
+		return d.decodeV9(data, exporter)
+	case 10:
+		return d.decodeIPFIX(data, exporter)
+	default:
+		return nil, fmt.Errorf("unsupported flow export version %d", version)
+	}
+}
+
+func decodeV5(data []byte) ([]flow, error) {
+	const headerLen, recordLen = 24, 48
+	if len(data) < headerLen {
+		return nil, errShortPacket
+	}
+	count := int(binary.BigEndian.Uint16(data[2:]))
+	if len(data) < headerLen+count*recordLen {
+		return nil, errShortPacket
+	}
+	// The top two bits are the sampling mode, the rest the interval.
+	sampling := uint64(binary.BigEndian.Uint16(data[22:]) & 0x3fff)
+	if sampling == 0 {
+		sampling = 1
+	}
+
+	flows := make([]flow, 0, count)
+	for i := 0; i < count; i++ {
+		r := data[headerLen+i*recordLen:]
+		flows = append(flows, flow{
+			src:      net.IP(append([]byte(nil), r[0:4]...)),
+			dst:      net.IP(append([]byte(nil), r[4:8]...)),
+			inIf:     int(binary.BigEndian.Uint16(r[12:])),
+			outIf:    int(binary.BigEndian.Uint16(r[14:])),
+			packets:  uint64(binary.BigEndian.Uint32(r[16:])) * sampling,
+			bytes:    uint64(binary.BigEndian.Uint32(r[20:])) * sampling,
+			srcPort:  binary.BigEndian.Uint16(r[32:]),
+			dstPort:  binary.BigEndian.Uint16(r[34:]),
+			protocol: r[38],
+		})
+	}
+	return flows, nil
+}
+
+func (d *decoder) decodeV9(data []byte, exporter string) ([]flow, error) {
+	const headerLen = 20
+	if len(data) < headerLen {
+		return nil, errShortPacket
+	}
+	domain := binary.BigEndian.Uint32(data[16:])
+	// FlowSet IDs: 0 template, 1 options template, >= 256 data.
+	return d.decodeSets(data[headerLen:], exporter, domain, 0, false)
+}
+
+func (d *decoder) decodeIPFIX(data []byte, exporter string) ([]flow, error) {
+	const headerLen = 16
+	if len(data) < headerLen {
+		return nil, errShortPacket
+	}
+	length := int(binary.BigEndian.Uint16(data[2:]))
+	if length < headerLen || length > len(data) {
+		return nil, errShortPacket
+	}
+	domain := binary.BigEndian.Uint32(data[12:])
+	// Set IDs: 2 template, 3 options template, >= 256 data.
+	return d.decodeSets(data[headerLen:length], exporter, domain, 2, true)
+}
+
+// decodeSets walks the FlowSets (v9) or Sets (IPFIX) of a packet. They share a
+// layout and differ only in the template set ID and IPFIX's enterprise and
+// variable-length fields.
+func (d *decoder) decodeSets(data []byte, exporter string, domain uint32, templateSet uint16, ipfix bool) ([]flow, error) {
+	var flows []flow
+	for len(data) >= 4 {
+		id := binary.BigEndian.Uint16(data)
+		length := int(binary.BigEndian.Uint16(data[2:]))
+		if length < 4 || length > len(data) {
+			return flows, errShortPacket
+		}
+		body := data[4:length]
+		data = data[length:]
+
+		switch {
+		case id == templateSet:
+			if err := d.parseTemplates(body, exporter, domain, ipfix); err != nil {
+				return flows, err
+			}
+		case id >= 256:
+			fields, ok := d.templates[templateKey{exporter, domain, id}]
+			if !ok {
+				continue
+			}
+			flows = append(flows, decodeRecords(body, fields, ipfix)...)
+		}
+	}
+	return flows, nil
+}
+
+func (d *decoder) parseTemplates(body []byte, exporter string, domain uint32, ipfix bool) error {
+	for len(body) >= 4 {
+		id := binary.BigEndian.Uint16(body)
+		count := int(binary.BigEndian.Uint16(body[2:]))
+		body = body[4:]
+		if id < 256 {
+			// Padding at the end of the set.
+			return nil
+		}
+
+		fields := make([]templateField, 0, count)
+		for i := 0; i < count; i++ {
+			if len(body) < 4 {
+				return errShortPacket
+			}
+			f := templateField{id: binary.BigEndian.Uint16(body), length: binary.BigEndian.Uint16(body[2:])}
+			body = body[4:]
+			if ipfix && f.id&0x8000 != 0 {
+				// Enterprise-specific element: skip the enterprise number and
+				// make sure the ID can't collide with a standard element.
+				if len(body) < 4 {
+					return errShortPacket
+				}
+				body = body[4:]
+				f.id = 0
+			}
+			fields = append(fields, f)
+		}
+		d.templates[templateKey{exporter, domain, id}] = fields
+	}
+	return nil
+}
+
+func decodeRecords(body []byte, fields []templateField, ipfix bool) []flow {
+	minLen := 0
+	for _, f := range fields {
+		if f.length == 65535 {
+			minLen++
+		} else {
+			minLen += int(f.length)
+		}
+	}
+	if minLen == 0 {
+		return nil
+	}
+
+	var flows []flow
+	for len(body) >= minLen {
+		f := flow{}
+		sampling := uint64(1)
+		ok := true
+		for _, field := range fields {
+			n := int(field.length)
+			if ipfix && field.length == 65535 {
+				if len(body) < 1 {
+					ok = false
+					break
+				}
+				n, body = int(body[0]), body[1:]
+				if n == 255 {
+					if len(body) < 2 {
+						ok = false
+						break
+					}
+					n, body = int(binary.BigEndian.Uint16(body)), body[2:]
+				}
+			}
+			if len(body) < n {
+				ok = false
+				break
+			}
+			value := body[:n]
+			body = body[n:]
+
+			switch field.id {
+			case fieldInBytes, fieldOutBytes, fieldOctetTotalCount:
+				if f.bytes == 0 {
+					f.bytes = readUint(value)
+				}
+			case fieldInPkts, fieldOutPkts, fieldPacketTotalCount:
+				if f.packets == 0 {
+					f.packets = readUint(value)
+				}
+			case fieldProtocol:
+				f.protocol = uint8(readUint(value))
+			case fieldL4SrcPort:
+				f.srcPort = uint16(readUint(value))
+			case fieldL4DstPort:
+				f.dstPort = uint16(readUint(value))
+			case fieldIPv4SrcAddr, fieldIPv6SrcAddr:
+				f.src = net.IP(append([]byte(nil), value...))
+			case fieldIPv4DstAddr, fieldIPv6DstAddr:
+				f.dst = net.IP(append([]byte(nil), value...))
+			case fieldInputSNMP:
+				f.inIf = int(readUint(value))
+			case fieldOutputSNMP:
+				f.outIf = int(readUint(value))
+			case fieldSamplingInterval:
+				if s := readUint(value); s > 1 {
+					sampling = s
+				}
+			}
+		}
+		if !ok {
+			break
+		}
+		if f.src == nil || f.dst == nil {
+			continue
+		}
+		f.bytes *= sampling
+		f.packets *= sampling
+		flows = append(flows, f)
+	}
+	return flows
+}
+
+// readUint decodes a big-endian unsigned integer of up to 8 bytes; the v9 and
+// IPFIX exporters may use reduced-size encoding for counters.
+func readUint(b []byte) uint64 {
+	var v uint64
+	for i := 0; i < len(b) && i < 8; i++ {
+		v = v<<8 | uint64(b[i])
+	}
+	return v
+}
@@ -0,0 +1,119 @@
+package flows
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/AMathur20/Home_Network/internal/models"
+)
+
+func replay(t *testing.T, d *decoder, files ...string) []flow {
+	t.Helper()
+	var flows []flow
+	for _, name := range files {
+		data, err := os.ReadFile(filepath.Join("testdata", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := d.decode(data, "192.168.88.1")
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		flows = append(flows, decoded...)
+	}
+	return flows
+}
+
+func TestDecodeV5(t *testing.T) {
+	flows := replay(t, newDecoder(), "netflow_v5.bin")
+	if len(flows) != 2 {
+		t.Fatalf("Expected 2 flows, got %d", len(flows))
+	}
+	f := flows[0]
+	if f.src.String() != "192.168.88.10" || f.dst.String() != "142.250.1.1" || f.protocol != 6 ||
+		f.srcPort != 51000 || f.dstPort != 443 || f.inIf != 2 || f.outIf != 1 || f.bytes != 5000 || f.packets != 10 {
+		t.Errorf("Unexpected v5 flow %+v", f)
+	}
+}
+
+func TestDecodeV9(t *testing.T) {
+	d := newDecoder()
+	if flows := replay(t, d, "netflow_v9_data.bin"); len(flows) != 0 {
+		t.Errorf("Expected data before its template to be dropped, got %+v", flows)
+	}
+	flows := replay(t, d, "netflow_v9_template.bin", "netflow_v9_data.bin")
+	if len(flows) != 2 {
+		t.Fatalf("Expected 2 flows, got %d", len(flows))
+	}
+	if f := flows[0]; f.dst.String() != "1.1.1.1" || f.protocol != 17 || f.dstPort != 53 || f.bytes != 300 || f.packets != 4 {
+		t.Errorf("Unexpected v9 flow %+v", f)
+	}
+}
+
+func TestDecodeIPFIX(t *testing.T) {
+	flows := replay(t, newDecoder(), "ipfix.bin")
+	if len(flows) != 1 {
+		t.Fatalf("Expected 1 flow, got %d", len(flows))
+	}
+	if f := flows[0]; f.src.String() != "2001:db8::10" || f.dst.String() != "2606:4700::1111" ||
+		f.dstPort != 443 || f.bytes != 90000 || f.packets != 60 || f.inIf != 2 {
+		t.Errorf("Unexpected IPFIX flow %+v", f)
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	c := NewCollector(models.FlowConfig{}, nil, nil)
+	d := newDecoder()
+	c.add("192.168.88.1", replay(t, d, "netflow_v5.bin", "netflow_v9_template.bin", "netflow_v9_data.bin"))
+
+	// Both directions of the HTTPS session fold onto port 443; the upload in
+	// the v5 and v9 exports is summed.
+	up := c.current[summaryKey{"192.168.88.1", 2, 1, "192.168.88.10", "142.250.1.1", 6, 443}]
+	down := c.current[summaryKey{"192.168.88.1", 1, 2, "142.250.1.1", "192.168.88.10", 6, 443}]
+	if up == nil || up.Bytes != 12000 || up.Packets != 22 {
+		t.Errorf("Unexpected upload summary %+v", up)
+	}
+	if down == nil || down.Bytes != 30000 {
+		t.Errorf("Unexpected download summary %+v", down)
+	}
+	if len(c.current) != 3 {
+		t.Errorf("Expected 3 summaries, got %d", len(c.current))
+	}
+}
+
+func TestDecodeSFlow(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "sflow.bin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := decodeSFlow(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.agent != "192.168.88.2" || len(d.flows) != 1 || len(d.counters) != 1 {
+		t.Fatalf("Unexpected datagram %+v", d)
+	}
+
+	// One sampled 1514-byte frame at 1-in-512 stands for 512 frames.
+	f := d.flows[0]
+	if f.src.String() != "192.168.30.5" || f.dst.String() != "93.184.216.34" || f.protocol != 6 ||
+		f.srcPort != 50123 || f.dstPort != 443 || f.inIf != 3 || f.outIf != 5 || f.bytes != 1514*512 || f.packets != 512 {
+		t.Errorf("Unexpected sFlow flow %+v", f)
+	}
+
+	ctr := d.counters[0]
+	if ctr.index != 5 || !ctr.operUp || ctr.speed != 1000000000 || ctr.inOctets != 123456789 || ctr.outOctets != 987654321 {
+		t.Errorf("Unexpected counters %+v", ctr)
+	}
+
+	c := NewCollector(models.FlowConfig{}, nil, nil)
+	now := time.Now()
+	c.counterMetrics(d.agent, d.counters, now)
+	d.counters[0].inOctets += 1250000
+	metrics := c.counterMetrics(d.agent, d.counters, now.Add(10*time.Second))
+	if len(metrics) != 1 || metrics[0].InSpeed != 1000000 || metrics[0].InterfaceName != "ifIndex 5" || metrics[0].Status != "up" {
+		t.Errorf("Unexpected metrics %+v", metrics)
+	}
+}
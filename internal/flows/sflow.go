@@ -0,0 +1,254 @@
+package flows
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+)
+
+// ifCounters is an sFlow generic interface counters record.
+type ifCounters struct {
+	index     int
+	speed     uint64 // bps
+	operUp    bool
+	inOctets  uint64
+	outOctets uint64
+}
+
+// sflowDatagram is the content of one sFlow v5 datagram that HNM uses.
+type sflowDatagram struct {
+	agent    string
+	flows    []flow
+	counters []ifCounters
+}
+
+// xdr reads the big-endian, 4-byte aligned fields sFlow is encoded in.
+type xdr struct {
+	data []byte
+	err  error
+}
+
+func (r *xdr) bytes(n int) []byte {
+	padded := (n + 3) &^ 3
+	if r.err != nil || n < 0 || len(r.data) < padded {
+		r.err = errShortPacket
+		return nil
+	}
+	b := r.data[:n]
+	r.data = r.data[padded:]
+	return b
+}
+
+func (r *xdr) uint32() uint32 {
+	if b := r.bytes(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (r *xdr) uint64() uint64 {
+	if b := r.bytes(8); b != nil {
+		return binary.BigEndian.Uint64(b)
+	}
+	return 0
+}
+
+// sub returns a reader for the next length-prefixed structure.
+func (r *xdr) sub() *xdr {
+	n := int(r.uint32())
+	return &xdr{data: r.bytes(n), err: r.err}
+}
+
+func decodeSFlow(data []byte) (*sflowDatagram, error) {
+	r := &xdr{data: data}
+	version := r.uint32()
+	if r.err != nil {
+		return nil, r.err
+	}
+	if version != 5 {
+		return nil, fmt.Errorf("unsupported sFlow version %d", version)
+	}
+
+	d := &sflowDatagram{}
+	switch r.uint32() {
+	case 1:
+		d.agent = net.IP(append([]byte(nil), r.bytes(4)...)).String()
+	case 2:
+		d.agent = net.IP(append([]byte(nil), r.bytes(16)...)).String()
+	default:
+		return nil, fmt.Errorf("unknown sFlow agent address type")
+	}
+	r.uint32() // sub-agent ID
+	r.uint32() // sequence number
+	r.uint32() // uptime
+	samples := int(r.uint32())
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	for i := 0; i < samples && r.err == nil; i++ {
+		format := r.uint32()
+		s := r.sub()
+		switch format {
+		case 1, 3: // flow sample, expanded flow sample
+			d.flows = append(d.flows, decodeFlowSample(s, format == 3)...)
+		case 2, 4: // counter sample, expanded counter sample
+			d.counters = append(d.counters, decodeCounterSample(s, format == 4)...)
+		}
+		// Other enterprises' sample formats are skipped.
+	}
+	return d, r.err
+}
+
+func decodeFlowSample(r *xdr, expanded bool) []flow {
+	r.uint32() // sequence number
+	r.uint32() // source ID
+	if expanded {
+		r.uint32()
+	}
+	rate := uint64(r.uint32())
+	if rate == 0 {
+		rate = 1
+	}
+	r.uint32() // sample pool
+	r.uint32() // drops
+	var in, out int
+	if expanded {
+		// format and value words; format 0 is a single ifIndex
+		inFormat, inValue := r.uint32(), r.uint32()
+		outFormat, outValue := r.uint32(), r.uint32()
+		if inFormat == 0 {
+			in = int(inValue)
+		}
+		if outFormat == 0 {
+			out = int(outValue)
+		}
+	} else {
+		// top two bits are the format, the rest the ifIndex
+		inValue, outValue := r.uint32(), r.uint32()
+		if inValue>>30 == 0 {
+			in = int(inValue & 0x3fffffff)
+		}
+		if outValue>>30 == 0 {
+			out = int(outValue & 0x3fffffff)
+		}
+	}
+
+	var flows []flow
+	records := int(r.uint32())
+	for i := 0; i < records && r.err == nil; i++ {
+		format := r.uint32()
+		rec := r.sub()
+		if format != 1 || rec.err != nil {
+			continue
+		}
+		// Raw packet header: protocol, frame length, stripped, header.
+		protocol := rec.uint32()
+		frameLength := uint64(rec.uint32())
+		rec.uint32()
+		header := rec.bytes(int(rec.uint32()))
+		if rec.err != nil {
+			continue
+		}
+		f, ok := parseHeader(protocol, header)
+		if !ok {
+			continue
+		}
+		f.inIf, f.outIf = in, out
+		f.bytes = frameLength * rate
+		f.packets = rate
+		flows = append(flows, f)
+	}
+	return flows
+}
+
+func decodeCounterSample(r *xdr, expanded bool) []ifCounters {
+	r.uint32() // sequence number
+	r.uint32() // source ID
+	if expanded {
+		r.uint32()
+	}
+
+	var counters []ifCounters
+	records := int(r.uint32())
+	for i := 0; i < records && r.err == nil; i++ {
+		format := r.uint32()
+		rec := r.sub()
+		if format != 1 || rec.err != nil {
+			continue
+		}
+		// Generic interface counters (RFC 2233 based).
+		c := ifCounters{index: int(rec.uint32())}
+		rec.uint32() // ifType
+		c.speed = rec.uint64()
+		rec.uint32() // ifDirection
+		c.operUp = rec.uint32()&2 != 0
+		c.inOctets = rec.uint64()
+		rec.bytes(6 * 4) // in ucast, mcast, bcast, discards, errors, unknown protos
+		c.outOctets = rec.uint64()
+		if rec.err == nil {
+			counters = append(counters, c)
+		}
+	}
+	return counters
+}
+
+// parseHeader extracts addresses, protocol and ports from a sampled Ethernet,
+// IPv4 or IPv6 header.
+func parseHeader(protocol uint32, b []byte) (flow, bool) {
+	const (
+		headerEthernet = 1
+		headerIPv4     = 11
+		headerIPv6     = 12
+	)
+	if protocol == headerEthernet {
+		if len(b) < 14 {
+			return flow{}, false
+		}
+		etherType := binary.BigEndian.Uint16(b[12:])
+		b = b[14:]
+		for (etherType == 0x8100 || etherType == 0x88a8) && len(b) >= 4 {
+			etherType = binary.BigEndian.Uint16(b[2:])
+			b = b[4:]
+		}
+		switch etherType {
+		case 0x0800:
+			protocol = headerIPv4
+		case 0x86dd:
+			protocol = headerIPv6
+		default:
+			return flow{}, false
+		}
+	}
+
+	var f flow
+	var l4 []byte
+	switch protocol {
+	case headerIPv4:
+		if len(b) < 20 {
+			return flow{}, false
+		}
+		ihl := int(b[0]&0x0f) * 4
+		f.protocol = b[9]
+		f.src = net.IP(append([]byte(nil), b[12:16]...))
+		f.dst = net.IP(append([]byte(nil), b[16:20]...))
+		if ihl >= 20 && len(b) > ihl {
+			l4 = b[ihl:]
+		}
+	case headerIPv6:
+		if len(b) < 40 {
+			return flow{}, false
+		}
+		f.protocol = b[6]
+		f.src = net.IP(append([]byte(nil), b[8:24]...))
+		f.dst = net.IP(append([]byte(nil), b[24:40]...))
+		l4 = b[40:]
+	default:
+		return flow{}, false
+	}
+	if (f.protocol == 6 || f.protocol == 17 || f.protocol == 132) && len(l4) >= 4 {
+		f.srcPort = binary.BigEndian.Uint16(l4)
+		f.dstPort = binary.BigEndian.Uint16(l4[2:])
+	}
+	return f, true
+}
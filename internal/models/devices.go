@@ -0,0 +1,51 @@
+package models
+
+import (
+	"log"
+	"net"
+	"sync/atomic"
+)
+
+// DevicesByAddress maps each device's IP addresses to the device, resolving
+// hostnames once. Used to attribute traps and syslog messages to a device.
+func DevicesByAddress(devices []DeviceConfig) map[string]DeviceConfig {
+	result := make(map[string]DeviceConfig)
+	for _, dev := range devices {
+		if ip := net.ParseIP(dev.Host); ip != nil {
+			result[ip.String()] = dev
+			continue
+		}
+		addrs, err := net.LookupHost(dev.Host)
+		if err != nil {
+			log.Printf("Failed to resolve %s (%s): %v", dev.Name, dev.Host, err)
+			continue
+		}
+		for _, addr := range addrs {
+			result[addr] = dev
+		}
+	}
+	return result
+}
+
+// DeviceAddresses is a DevicesByAddress map that can be replaced while in use,
+// e.g. when the config is reloaded.
+type DeviceAddresses struct {
+	m atomic.Pointer[map[string]DeviceConfig]
+}
+
+func NewDeviceAddresses(devices []DeviceConfig) *DeviceAddresses {
+	d := &DeviceAddresses{}
+	d.Set(devices)
+	return d
+}
+
+func (d *DeviceAddresses) Set(devices []DeviceConfig) {
+	m := DevicesByAddress(devices)
+	d.m.Store(&m)
+}
+
+// Lookup returns the device with the given IP address.
+func (d *DeviceAddresses) Lookup(addr string) (DeviceConfig, bool) {
+	dev, ok := (*d.m.Load())[addr]
+	return dev, ok
+}
@@ -12,33 +12,265 @@ const (
 )
 
 type Config struct {
-	Poller IntervalConfig `yaml:"poller"`
-	Devices []DeviceConfig `yaml:"devices"`
+	Poller         IntervalConfig       `yaml:"poller"`
+	Discovery      DiscoveryConfig      `yaml:"discovery"`
+	Classification []ClassificationRule `yaml:"classification,omitempty"`
+	Endpoints      EndpointConfig       `yaml:"endpoints"`
+	Wireless       WirelessConfig       `yaml:"wireless"`
+	Optics         OpticsConfig         `yaml:"optics"`
+	PoE            PoEConfig            `yaml:"poe"`
+	Traps          TrapConfig           `yaml:"traps"`
+	Syslog         SyslogConfig         `yaml:"syslog"`
+	Flows          FlowConfig           `yaml:"flows"`
+	Probes         ProbeConfig          `yaml:"probes"`
+	Security       SecurityConfig       `yaml:"security"`
+	Notifications  NotificationConfig   `yaml:"notifications"`
+	// Credentials are named profiles that devices and discovery refer to.
+	Credentials map[string]*CredentialProfile `yaml:"credentials,omitempty"`
+	// Defaults apply to every device of a type.
+	Defaults map[DeviceType]*DeviceDefaults `yaml:"defaults,omitempty"`
+	Devices  []DeviceConfig                 `yaml:"devices"`
+}
+
+// Profiles returns the credential profiles with the given names, skipping
+// unknown ones.
+func (c *Config) Profiles(names []string) []CredentialProfile {
+	profiles := make([]CredentialProfile, 0, len(names))
+	for _, name := range names {
+		if p := c.Credentials[name]; p != nil {
+			profile := *p
+			profile.Name = name
+			profiles = append(profiles, profile)
+		}
+	}
+	return profiles
 }
 
 type IntervalConfig struct {
-	Live     int `yaml:"live"`     // seconds
-	History  int `yaml:"history"`  // seconds
+	Live    int `yaml:"live"`    // seconds
+	History int `yaml:"history"` // seconds
+	// FailureThreshold is the number of consecutive failed polls before a
+	// device is considered unreachable, defaults to 3.
+	FailureThreshold int `yaml:"failure_threshold,omitempty"`
+	// MaxBackoff caps the delay between polls of an unreachable device.
+	MaxBackoff int `yaml:"max_backoff,omitempty"` // seconds, defaults to 300
+	// Priorities maps priority classes to live intervals in seconds. high
+	// defaults to 2, normal to Live and low to 30.
+	Priorities map[string]int `yaml:"priorities,omitempty"`
+}
+
+// Priority classes a device can be polled at.
+const (
+	PriorityHigh   = "high"
+	PriorityNormal = "normal"
+	PriorityLow    = "low"
+)
+
+const (
+	DiscoveryModeInitial = "initial" // Discover only when topology.yaml is empty
+	DiscoveryModeMerge   = "merge"   // Re-discover on boot and merge into topology.yaml
+)
+
+type DiscoveryConfig struct {
+	Mode      string `yaml:"mode,omitempty"`
+	Interval  int    `yaml:"interval,omitempty"` // seconds, 0 disables scheduled rediscovery
+	AutoApply bool   `yaml:"auto_apply"`         // Apply changes immediately instead of waiting for approval
+	// Credentials are the profiles tried, in order, against neighbors that
+	// aren't in the device list, so they can be crawled too.
+	Credentials []string `yaml:"credentials,omitempty"`
+}
+
+type EndpointConfig struct {
+	Disabled bool   `yaml:"disabled,omitempty"` // Skip FDB/ARP/DHCP collection
+	OUIFile  string `yaml:"oui_file,omitempty"` // Wireshark manuf or IEEE oui.txt for vendor lookup
+}
+
+type WirelessConfig struct {
+	Disabled bool `yaml:"disabled,omitempty"` // Skip registration table collection
+}
+
+type OpticsConfig struct {
+	Disabled   bool              `yaml:"disabled,omitempty"` // Skip SFP diagnostics collection
+	Thresholds OpticalThresholds `yaml:"thresholds,omitempty"`
+	// Ports overrides thresholds for single ports, keyed by "device/interface".
+	Ports map[string]OpticalThresholds `yaml:"ports,omitempty"`
+}
+
+// OpticalThresholds are the alarm limits for SFP DDM readings. Nil means
+// unset; unset fields fall back to vendor thresholds, then built-in defaults.
+// 0 is a valid limit, commonly for rx_power_high in dBm.
+type OpticalThresholds struct {
+	TempHigh    *float64 `yaml:"temp_high,omitempty"`     // °C
+	VoltageLow  *float64 `yaml:"voltage_low,omitempty"`   // V
+	VoltageHigh *float64 `yaml:"voltage_high,omitempty"`  // V
+	BiasHigh    *float64 `yaml:"bias_high,omitempty"`     // mA
+	TxPowerLow  *float64 `yaml:"tx_power_low,omitempty"`  // dBm
+	RxPowerLow  *float64 `yaml:"rx_power_low,omitempty"`  // dBm
+	RxPowerHigh *float64 `yaml:"rx_power_high,omitempty"` // dBm
+}
+
+type PoEConfig struct {
+	Disabled bool `yaml:"disabled,omitempty"` // Skip PoE collection
+}
+
+// TrapConfig enables the SNMP trap and inform receiver.
+type TrapConfig struct {
+	Enabled   bool          `yaml:"enabled,omitempty"`
+	Listen    string        `yaml:"listen,omitempty"`    // UDP address, defaults to :162
+	Community string        `yaml:"community,omitempty"` // v1/v2c community to accept; empty accepts any
+	V3        *TrapV3Config `yaml:"v3,omitempty"`
+}
+
+// TrapV3Config is the USM user traps and informs are authenticated against.
+type TrapV3Config struct {
+	USMUser `yaml:",inline"`
+	// EngineID is the hex snmpEngineID keys are localized to: the sending
+	// device's for traps, or our own for informs.
+	EngineID string `yaml:"engine_id,omitempty"`
+}
+
+// SyslogConfig enables the syslog receiver.
+type SyslogConfig struct {
+	Enabled   bool   `yaml:"enabled,omitempty"`
+	Listen    string `yaml:"listen,omitempty"`    // UDP and TCP address, defaults to :514
+	Retention int    `yaml:"retention,omitempty"` // days, defaults to 30
+}
+
+// FlowConfig enables the NetFlow v5/v9, IPFIX and sFlow collector.
+type FlowConfig struct {
+	Enabled     bool   `yaml:"enabled,omitempty"`
+	Listen      string `yaml:"listen,omitempty"`       // UDP address, defaults to :2055
+	SFlowListen string `yaml:"sflow_listen,omitempty"` // UDP address for sFlow v5, defaults to :6343
+	Interval    int    `yaml:"interval,omitempty"`     // Aggregation interval in seconds, defaults to 60
+	Retention   int    `yaml:"retention,omitempty"`    // days, defaults to 7
+}
+
+// ProbeConfig controls active reachability probes. Every device gets an ICMP
+// probe to its host and Targets adds further checks.
+type ProbeConfig struct {
+	Disabled bool          `yaml:"disabled,omitempty"`
+	Interval int           `yaml:"interval,omitempty"` // seconds, defaults to 30
+	Count    int           `yaml:"count,omitempty"`    // ICMP/TCP attempts per round, defaults to 5
+	Timeout  int           `yaml:"timeout,omitempty"`  // seconds per attempt, defaults to 2
+	Targets  []ProbeTarget `yaml:"targets,omitempty"`
+}
+
+const (
+	ProbeICMP = "icmp"
+	ProbeTCP  = "tcp"
+	ProbeHTTP = "http"
+)
+
+// ProbeTarget is a single check. Host defaults to the host of Device, so a
+// device can be given extra checks without repeating its address.
+type ProbeTarget struct {
+	Name         string `yaml:"name,omitempty"`
+	Device       string `yaml:"device,omitempty"` // Configured device whose state this probe feeds
+	Type         string `yaml:"type"`             // icmp, tcp, http
+	Host         string `yaml:"host,omitempty"`
+	Port         int    `yaml:"port,omitempty"`          // tcp
+	URL          string `yaml:"url,omitempty"`           // http
+	ExpectStatus int    `yaml:"expect_status,omitempty"` // http, defaults to any status below 400
+	Insecure     bool   `yaml:"insecure,omitempty"`      // http, skip TLS verification
+}
+
+type SecurityConfig struct {
+	KnownDevices string `yaml:"known_devices,omitempty"` // Path to the known devices file
+	FlapCount    int    `yaml:"flap_count,omitempty"`    // Port moves within FlapWindow that count as flapping
+	FlapWindow   int    `yaml:"flap_window,omitempty"`   // seconds
+	Notify       bool   `yaml:"notify,omitempty"`        // Route security events to notifications
+}
+
+type NotificationConfig struct {
+	Webhook string `yaml:"webhook,omitempty"` // URL that receives events as JSON POSTs
+}
+
+// KnownDevice is an allowlist entry. Device, Interface and VLAN are optional
+// expectations about where the MAC should appear.
+type KnownDevice struct {
+	MAC       string `yaml:"mac"`
+	Name      string `yaml:"name,omitempty"`
+	Device    string `yaml:"device,omitempty"`
+	Interface string `yaml:"interface,omitempty"`
+	VLAN      int    `yaml:"vlan,omitempty"`
+}
+
+// ClassificationRule overrides automatic link classification. Every field that
+// is set must match; the first matching rule wins.
+type ClassificationRule struct {
+	Name     string `yaml:"name,omitempty"`      // Regular expression on the interface name
+	IfType   int    `yaml:"if_type,omitempty"`   // IANAifType, e.g. 6 for ethernetCsmacd
+	MinSpeed uint64 `yaml:"min_speed,omitempty"` // Mbps
+	MaxSpeed uint64 `yaml:"max_speed,omitempty"` // Mbps
+	SFP      *bool  `yaml:"sfp,omitempty"`
+	Type     string `yaml:"type"`
 }
 
 type DeviceConfig struct {
-	Name     string     `yaml:"name"`
-	Host     string     `yaml:"host"`
-	Type     DeviceType `yaml:"type"`
-	Auth     AuthConfig `yaml:"auth"`
-	SNMP     SNMPConfig `yaml:"snmp"`
+	Name string     `yaml:"name"`
+	Host string     `yaml:"host"`
+	Type DeviceType `yaml:"type"`
+	// Credentials names the profiles the device's snmp and auth blocks are
+	// filled in from. Fields set on the device take precedence, then the
+	// profiles in order, then the defaults for its type.
+	Credentials []string   `yaml:"credentials,omitempty"`
+	Auth        AuthConfig `yaml:"auth"`
+	SNMP        SNMPConfig `yaml:"snmp"`
+	// PoEBudget is the PSE power budget in watts, for devices that don't
+	// report one over SNMP.
+	PoEBudget float64 `yaml:"poe_budget,omitempty"`
+	// Interval overrides the live polling interval in seconds. Priority picks
+	// one of the poller's priority classes instead.
+	Interval int    `yaml:"interval,omitempty"`
+	Priority string `yaml:"priority,omitempty"`
+}
+
+// PolledBySNMP reports whether the polling engine reads interface counters
+// from the device over SNMP.
+func (d DeviceConfig) PolledBySNMP() bool {
+	return d.Type == DeviceTypeMikroTik || d.SNMP.Version != ""
 }
 
+// AuthConfig holds vendor API credentials, e.g. for the RouterOS API.
 type AuthConfig struct {
 	Username string `yaml:"username,omitempty"`
 	Password string `yaml:"password,omitempty"`
+	Port     int    `yaml:"port,omitempty"` // API port, defaults to 8728 for RouterOS
 }
 
 type SNMPConfig struct {
 	Version   string `yaml:"version"` // v2c, v3
 	Community string `yaml:"community,omitempty"`
 	Port      int    `yaml:"port"`
-	// v3 fields can be added here
+
+	// USMUser is the SNMPv3 user to poll as.
+	USMUser `yaml:",inline"`
+}
+
+// USMUser is an SNMPv3 user and its keys.
+type USMUser struct {
+	Username       string `yaml:"username,omitempty"`
+	AuthProtocol   string `yaml:"auth_protocol,omitempty"` // md5, sha, sha224, sha256, sha384, sha512
+	AuthPassphrase string `yaml:"auth_passphrase,omitempty"`
+	PrivProtocol   string `yaml:"priv_protocol,omitempty"` // des, aes, aes192, aes256
+	PrivPassphrase string `yaml:"priv_passphrase,omitempty"`
+}
+
+// CredentialProfile is a named set of SNMP and API credentials.
+type CredentialProfile struct {
+	Name string     `yaml:"-"`
+	SNMP SNMPConfig `yaml:"snmp,omitempty"`
+	Auth AuthConfig `yaml:"auth,omitempty"`
+}
+
+// DeviceDefaults are the settings devices of a type get unless they set
+// their own.
+type DeviceDefaults struct {
+	Credentials []string   `yaml:"credentials,omitempty"`
+	SNMP        SNMPConfig `yaml:"snmp,omitempty"`
+	Auth        AuthConfig `yaml:"auth,omitempty"`
+	Interval    int        `yaml:"interval,omitempty"`
+	Priority    string     `yaml:"priority,omitempty"`
 }
 
 type InterfaceMetric struct {
@@ -49,5 +281,264 @@ type InterfaceMetric struct {
 	OutOctets     uint64
 	InSpeed       float64 // bps
 	OutSpeed      float64 // bps
-	Status        string  // up, down
+	Status        string  // up, down, degraded (LAG with some members down)
+	Parent        string  // LAG this interface is a member of, if any
+	MembersUp     int     // For LAG interfaces: members currently up
+	MembersTotal  int     // For LAG interfaces: configured members
+	STPState      string  // forwarding, blocking, discarding; empty if STP is not running
+	Stale         bool    // Not refreshed by the device's last poll; set by the API, not stored
+}
+
+// BridgeStatus is the bridge-wide spanning-tree state of a device.
+type BridgeStatus struct {
+	BridgeAddress  string
+	DesignatedRoot string // priority.mac of the root bridge
+	RootPort       int    // dot1dBasePort towards the root, 0 if this bridge is root
+	TopChanges     uint64 // dot1dStpTopChanges counter
+}
+
+const (
+	EventSTPRootChange     = "stp_root_change"
+	EventSTPTopologyChange = "stp_topology_change"
+	EventNewDevice         = "new_device"
+	EventUnexpectedPort    = "unexpected_port"
+	EventUnexpectedVLAN    = "unexpected_vlan"
+	EventMACFlap           = "mac_flap"
+	EventWirelessRoam      = "wireless_roam"
+	EventOpticAlarm        = "optic_alarm"
+	EventLinkDown          = "link_down"
+	EventLinkUp            = "link_up"
+	EventColdStart         = "cold_start"
+	EventWarmStart         = "warm_start"
+	EventLLDPChange        = "lldp_change"
+	EventDeviceDown        = "device_down"
+	EventDeviceUp          = "device_up"
+	EventPollUnreachable   = "poll_unreachable"
+	EventPollRecovered     = "poll_recovered"
+)
+
+// Event is a point-in-time occurrence on a device, such as an STP topology
+// change.
+type Event struct {
+	Timestamp     time.Time
+	DeviceName    string
+	InterfaceName string
+	Kind          string
+	MAC           string // Endpoint the event refers to, if any
+	Message       string
+}
+
+const (
+	TopologyEventApplied    = "applied"
+	TopologyEventPending    = "pending"
+	TopologyEventRejected   = "rejected"
+	TopologyEventSuperseded = "superseded"
+)
+
+type TopologyEvent struct {
+	Timestamp       time.Time
+	Kind            string // added, removed, changed
+	SourceDevice    string
+	SourceInterface string
+	TargetDevice    string
+	TargetInterface string
+	LinkType        string
+	Detail          string
+	Status          string // applied, pending, rejected, superseded
+}
+
+// Endpoint is a host seen in a forwarding, ARP or DHCP table. DeviceName and
+// InterfaceName identify the switch port it is attached to.
+type Endpoint struct {
+	MAC           string
+	IP            string
+	Hostname      string
+	Vendor        string
+	DeviceName    string
+	InterfaceName string
+	VLAN          int
+	FirstSeen     time.Time
+	LastSeen      time.Time
+}
+
+// WirelessClient is one sample of an associated station from an AP's
+// registration table. Zero values mean the AP did not report the field.
+type WirelessClient struct {
+	Timestamp     time.Time
+	DeviceName    string // AP
+	InterfaceName string // Radio or virtual AP interface
+	MAC           string
+	SSID          string
+	Signal        int     // dBm
+	SNR           int     // dB
+	TxRate        float64 // Mbps
+	RxRate        float64 // Mbps
+	CCQ           int     // percent
+	Uptime        int64   // seconds associated
+}
+
+// WirelessInterface is one sample of an AP radio.
+type WirelessInterface struct {
+	Timestamp          time.Time
+	DeviceName         string
+	InterfaceName      string
+	SSID               string
+	Frequency          int // MHz
+	Clients            int
+	NoiseFloor         int     // dBm
+	ChannelUtilization float64 // percent busy, -1 if unknown
+}
+
+// OpticalMetric is one SFP DDM sample. Readings the module does not report
+// are nil. Alarms lists the readings that crossed Thresholds, e.g.
+// "rx_power_low".
+type OpticalMetric struct {
+	Timestamp     time.Time
+	DeviceName    string
+	InterfaceName string
+	Temperature   *float64 // °C
+	Voltage       *float64 // V
+	BiasCurrent   *float64 // mA
+	TxPower       *float64 // dBm
+	RxPower       *float64 // dBm
+	Thresholds    OpticalThresholds
+	Alarms        []string
+}
+
+const (
+	PoEStatusDelivering = "delivering"
+	PoEStatusSearching  = "searching"
+	PoEStatusDisabled   = "disabled"
+	PoEStatusFault      = "fault"
+)
+
+// PoEPort is one sample of a PSE port. Class is the detected PD class, -1 if
+// unknown. Power, Voltage and Current are zero when the device doesn't report
+// them per port.
+type PoEPort struct {
+	Timestamp     time.Time
+	DeviceName    string
+	InterfaceName string
+	Status        string // delivering, searching, disabled, fault
+	Class         int
+	Power         float64 // W
+	Voltage       float64 // V
+	Current       float64 // mA
+}
+
+// PoEBudget is one sample of a device's total PSE budget and draw.
+type PoEBudget struct {
+	Timestamp   time.Time
+	DeviceName  string
+	Budget      float64 // W, 0 if unknown
+	Consumption float64 // W
+}
+
+// LogMessage is a syslog line received from a device. Timestamp is the
+// receive time, since device clocks are often unset or in another zone.
+type LogMessage struct {
+	Timestamp  time.Time
+	DeviceName string // Empty if the sender is not a configured device
+	Source     string // Sender IP
+	Hostname   string
+	Facility   int
+	Severity   int // 0 (emerg) to 7 (debug)
+	AppName    string
+	Message    string
+}
+
+// TimelineEntry is an event or log line on a device's timeline.
+type TimelineEntry struct {
+	Timestamp     time.Time
+	DeviceName    string
+	InterfaceName string
+	Source        string // "event" or "log"
+	Kind          string // Event kind, or the log severity name
+	Message       string
+}
+
+// FlowSummary is the traffic between two hosts on one service port during
+// one aggregation interval, as exported by a router.
+type FlowSummary struct {
+	Timestamp    time.Time // Start of the interval
+	DeviceName   string    // Exporter; its IP if it is not a configured device
+	InInterface  string
+	OutInterface string
+	SrcAddr      string
+	DstAddr      string
+	Protocol     int // IANA protocol number
+	Port         int // Service port, the lower of the two ports; 0 for ICMP
+	Bytes        uint64
+	Packets      uint64
+}
+
+// FlowTop is the traffic breakdown for an interface and time range.
+type FlowTop struct {
+	Talkers   []FlowTalker
+	Protocols []FlowProtocol
+	Ports     []FlowPort
+}
+
+type FlowTalker struct {
+	Address string
+	Bytes   uint64 // Sent and received
+	Packets uint64
+}
+
+type FlowProtocol struct {
+	Protocol int
+	Name     string
+	Bytes    uint64
+	Packets  uint64
+}
+
+type FlowPort struct {
+	Protocol int
+	Port     int
+	Bytes    uint64
+	Packets  uint64
+}
+
+// ProbeResult is the outcome of one round of a reachability probe.
+type ProbeResult struct {
+	Timestamp  time.Time
+	Name       string
+	DeviceName string // Empty for targets not tied to a device
+	Type       string
+	Target     string
+	Status     string  // up, down
+	RTT        float64 // ms, average of successful attempts
+	Jitter     float64 // ms, mean difference between consecutive RTTs
+	Loss       float64 // percent
+	Error      string  // Last failure, if any
+}
+
+// Polling states of a device.
+const (
+	DeviceStateUnknown     = "unknown"     // Not polled yet
+	DeviceStateUp          = "up"          // Last poll succeeded
+	DeviceStateDegraded    = "degraded"    // Failing, but below the failure threshold
+	DeviceStateUnreachable = "unreachable" // Failed FailureThreshold polls in a row; polled with backoff
+)
+
+// DeviceHealth is the polling health of a device.
+type DeviceHealth struct {
+	DeviceName          string
+	State               string
+	ConsecutiveFailures int
+	LastAttempt         time.Time
+	LastSuccess         time.Time
+	LastError           string
+	PollDuration        float64   // ms, of the last poll
+	NextPoll            time.Time // Set while backing off
+	Probe               string    // up or down from the reachability probes; empty if not probed
+}
+
+// ReloadStatus is the outcome of the latest reload of a watched file.
+type ReloadStatus struct {
+	File            string
+	LoadedAt        time.Time // Last successful load
+	CheckedAt       time.Time // Last attempt
+	Error           string    // Why the last attempt was rejected; empty if it was applied
+	RestartRequired []string  // Changed settings that only take effect after a restart
 }
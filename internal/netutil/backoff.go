@@ -0,0 +1,26 @@
+// Package netutil holds helpers shared by the UDP and TCP listeners.
+package netutil
+
+import "time"
+
+// Backoff spaces out the retries of a receive loop after read or accept
+// errors, so a persistent error doesn't spin and flood the log. The delay
+// doubles from 5ms up to a second, as net/http does for Accept errors.
+type Backoff struct {
+	delay time.Duration
+}
+
+// Wait sleeps before the next read after an error.
+func (b *Backoff) Wait() {
+	if b.delay == 0 {
+		b.delay = 5 * time.Millisecond
+	} else if b.delay *= 2; b.delay > time.Second {
+		b.delay = time.Second
+	}
+	time.Sleep(b.delay)
+}
+
+// Reset is called after a successful read.
+func (b *Backoff) Reset() {
+	b.delay = 0
+}
@@ -0,0 +1,43 @@
+// Package notify delivers events to external notification channels.
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/AMathur20/Home_Network/internal/models"
+)
+
+type Notifier interface {
+	Notify(e models.Event) error
+}
+
+// Webhook POSTs each event as JSON to a URL, e.g. a Home Assistant, ntfy or
+// Gotify endpoint.
+type Webhook struct {
+	url    string
+	client *http.Client
+}
+
+func NewWebhook(url string) *Webhook {
+	return &Webhook{url: url, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (w *Webhook) Notify(e models.Event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	resp, err := w.client.Post(w.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+// Package oui resolves the vendor of a MAC address from its OUI prefix.
+package oui
+
+import (
+	"bufio"
+	"os"
+	"strings"
+	"sync"
+)
+
+// builtin covers vendors commonly found on home networks. A full table can be
+// loaded from a Wireshark "manuf" or IEEE "oui.txt" file with Load.
+var builtin = map[string]string{
+	"000C42": "MikroTik",
+	"4C5E0C": "MikroTik",
+	"6C3B6B": "MikroTik",
+	"B869F4": "MikroTik",
+	"CC2DE0": "MikroTik",
+	"D4CA6D": "MikroTik",
+	"E48D8C": "MikroTik",
+	"48A98A": "MikroTik",
+	"002722": "Ubiquiti",
+	"0418D6": "Ubiquiti",
+	"245A4C": "Ubiquiti",
+	"68D79A": "Ubiquiti",
+	"74ACB9": "Ubiquiti",
+	"788A20": "Ubiquiti",
+	"F09FC2": "Ubiquiti",
+	"FCECDA": "Ubiquiti",
+	"001132": "Synology",
+	"245EBE": "QNAP",
+	"B827EB": "Raspberry Pi",
+	"DCA632": "Raspberry Pi",
+	"E45F01": "Raspberry Pi",
+	"D83ADD": "Raspberry Pi",
+	"18B430": "Nest",
+	"F4F5D8": "Google",
+	"3C5AB4": "Google",
+	"F0272D": "Amazon",
+	"44650D": "Amazon",
+	"B0BE76": "TP-Link",
+	"50C7BF": "TP-Link",
+	"000C29": "VMware",
+	"005056": "VMware",
+	"525400": "QEMU/KVM",
+	"0242AC": "Docker",
+	"001B63": "Apple",
+	"3C0754": "Apple",
+	"F0D1A9": "Apple",
+	"ACBC32": "Apple",
+	"001632": "Samsung",
+	"8C8590": "Apple",
+	"B4FBE4": "Ubiquiti",
+	"00E04C": "Realtek",
+	"B0AA77": "Cisco",
+}
+
+var (
+	mu     sync.RWMutex
+	loaded = map[string]string{}
+)
+
+// Lookup returns the vendor for mac, "Private" for locally administered
+// (randomized) addresses, or "" if unknown.
+func Lookup(mac string) string {
+	prefix := normalize(mac)
+	if len(prefix) < 6 {
+		return ""
+	}
+	prefix = prefix[:6]
+
+	mu.RLock()
+	vendor, ok := loaded[prefix]
+	mu.RUnlock()
+	if ok {
+		return vendor
+	}
+	if vendor, ok := builtin[prefix]; ok {
+		return vendor
+	}
+
+	// Second-least-significant bit of the first octet marks a locally
+	// administered address, as used by phones with MAC randomization.
+	if strings.ContainsRune("2367ABEF", rune(prefix[1])) {
+		return "Private"
+	}
+	return ""
+}
+
+// Load reads an OUI database in Wireshark manuf ("00:0C:42<tab>Routerbo<tab>MikroTik")
+// or IEEE oui.txt ("00-0C-42   (hex)\t\tRouterboard.com") format.
+func Load(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	entries := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if strings.Contains(line, "(hex)") {
+			parts := strings.SplitN(line, "(hex)", 2)
+			prefix := normalize(parts[0])
+			if len(prefix) == 6 {
+				entries[prefix] = strings.TrimSpace(parts[1])
+			}
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		if len(fields) < 2 {
+			continue
+		}
+		prefix := normalize(fields[0])
+		if len(prefix) != 6 {
+			// Skip /28 and /36 assignments
+			continue
+		}
+		vendor := strings.TrimSpace(fields[len(fields)-1])
+		entries[prefix] = vendor
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	mu.Lock()
+	loaded = entries
+	mu.Unlock()
+	return nil
+}
+
+func normalize(mac string) string {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(mac) {
+		if (r >= '0' && r <= '9') || (r >= 'A' && r <= 'F') {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
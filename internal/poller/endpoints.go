@@ -0,0 +1,302 @@
+package poller
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AMathur20/Home_Network/internal/models"
+	"github.com/AMathur20/Home_Network/internal/oui"
+	"github.com/AMathur20/Home_Network/internal/routeros"
+	"github.com/AMathur20/Home_Network/internal/snmp"
+	"github.com/AMathur20/Home_Network/internal/topology"
+	"github.com/gosnmp/gosnmp"
+)
+
+const (
+	// BRIDGE-MIB / Q-BRIDGE-MIB forwarding tables
+	oidDot1dTpFdbPort   = ".1.3.6.1.2.1.17.4.3.1.2"
+	oidDot1dTpFdbStatus = ".1.3.6.1.2.1.17.4.3.1.3"
+	oidDot1qTpFdbPort   = ".1.3.6.1.2.1.17.7.1.2.2.1.2"
+	oidDot1qTpFdbStatus = ".1.3.6.1.2.1.17.7.1.2.2.1.3"
+
+	// IP-MIB ARP tables
+	oidIpNetToPhysicalPhysAddress = ".1.3.6.1.2.1.4.35.1.4"
+	oidIpNetToMediaPhysAddress    = ".1.3.6.1.2.1.4.22.1.2"
+
+	fdbStatusSelf = 4
+)
+
+type fdbEntry struct {
+	MAC       string
+	Interface string
+	VLAN      int
+}
+
+type arpEntry struct {
+	MAC       string
+	IP        string
+	Interface string
+}
+
+type leaseEntry struct {
+	MAC      string
+	IP       string
+	Hostname string
+}
+
+// endpointTables is the raw endpoint data read from one device.
+type endpointTables struct {
+	device string
+	fdb    []fdbEntry
+	arp    []arpEntry
+	leases []leaseEntry
+}
+
+// PollEndpoints reads the bridge forwarding and ARP tables over SNMP, plus DHCP
+// leases over the RouterOS API on MikroTik devices with credentials.
+func (p *SNMPPoller) PollEndpoints() (*endpointTables, error) {
+	params, err := p.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer params.Conn.Close()
+
+	ifNames := make(map[int]string)
+	err = params.BulkWalk(oidIfName, func(pdu gosnmp.SnmpPDU) error {
+		index := 0
+		fmt.Sscanf(pdu.Name, oidIfName+".%d", &index)
+		ifNames[index] = models.PduToString(pdu.Value)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk ifName: %v", err)
+	}
+
+	tables := &endpointTables{device: p.config.Name}
+
+	// 1. Forwarding database. Prefer the VLAN-aware Q-BRIDGE table.
+	portNames := make(map[int]string)
+	for port, ifIndex := range snmp.BridgePorts(params) {
+		portNames[port] = ifNames[ifIndex]
+	}
+
+	tables.fdb = walkFdb(params, oidDot1qTpFdbPort, oidDot1qTpFdbStatus, true, portNames)
+	if len(tables.fdb) == 0 {
+		tables.fdb = walkFdb(params, oidDot1dTpFdbPort, oidDot1dTpFdbStatus, false, portNames)
+	}
+
+	// 2. ARP. Prefer ipNetToPhysicalTable, fall back to the deprecated ipNetToMediaTable.
+	params.BulkWalk(oidIpNetToPhysicalPhysAddress, func(pdu gosnmp.SnmpPDU) error {
+		// Index: ifIndex.addrType.addrLen.a.b.c.d
+		parts := strings.Split(strings.TrimPrefix(pdu.Name, oidIpNetToPhysicalPhysAddress+"."), ".")
+		if len(parts) != 7 || parts[1] != "1" || parts[2] != "4" {
+			return nil
+		}
+		index, _ := strconv.Atoi(parts[0])
+		tables.arp = append(tables.arp, arpEntry{
+			MAC:       snmp.FormatMAC(pdu.Value),
+			IP:        strings.Join(parts[3:], "."),
+			Interface: ifNames[index],
+		})
+		return nil
+	})
+	if len(tables.arp) == 0 {
+		params.BulkWalk(oidIpNetToMediaPhysAddress, func(pdu gosnmp.SnmpPDU) error {
+			// Index: ifIndex.a.b.c.d
+			parts := strings.Split(strings.TrimPrefix(pdu.Name, oidIpNetToMediaPhysAddress+"."), ".")
+			if len(parts) != 5 {
+				return nil
+			}
+			index, _ := strconv.Atoi(parts[0])
+			tables.arp = append(tables.arp, arpEntry{
+				MAC:       snmp.FormatMAC(pdu.Value),
+				IP:        strings.Join(parts[1:], "."),
+				Interface: ifNames[index],
+			})
+			return nil
+		})
+	}
+
+	// 3. DHCP leases
+	if p.config.Type == models.DeviceTypeMikroTik && p.config.Auth.Username != "" {
+		leases, err := pollLeasesRouterOS(p.config)
+		if err != nil {
+			return tables, fmt.Errorf("failed to read DHCP leases: %v", err)
+		}
+		tables.leases = leases
+	}
+
+	return tables, nil
+}
+
+// walkFdb reads a forwarding table indexed by MAC, or by fdbId.MAC when
+// vlanIndexed is set.
+func walkFdb(params *gosnmp.GoSNMP, portOid, statusOid string, vlanIndexed bool, portNames map[int]string) []fdbEntry {
+	self := make(map[string]bool)
+	params.BulkWalk(statusOid, func(pdu gosnmp.SnmpPDU) error {
+		if models.PduToInt(pdu.Value) == fdbStatusSelf {
+			self[strings.TrimPrefix(pdu.Name, statusOid+".")] = true
+		}
+		return nil
+	})
+
+	var entries []fdbEntry
+	params.BulkWalk(portOid, func(pdu gosnmp.SnmpPDU) error {
+		suffix := strings.TrimPrefix(pdu.Name, portOid+".")
+		port := models.PduToInt(pdu.Value)
+		if self[suffix] || port == 0 || portNames[port] == "" {
+			return nil
+		}
+
+		parts := strings.Split(suffix, ".")
+		vlan := 0
+		if vlanIndexed {
+			if len(parts) != 7 {
+				return nil
+			}
+			vlan, _ = strconv.Atoi(parts[0])
+			parts = parts[1:]
+		}
+		mac, ok := macFromOid(parts)
+		if !ok {
+			return nil
+		}
+		entries = append(entries, fdbEntry{MAC: mac, Interface: portNames[port], VLAN: vlan})
+		return nil
+	})
+	return entries
+}
+
+func pollLeasesRouterOS(dev models.DeviceConfig) ([]leaseEntry, error) {
+	client, err := routeros.Dial(dev)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	replies, err := client.Run("/ip/dhcp-server/lease/print")
+	if err != nil {
+		return nil, err
+	}
+
+	leases := make([]leaseEntry, 0, len(replies))
+	for _, r := range replies {
+		if r["mac-address"] == "" {
+			continue
+		}
+		leases = append(leases, leaseEntry{
+			MAC:      strings.ToLower(r["mac-address"]),
+			IP:       r["address"],
+			Hostname: r["host-name"],
+		})
+	}
+	return leases, nil
+}
+
+// resolveEndpoints merges the tables of all devices into one endpoint per MAC.
+// Each MAC is placed on the access port that learned it: ports carrying
+// topology links are uplinks and only used when nothing better is known, and
+// among candidates the port with the fewest learned MACs wins.
+func resolveEndpoints(tables []*endpointTables, topo *topology.Topology, now time.Time) []models.Endpoint {
+	uplinks := make(map[string]bool)
+	if topo != nil {
+		for _, l := range topo.Links {
+			uplinks[l.SourceDevice+"/"+l.SourceInterface] = true
+			uplinks[l.TargetDevice+"/"+l.TargetInterface] = true
+			for _, m := range l.Members {
+				uplinks[l.SourceDevice+"/"+m.SourceInterface] = true
+				uplinks[l.TargetDevice+"/"+m.TargetInterface] = true
+			}
+		}
+	}
+
+	type sighting struct {
+		device, iface string
+		vlan          int
+	}
+	sightings := make(map[string][]sighting)
+	portLoad := make(map[string]int)
+	endpoints := make(map[string]*models.Endpoint)
+	order := make([]string, 0)
+
+	get := func(mac string) *models.Endpoint {
+		ep, ok := endpoints[mac]
+		if !ok {
+			ep = &models.Endpoint{MAC: mac, Vendor: oui.Lookup(mac), FirstSeen: now, LastSeen: now}
+			endpoints[mac] = ep
+			order = append(order, mac)
+		}
+		return ep
+	}
+
+	for _, t := range tables {
+		for _, f := range t.fdb {
+			get(f.MAC)
+			sightings[f.MAC] = append(sightings[f.MAC], sighting{t.device, f.Interface, f.VLAN})
+			portLoad[t.device+"/"+f.Interface]++
+		}
+	}
+	for _, t := range tables {
+		for _, a := range t.arp {
+			ep := get(a.MAC)
+			if ep.IP == "" {
+				ep.IP = a.IP
+			}
+			if len(sightings[a.MAC]) == 0 && ep.DeviceName == "" {
+				ep.DeviceName = t.device
+				ep.InterfaceName = a.Interface
+			}
+		}
+	}
+	for _, t := range tables {
+		for _, l := range t.leases {
+			ep := get(l.MAC)
+			if l.IP != "" {
+				ep.IP = l.IP
+			}
+			if l.Hostname != "" {
+				ep.Hostname = l.Hostname
+			}
+		}
+	}
+
+	result := make([]models.Endpoint, 0, len(order))
+	for _, mac := range order {
+		ep := endpoints[mac]
+		var best *sighting
+		bestUplink := true
+		for i := range sightings[mac] {
+			s := &sightings[mac][i]
+			uplink := uplinks[s.device+"/"+s.iface]
+			if best == nil || (bestUplink && !uplink) ||
+				(uplink == bestUplink && portLoad[s.device+"/"+s.iface] < portLoad[best.device+"/"+best.iface]) {
+				best, bestUplink = s, uplink
+			}
+		}
+		if best != nil {
+			ep.DeviceName = best.device
+			ep.InterfaceName = best.iface
+			ep.VLAN = best.vlan
+		}
+		result = append(result, *ep)
+	}
+	return result
+}
+
+func macFromOid(parts []string) (string, bool) {
+	if len(parts) != 6 {
+		return "", false
+	}
+	b := make(net.HardwareAddr, 6)
+	for i, p := range parts {
+		v, err := strconv.Atoi(p)
+		if err != nil || v < 0 || v > 255 {
+			return "", false
+		}
+		b[i] = byte(v)
+	}
+	return b.String(), true
+}
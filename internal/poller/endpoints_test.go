@@ -0,0 +1,60 @@
+package poller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/AMathur20/Home_Network/internal/topology"
+)
+
+func TestResolveEndpoints(t *testing.T) {
+	topo := &topology.Topology{Links: []topology.Link{
+		{SourceDevice: "core", SourceInterface: "ether1", TargetDevice: "switch", TargetInterface: "port1"},
+	}}
+	tables := []*endpointTables{
+		{
+			device: "core",
+			fdb: []fdbEntry{
+				{MAC: "00:11:32:aa:bb:cc", Interface: "ether1", VLAN: 10},
+				{MAC: "b8:27:eb:00:00:01", Interface: "ether1", VLAN: 10},
+			},
+			arp: []arpEntry{
+				{MAC: "00:11:32:aa:bb:cc", IP: "192.168.88.20", Interface: "bridge"},
+				{MAC: "3a:00:00:00:00:09", IP: "192.168.88.99", Interface: "bridge"},
+			},
+			leases: []leaseEntry{
+				{MAC: "00:11:32:aa:bb:cc", IP: "192.168.88.20", Hostname: "nas"},
+			},
+		},
+		{
+			device: "switch",
+			fdb: []fdbEntry{
+				{MAC: "00:11:32:aa:bb:cc", Interface: "port7", VLAN: 10},
+				{MAC: "b8:27:eb:00:00:01", Interface: "port1", VLAN: 10},
+			},
+		},
+	}
+
+	endpoints := resolveEndpoints(tables, topo, time.Now())
+	if len(endpoints) != 3 {
+		t.Fatalf("Expected 3 endpoints, got %+v", endpoints)
+	}
+
+	nas := endpoints[0]
+	if nas.DeviceName != "switch" || nas.InterfaceName != "port7" || nas.VLAN != 10 {
+		t.Errorf("Expected NAS on switch/port7, got %s/%s vlan %d", nas.DeviceName, nas.InterfaceName, nas.VLAN)
+	}
+	if nas.Hostname != "nas" || nas.IP != "192.168.88.20" || nas.Vendor != "Synology" {
+		t.Errorf("Unexpected NAS details: %+v", nas)
+	}
+
+	// Only ever seen on uplinks: fall back to the least loaded uplink port.
+	if pi := endpoints[1]; pi.DeviceName == "" || pi.Vendor != "Raspberry Pi" {
+		t.Errorf("Unexpected Pi details: %+v", pi)
+	}
+
+	phone := endpoints[2]
+	if phone.DeviceName != "core" || phone.InterfaceName != "bridge" || phone.Vendor != "Private" {
+		t.Errorf("Expected ARP-only host on core/bridge with private MAC, got %+v", phone)
+	}
+}
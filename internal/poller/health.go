@@ -0,0 +1,136 @@
+package poller
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/AMathur20/Home_Network/internal/models"
+)
+
+// deviceHealth is the engine's view of a device's polling health. polling is
+// set while a poll is in flight, so a slow device doesn't pile up timeouts.
+type deviceHealth struct {
+	models.DeviceHealth
+	polling bool
+}
+
+func (e *PollingEngine) failureThreshold() int {
+	if e.config().Poller.FailureThreshold > 0 {
+		return e.config().Poller.FailureThreshold
+	}
+	return 3
+}
+
+// backoff returns the delay before the next poll of a device that has failed
+// failures polls in a row: twice its interval once it becomes unreachable,
+// doubling with every further failure up to MaxBackoff.
+func (e *PollingEngine) backoff(interval time.Duration, failures int) time.Duration {
+	maxBackoff := time.Duration(e.config().Poller.MaxBackoff) * time.Second
+	if maxBackoff <= 0 {
+		maxBackoff = 5 * time.Minute
+	}
+	delay := interval
+	for i := e.failureThreshold(); i <= failures && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay <= 0 || delay > maxBackoff {
+		delay = maxBackoff
+	}
+	return delay
+}
+
+// health returns the entry for a device, creating it if needed. The caller
+// must hold e.mu.
+func (e *PollingEngine) health(device string) *deviceHealth {
+	h, ok := e.devices[device]
+	if !ok {
+		h = &deviceHealth{DeviceHealth: models.DeviceHealth{DeviceName: device, State: models.DeviceStateUnknown}}
+		e.devices[device] = h
+	}
+	return h
+}
+
+// beginPoll reports whether a device should be polled now and, if so, marks
+// the poll as in flight. Unreachable devices are skipped until their backoff
+// expires unless force is set.
+func (e *PollingEngine) beginPoll(device string, now time.Time, force bool) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	h := e.health(device)
+	if h.polling || (!force && now.Before(h.NextPoll)) {
+		return false
+	}
+	h.polling = true
+	return true
+}
+
+// endPoll records the outcome of a poll that started at start and returns an
+// event if the device became unreachable or recovered.
+func (e *PollingEngine) endPoll(dev models.DeviceConfig, start, end time.Time, err error) []models.Event {
+	device := dev.Name
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	h := e.health(device)
+	h.polling = false
+	h.LastAttempt = start
+	h.PollDuration = float64(end.Sub(start)) / float64(time.Millisecond)
+	last := h.State
+
+	if err == nil {
+		h.State = models.DeviceStateUp
+		h.ConsecutiveFailures = 0
+		h.LastSuccess = start
+		h.LastError = ""
+		h.NextPoll = time.Time{}
+		if last != models.DeviceStateUnreachable {
+			return nil
+		}
+		return []models.Event{{
+			Timestamp:  end,
+			DeviceName: device,
+			Kind:       models.EventPollRecovered,
+			Message:    "SNMP polling recovered",
+		}}
+	}
+
+	h.ConsecutiveFailures++
+	h.LastError = err.Error()
+	if h.ConsecutiveFailures < e.failureThreshold() {
+		h.State = models.DeviceStateDegraded
+		return nil
+	}
+	h.State = models.DeviceStateUnreachable
+	delay := e.backoff(e.interval(dev), h.ConsecutiveFailures)
+	h.NextPoll = end.Add(delay)
+	if last == models.DeviceStateUnreachable {
+		return nil
+	}
+	return []models.Event{{
+		Timestamp:  end,
+		DeviceName: device,
+		Kind:       models.EventPollUnreachable,
+		Message:    fmt.Sprintf("%d polls failed, retrying in %s: %v", h.ConsecutiveFailures, delay, err),
+	}}
+}
+
+// Health returns the polling health of every configured device. Devices that
+// aren't polled over SNMP stay unknown.
+func (e *PollingEngine) Health() []models.DeviceHealth {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	devices := e.config().Devices
+	health := make([]models.DeviceHealth, 0, len(devices))
+	for _, dev := range devices {
+		health = append(health, e.health(dev.Name).DeviceHealth)
+	}
+	return health
+}
+
+// reachable reports whether a device is not currently backing off, so the
+// slower collections can skip it instead of waiting for SNMP timeouts.
+func (e *PollingEngine) reachable(device string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	h, ok := e.devices[device]
+	return !ok || h.State != models.DeviceStateUnreachable
+}
@@ -0,0 +1,82 @@
+package poller
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/AMathur20/Home_Network/internal/models"
+)
+
+func TestDeviceHealthTransitions(t *testing.T) {
+	cfg := &models.Config{Poller: models.IntervalConfig{Live: 5, FailureThreshold: 3, MaxBackoff: 60}}
+	engine := NewPollingEngine(cfg, nil, nil)
+	timeout := errors.New("request timeout")
+	now := time.Now()
+
+	poll := func(err error) []models.Event {
+		if !engine.beginPoll("switch", now, false) {
+			t.Fatalf("poll at %s was not due", now)
+		}
+		events := engine.endPoll(models.DeviceConfig{Name: "switch"}, now, now.Add(100*time.Millisecond), err)
+		now = now.Add(5 * time.Second)
+		return events
+	}
+	state := func() models.DeviceHealth {
+		return engine.devices["switch"].DeviceHealth
+	}
+
+	if events := poll(nil); len(events) != 0 || state().State != models.DeviceStateUp || state().PollDuration != 100 {
+		t.Fatalf("unexpected state after success: %+v", state())
+	}
+	poll(timeout)
+	if events := poll(timeout); len(events) != 0 || state().State != models.DeviceStateDegraded {
+		t.Fatalf("expected degraded after 2 failures: %+v", state())
+	}
+
+	events := poll(timeout)
+	if len(events) != 1 || events[0].Kind != models.EventPollUnreachable {
+		t.Fatalf("expected an unreachable event, got %+v", events)
+	}
+	h := state()
+	if h.State != models.DeviceStateUnreachable || h.LastError != "request timeout" || h.ConsecutiveFailures != 3 {
+		t.Fatalf("unexpected unreachable state: %+v", h)
+	}
+	if backoff := h.NextPoll.Sub(h.LastAttempt); backoff != 10*time.Second+100*time.Millisecond {
+		t.Errorf("expected a 10s backoff, got %s", backoff)
+	}
+	if engine.beginPoll("switch", now, false) {
+		t.Error("expected the device to be skipped while backing off")
+	}
+	if engine.reachable("switch") {
+		t.Error("expected the device to be unreachable")
+	}
+
+	// Doubles with each failure and is capped at MaxBackoff.
+	for _, want := range []time.Duration{20 * time.Second, 40 * time.Second, 60 * time.Second, 60 * time.Second} {
+		if got := engine.backoff(5*time.Second, state().ConsecutiveFailures+1); got != want {
+			t.Errorf("expected backoff %s, got %s", want, got)
+		}
+		engine.devices["switch"].ConsecutiveFailures++
+	}
+
+	// A forced poll, e.g. after a trap, ignores the backoff.
+	if !engine.beginPoll("switch", now, true) {
+		t.Fatal("expected a forced poll to run")
+	}
+	events = engine.endPoll(models.DeviceConfig{Name: "switch"}, now, now, nil)
+	if len(events) != 1 || events[0].Kind != models.EventPollRecovered || state().State != models.DeviceStateUp || !state().NextPoll.IsZero() {
+		t.Errorf("expected recovery, got %+v and %+v", events, state())
+	}
+}
+
+func TestBeginPollSkipsInFlight(t *testing.T) {
+	engine := NewPollingEngine(&models.Config{}, nil, nil)
+	now := time.Now()
+	if !engine.beginPoll("router", now, false) {
+		t.Fatal("expected the first poll to start")
+	}
+	if engine.beginPoll("router", now, true) {
+		t.Error("expected a second poll to wait for the one in flight")
+	}
+}
@@ -0,0 +1,458 @@
+package poller
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AMathur20/Home_Network/internal/models"
+	"github.com/AMathur20/Home_Network/internal/routeros"
+	"github.com/gosnmp/gosnmp"
+)
+
+const (
+	// ENTITY-SENSOR-MIB entPhySensorTable, indexed by entPhysicalIndex
+	oidEntPhySensorType      = ".1.3.6.1.2.1.99.1.1.1.1"
+	oidEntPhySensorScale     = ".1.3.6.1.2.1.99.1.1.1.2"
+	oidEntPhySensorPrecision = ".1.3.6.1.2.1.99.1.1.1.3"
+	oidEntPhySensorValue     = ".1.3.6.1.2.1.99.1.1.1.4"
+
+	// ENTITY-MIB
+	oidEntPhysicalDescr       = ".1.3.6.1.2.1.47.1.1.1.1.2"
+	oidEntPhysicalContainedIn = ".1.3.6.1.2.1.47.1.1.1.1.4"
+	oidEntPhysicalName        = ".1.3.6.1.2.1.47.1.1.1.1.7"
+	oidEntAliasMappingId      = ".1.3.6.1.2.1.47.1.3.2.1.2"
+
+	// CISCO-ENTITY-SENSOR-MIB entSensorThresholdTable, indexed by
+	// entPhysicalIndex.thresholdIndex
+	oidEntSensorThresholdRelation = ".1.3.6.1.4.1.9.9.91.1.2.1.1.3"
+	oidEntSensorThresholdValue    = ".1.3.6.1.4.1.9.9.91.1.2.1.1.4"
+
+	oidIfIndex = ".1.3.6.1.2.1.2.2.1.1"
+)
+
+// entPhySensorType values
+const (
+	sensorVoltsDC = 4
+	sensorAmperes = 5
+	sensorWatts   = 6
+	sensorCelsius = 8
+	sensorDBm     = 14
+)
+
+// defaultOpticalThresholds are conservative limits for common 1G/10G SFP and
+// SFP+ modules, used when neither the device nor the config supplies any.
+var defaultOpticalThresholds = models.OpticalThresholds{
+	TempHigh:    float64Ptr(70),
+	VoltageLow:  float64Ptr(3.0),
+	VoltageHigh: float64Ptr(3.6),
+	TxPowerLow:  float64Ptr(-9),
+	RxPowerLow:  float64Ptr(-15),
+	RxPowerHigh: float64Ptr(0.5),
+}
+
+// entitySensor is one ENTITY-SENSOR-MIB sensor with its decoded reading.
+type entitySensor struct {
+	kind      int
+	value     float64
+	exponent  int // Scale and precision, applied to thresholds as well
+	name      string
+	parent    int
+	threshold models.OpticalThresholds
+}
+
+// PollOptics reads SFP diagnostics, via the RouterOS ethernet monitor on
+// MikroTik devices with an auth block and ENTITY-SENSOR-MIB otherwise.
+// Thresholds are resolved from cfg and evaluated into Alarms.
+func (p *SNMPPoller) PollOptics(cfg models.OpticsConfig) ([]models.OpticalMetric, error) {
+	var metrics []models.OpticalMetric
+	var vendor map[string]models.OpticalThresholds
+	var err error
+
+	if p.config.Type == models.DeviceTypeMikroTik && p.config.Auth.Username != "" {
+		metrics, err = pollOpticsRouterOS(p.config)
+	} else {
+		metrics, vendor, err = p.pollOpticsEntity()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range metrics {
+		m := &metrics[i]
+		m.Thresholds = resolveThresholds(cfg, vendor[m.InterfaceName], m.DeviceName+"/"+m.InterfaceName)
+		m.Alarms = evaluateOptic(*m)
+	}
+	return metrics, nil
+}
+
+func pollOpticsRouterOS(dev models.DeviceConfig) ([]models.OpticalMetric, error) {
+	client, err := routeros.Dial(dev)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	ports, err := client.Run("/interface/ethernet/print")
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, p := range ports {
+		name := strings.ToLower(p["name"])
+		if strings.Contains(name, "sfp") || strings.Contains(name, "combo") {
+			names = append(names, p["name"])
+		}
+	}
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	replies, err := client.Run("/interface/ethernet/monitor", "=numbers="+strings.Join(names, ","), "=once=")
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var metrics []models.OpticalMetric
+	for _, r := range replies {
+		if r["sfp-temperature"] == "" && r["sfp-rx-power"] == "" {
+			// No module fitted, or a module without DDM
+			continue
+		}
+		metrics = append(metrics, models.OpticalMetric{
+			Timestamp:     now,
+			DeviceName:    dev.Name,
+			InterfaceName: r["name"],
+			Temperature:   leadingFloat(r["sfp-temperature"]),
+			Voltage:       leadingFloat(r["sfp-supply-voltage"]),
+			BiasCurrent:   leadingFloat(r["sfp-tx-bias-current"]),
+			TxPower:       leadingFloat(r["sfp-tx-power"]),
+			RxPower:       leadingFloat(r["sfp-rx-power"]),
+		})
+	}
+	return metrics, nil
+}
+
+// pollOpticsEntity reads transceiver sensors from ENTITY-SENSOR-MIB and maps
+// them to interfaces through the ENTITY-MIB containment tree and alias
+// mapping. Vendor thresholds come from CISCO-ENTITY-SENSOR-MIB where present.
+func (p *SNMPPoller) pollOpticsEntity() ([]models.OpticalMetric, map[string]models.OpticalThresholds, error) {
+	params, err := p.connect()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer params.Conn.Close()
+
+	sensors := make(map[int]*entitySensor)
+	sensor := func(index int) *entitySensor {
+		s, ok := sensors[index]
+		if !ok {
+			s = &entitySensor{}
+			sensors[index] = s
+		}
+		return s
+	}
+	walkInt := func(oid string, set func(index int, pdu gosnmp.SnmpPDU)) error {
+		return params.BulkWalk(oid, func(pdu gosnmp.SnmpPDU) error {
+			index := 0
+			fmt.Sscanf(pdu.Name, oid+".%d", &index)
+			set(index, pdu)
+			return nil
+		})
+	}
+
+	err = walkInt(oidEntPhySensorType, func(i int, pdu gosnmp.SnmpPDU) { sensor(i).kind = models.PduToInt(pdu.Value) })
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to walk entPhySensorType: %v", err)
+	}
+	if len(sensors) == 0 {
+		return nil, nil, nil
+	}
+
+	values := make(map[int]int)
+	walkInt(oidEntPhySensorValue, func(i int, pdu gosnmp.SnmpPDU) { values[i] = models.PduToInt(pdu.Value) })
+	walkInt(oidEntPhySensorScale, func(i int, pdu gosnmp.SnmpPDU) {
+		// units(9) is 10^0, every step is a factor of 1000
+		sensor(i).exponent += (models.PduToInt(pdu.Value) - 9) * 3
+	})
+	walkInt(oidEntPhySensorPrecision, func(i int, pdu gosnmp.SnmpPDU) { sensor(i).exponent -= models.PduToInt(pdu.Value) })
+
+	names := make(map[int]string)
+	parents := make(map[int]int)
+	walkInt(oidEntPhysicalDescr, func(i int, pdu gosnmp.SnmpPDU) { names[i] = models.PduToString(pdu.Value) })
+	walkInt(oidEntPhysicalName, func(i int, pdu gosnmp.SnmpPDU) {
+		if n := models.PduToString(pdu.Value); n != "" {
+			names[i] = n
+		}
+	})
+	walkInt(oidEntPhysicalContainedIn, func(i int, pdu gosnmp.SnmpPDU) { parents[i] = models.PduToInt(pdu.Value) })
+
+	ifIndexes := make(map[int]int)
+	walkInt(oidEntAliasMappingId, func(i int, pdu gosnmp.SnmpPDU) {
+		if oid, ok := pdu.Value.(string); ok && strings.HasPrefix(oid, oidIfIndex+".") {
+			ifIndexes[i], _ = strconv.Atoi(strings.TrimPrefix(oid, oidIfIndex+"."))
+		}
+	})
+
+	ifNames := make(map[int]string)
+	walkInt(oidIfName, func(i int, pdu gosnmp.SnmpPDU) { ifNames[i] = models.PduToString(pdu.Value) })
+
+	thresholds := make(map[int][]vendorThreshold)
+	relations := make(map[string]int)
+	params.BulkWalk(oidEntSensorThresholdRelation, func(pdu gosnmp.SnmpPDU) error {
+		relations[strings.TrimPrefix(pdu.Name, oidEntSensorThresholdRelation+".")] = models.PduToInt(pdu.Value)
+		return nil
+	})
+	params.BulkWalk(oidEntSensorThresholdValue, func(pdu gosnmp.SnmpPDU) error {
+		suffix := strings.TrimPrefix(pdu.Name, oidEntSensorThresholdValue+".")
+		index := 0
+		fmt.Sscanf(suffix, "%d.", &index)
+		thresholds[index] = append(thresholds[index], vendorThreshold{relation: relations[suffix], value: models.PduToInt(pdu.Value)})
+		return nil
+	})
+
+	for i, s := range sensors {
+		s.value = float64(values[i]) * math.Pow10(s.exponent)
+		s.name = names[i]
+		s.parent = parents[i]
+	}
+
+	// Resolve each sensor to the interface of its nearest mapped ancestor.
+	port := func(index int) string {
+		for depth := 0; depth < 8 && index != 0; depth++ {
+			if ifIndex, ok := ifIndexes[index]; ok {
+				return ifNames[ifIndex]
+			}
+			index = parents[index]
+		}
+		return ""
+	}
+
+	now := time.Now()
+	byPort := make(map[string]*models.OpticalMetric)
+	vendor := make(map[string]models.OpticalThresholds)
+	for i, s := range sensors {
+		iface := port(i)
+		if iface == "" {
+			continue
+		}
+		m, ok := byPort[iface]
+		if !ok {
+			m = &models.OpticalMetric{Timestamp: now, DeviceName: p.config.Name, InterfaceName: iface}
+			byPort[iface] = m
+		}
+		t := vendor[iface]
+		applySensor(m, &t, s, thresholds[i])
+		vendor[iface] = t
+	}
+
+	metrics := make([]models.OpticalMetric, 0, len(byPort))
+	for _, m := range byPort {
+		metrics = append(metrics, *m)
+	}
+	sort.Slice(metrics, func(i, j int) bool { return metrics[i].InterfaceName < metrics[j].InterfaceName })
+	return metrics, vendor, nil
+}
+
+type vendorThreshold struct {
+	relation int // lessThan(1), lessOrEqual(2), greaterThan(3), greaterOrEqual(4)
+	value    int
+}
+
+// applySensor stores a sensor reading on m and folds its vendor thresholds
+// into t. The earliest warning wins: the highest low and the lowest high limit.
+func applySensor(m *models.OpticalMetric, t *models.OpticalThresholds, s *entitySensor, vendor []vendorThreshold) {
+	name := strings.ToLower(s.name)
+	tx := strings.Contains(name, "tx") || strings.Contains(name, "transmit")
+	rx := strings.Contains(name, "rx") || strings.Contains(name, "receive")
+
+	convert := func(v float64) float64 { return v }
+	var reading, low, high **float64
+	switch {
+	case s.kind == sensorCelsius:
+		reading, high = &m.Temperature, &t.TempHigh
+	case s.kind == sensorVoltsDC:
+		reading, low, high = &m.Voltage, &t.VoltageLow, &t.VoltageHigh
+	case s.kind == sensorAmperes:
+		convert = func(v float64) float64 { return v * 1000 }
+		reading, high = &m.BiasCurrent, &t.BiasHigh
+	case (s.kind == sensorDBm || s.kind == sensorWatts) && tx:
+		reading, low = &m.TxPower, &t.TxPowerLow
+	case (s.kind == sensorDBm || s.kind == sensorWatts) && rx:
+		reading, low, high = &m.RxPower, &t.RxPowerLow, &t.RxPowerHigh
+	default:
+		return
+	}
+	if s.kind == sensorWatts {
+		convert = func(v float64) float64 { return 10 * math.Log10(v*1000) }
+	}
+
+	v := convert(s.value)
+	*reading = &v
+
+	for _, vt := range vendor {
+		limit := convert(float64(vt.value) * math.Pow10(s.exponent))
+		switch {
+		case (vt.relation == 1 || vt.relation == 2) && low != nil:
+			if *low == nil || limit > **low {
+				*low = &limit
+			}
+		case (vt.relation == 3 || vt.relation == 4) && high != nil:
+			if *high == nil || limit < **high {
+				*high = &limit
+			}
+		}
+	}
+}
+
+// resolveThresholds layers per-port config over global config over vendor
+// thresholds over the built-in defaults.
+func resolveThresholds(cfg models.OpticsConfig, vendor models.OpticalThresholds, port string) models.OpticalThresholds {
+	t := defaultOpticalThresholds
+	for _, layer := range []models.OpticalThresholds{vendor, cfg.Thresholds, cfg.Ports[port]} {
+		overlay := func(dst **float64, src *float64) {
+			if src != nil {
+				*dst = src
+			}
+		}
+		overlay(&t.TempHigh, layer.TempHigh)
+		overlay(&t.VoltageLow, layer.VoltageLow)
+		overlay(&t.VoltageHigh, layer.VoltageHigh)
+		overlay(&t.BiasHigh, layer.BiasHigh)
+		overlay(&t.TxPowerLow, layer.TxPowerLow)
+		overlay(&t.RxPowerLow, layer.RxPowerLow)
+		overlay(&t.RxPowerHigh, layer.RxPowerHigh)
+	}
+	return t
+}
+
+// evaluateOptic returns the thresholds crossed by m. Unset thresholds and
+// missing readings are skipped.
+func evaluateOptic(m models.OpticalMetric) []string {
+	var alarms []string
+	check := func(name string, reading, limit *float64, high bool) {
+		if reading == nil || limit == nil {
+			return
+		}
+		if (high && *reading > *limit) || (!high && *reading < *limit) {
+			alarms = append(alarms, name)
+		}
+	}
+	t := m.Thresholds
+	check("temp_high", m.Temperature, t.TempHigh, true)
+	check("voltage_low", m.Voltage, t.VoltageLow, false)
+	check("voltage_high", m.Voltage, t.VoltageHigh, true)
+	check("bias_high", m.BiasCurrent, t.BiasHigh, true)
+	check("tx_power_low", m.TxPower, t.TxPowerLow, false)
+	check("rx_power_low", m.RxPower, t.RxPowerLow, false)
+	check("rx_power_high", m.RxPower, t.RxPowerHigh, true)
+	return alarms
+}
+
+func (e *PollingEngine) pollOptics() {
+	for _, dev := range e.config().Devices {
+		if !dev.PolledBySNMP() || !e.reachable(dev.Name) {
+			continue
+		}
+		metrics, err := NewSNMPPoller(dev).PollOptics(e.config().Optics)
+		if err != nil {
+			log.Printf("Error collecting SFP diagnostics from %s: %v", dev.Name, err)
+			continue
+		}
+
+		e.mu.Lock()
+		events := e.checkOptics(metrics)
+		e.mu.Unlock()
+
+		for _, ev := range events {
+			log.Printf("Optic alarm on %s/%s: %s", ev.DeviceName, ev.InterfaceName, ev.Message)
+			if err := e.storage.SaveEvent(ev); err != nil {
+				log.Printf("Error saving event for %s: %v", ev.DeviceName, err)
+			}
+		}
+		for _, m := range metrics {
+			if err := e.storage.SaveOpticalMetric(m); err != nil {
+				log.Printf("Error saving SFP diagnostics for %s/%s: %v", m.DeviceName, m.InterfaceName, err)
+			}
+		}
+	}
+}
+
+// checkOptics returns an event when a port raises an alarm it did not have on
+// the previous collection. The caller must hold e.mu.
+func (e *PollingEngine) checkOptics(metrics []models.OpticalMetric) []models.Event {
+	var events []models.Event
+	for _, m := range metrics {
+		key := m.DeviceName + "/" + m.InterfaceName
+		last := e.optics[key]
+		e.optics[key] = m.Alarms
+
+		var raised []string
+		for _, a := range m.Alarms {
+			if !containsString(last, a) {
+				raised = append(raised, a)
+			}
+		}
+		if len(raised) == 0 {
+			continue
+		}
+		events = append(events, models.Event{
+			Timestamp:     m.Timestamp,
+			DeviceName:    m.DeviceName,
+			InterfaceName: m.InterfaceName,
+			Kind:          models.EventOpticAlarm,
+			Message:       fmt.Sprintf("%s (%s)", strings.Join(raised, ", "), describeOptic(m)),
+		})
+	}
+	return events
+}
+
+func describeOptic(m models.OpticalMetric) string {
+	var parts []string
+	add := func(format string, v *float64) {
+		if v != nil {
+			parts = append(parts, fmt.Sprintf(format, *v))
+		}
+	}
+	add("%.1f°C", m.Temperature)
+	add("%.2fV", m.Voltage)
+	add("bias %.1fmA", m.BiasCurrent)
+	add("tx %.2fdBm", m.TxPower)
+	add("rx %.2fdBm", m.RxPower)
+	return strings.Join(parts, ", ")
+}
+
+func float64Ptr(v float64) *float64 {
+	return &v
+}
+
+// leadingFloat parses the number at the start of a RouterOS value such as
+// "-3.1dBm" or "35C", returning nil if there is none.
+func leadingFloat(v string) *float64 {
+	end := 0
+	if end < len(v) && (v[end] == '-' || v[end] == '+') {
+		end++
+	}
+	for end < len(v) && (v[end] == '.' || (v[end] >= '0' && v[end] <= '9')) {
+		end++
+	}
+	f, err := strconv.ParseFloat(v[:end], 64)
+	if err != nil {
+		return nil
+	}
+	return &f
+}
+
+func containsString(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
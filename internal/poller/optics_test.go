@@ -0,0 +1,76 @@
+package poller
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/AMathur20/Home_Network/internal/models"
+)
+
+func TestApplySensor(t *testing.T) {
+	m := &models.OpticalMetric{}
+	var th models.OpticalThresholds
+
+	// -3.10 dBm with precision 2, vendor low alarm at -12.00 dBm and warning at -10.00
+	rx := &entitySensor{kind: sensorDBm, value: -3.1, exponent: -2, name: "Te1/1 Receive Power Sensor"}
+	applySensor(m, &th, rx, []vendorThreshold{{relation: 1, value: -1200}, {relation: 2, value: -1000}, {relation: 3, value: 200}})
+	// 0.5 mW transmit power reported in watts
+	tx := &entitySensor{kind: sensorWatts, value: 0.0005, name: "Te1/1 Transmit Power Sensor"}
+	applySensor(m, &th, tx, nil)
+	bias := &entitySensor{kind: sensorAmperes, value: 0.0065, name: "Te1/1 Bias Current Sensor"}
+	applySensor(m, &th, bias, nil)
+
+	if m.RxPower == nil || *m.RxPower != -3.1 {
+		t.Errorf("Unexpected rx power %v", m.RxPower)
+	}
+	if m.TxPower == nil || math.Abs(*m.TxPower-(-3.01)) > 0.01 {
+		t.Errorf("Unexpected tx power %v", m.TxPower)
+	}
+	if m.BiasCurrent == nil || math.Abs(*m.BiasCurrent-6.5) > 1e-9 {
+		t.Errorf("Unexpected bias current %v", m.BiasCurrent)
+	}
+	if th.RxPowerLow == nil || *th.RxPowerLow != -10 || th.RxPowerHigh == nil || *th.RxPowerHigh != 2 {
+		t.Errorf("Expected earliest vendor thresholds, got %+v", th)
+	}
+}
+
+func TestOpticAlarms(t *testing.T) {
+	cfg := models.OpticsConfig{
+		Thresholds: models.OpticalThresholds{TempHigh: float64Ptr(60)},
+		Ports: map[string]models.OpticalThresholds{
+			"core/sfp1": {RxPowerLow: float64Ptr(-20)},
+			"core/sfp3": {TxPowerLow: float64Ptr(0), RxPowerHigh: float64Ptr(0)},
+		},
+	}
+	vendor := models.OpticalThresholds{RxPowerLow: float64Ptr(-16)}
+	temp, rx := 65.0, -18.0
+	m := models.OpticalMetric{Timestamp: time.Now(), DeviceName: "core", InterfaceName: "sfp2", Temperature: &temp, RxPower: &rx}
+
+	m.Thresholds = resolveThresholds(cfg, vendor, "core/sfp2")
+	if alarms := evaluateOptic(m); !reflect.DeepEqual(alarms, []string{"temp_high", "rx_power_low"}) {
+		t.Errorf("Unexpected alarms %v", alarms)
+	}
+
+	tx, rxHigh := -1.0, 0.5
+	sfp3 := models.OpticalMetric{TxPower: &tx, RxPower: &rxHigh, Thresholds: resolveThresholds(cfg, vendor, "core/sfp3")}
+	if alarms := evaluateOptic(sfp3); !reflect.DeepEqual(alarms, []string{"tx_power_low", "rx_power_high"}) {
+		t.Errorf("Expected 0 dBm thresholds to apply, got %v", alarms)
+	}
+
+	m.InterfaceName = "sfp1"
+	m.Thresholds = resolveThresholds(cfg, vendor, "core/sfp1")
+	m.Alarms = evaluateOptic(m)
+	if !reflect.DeepEqual(m.Alarms, []string{"temp_high"}) {
+		t.Errorf("Expected per-port threshold to win, got %v", m.Alarms)
+	}
+
+	engine := NewPollingEngine(&models.Config{}, nil, nil)
+	if events := engine.checkOptics([]models.OpticalMetric{m}); len(events) != 1 || events[0].Kind != models.EventOpticAlarm {
+		t.Errorf("Expected an optic alarm event, got %+v", events)
+	}
+	if events := engine.checkOptics([]models.OpticalMetric{m}); len(events) != 0 {
+		t.Errorf("Expected a standing alarm to be reported once, got %+v", events)
+	}
+}
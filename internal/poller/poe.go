@@ -0,0 +1,305 @@
+package poller
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/AMathur20/Home_Network/internal/models"
+	"github.com/AMathur20/Home_Network/internal/routeros"
+	"github.com/gosnmp/gosnmp"
+)
+
+const (
+	// POWER-ETHERNET-MIB pethPsePortTable, indexed by group.port
+	oidPethPsePortDetectionStatus     = ".1.3.6.1.2.1.105.1.1.1.6"
+	oidPethPsePortPowerClassification = ".1.3.6.1.2.1.105.1.1.1.10"
+
+	// POWER-ETHERNET-MIB pethMainPseTable, indexed by group
+	oidPethMainPsePower            = ".1.3.6.1.2.1.105.1.3.1.1.2"
+	oidPethMainPseConsumptionPower = ".1.3.6.1.2.1.105.1.3.1.1.4"
+
+	// MIKROTIK-MIB mtxrPOETable, indexed by ifIndex
+	oidMtxrPOEStatus  = ".1.3.6.1.4.1.14988.1.1.15.1.1.3"
+	oidMtxrPOEVoltage = ".1.3.6.1.4.1.14988.1.1.15.1.1.4"
+	oidMtxrPOECurrent = ".1.3.6.1.4.1.14988.1.1.15.1.1.5"
+	oidMtxrPOEPower   = ".1.3.6.1.4.1.14988.1.1.15.1.1.6"
+)
+
+type poeStats struct {
+	ports  []models.PoEPort
+	budget *models.PoEBudget
+}
+
+// PollPoE reads per-port PoE state and the PSE budget. MikroTik devices are
+// read with the RouterOS poe-out monitor when an auth block is configured and
+// MIKROTIK-MIB otherwise; other devices use POWER-ETHERNET-MIB, which has no
+// per-port power draw.
+func (p *SNMPPoller) PollPoE() (*poeStats, error) {
+	now := time.Now()
+	var stats *poeStats
+	var err error
+
+	if p.config.Type == models.DeviceTypeMikroTik && p.config.Auth.Username != "" {
+		stats, err = pollPoERouterOS(p.config, now)
+	} else {
+		var params *gosnmp.GoSNMP
+		if params, err = p.connect(); err != nil {
+			return nil, err
+		}
+		defer params.Conn.Close()
+		if p.config.Type == models.DeviceTypeMikroTik {
+			stats, err = pollPoEMikroTikSNMP(params, p.config.Name, now)
+		} else {
+			stats, err = pollPoEStandard(params, p.config.Name, now)
+		}
+	}
+	if err != nil || len(stats.ports) == 0 {
+		return stats, err
+	}
+
+	if stats.budget == nil {
+		stats.budget = &models.PoEBudget{Timestamp: now, DeviceName: p.config.Name}
+		for _, port := range stats.ports {
+			stats.budget.Consumption += port.Power
+		}
+	}
+	if stats.budget.Budget == 0 {
+		stats.budget.Budget = p.config.PoEBudget
+	}
+	return stats, nil
+}
+
+func pollPoERouterOS(dev models.DeviceConfig, now time.Time) (*poeStats, error) {
+	client, err := routeros.Dial(dev)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	ports, err := client.Run("/interface/ethernet/poe/print")
+	if routeros.IsNoSuchCommand(err) {
+		// The poe menu only exists on PoE-capable hardware.
+		return &poeStats{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, p := range ports {
+		names = append(names, p["name"])
+	}
+	if len(names) == 0 {
+		return &poeStats{}, nil
+	}
+
+	replies, err := client.Run("/interface/ethernet/poe/monitor", "=numbers="+strings.Join(names, ","), "=once=")
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &poeStats{}
+	for _, r := range replies {
+		port := models.PoEPort{
+			Timestamp:     now,
+			DeviceName:    dev.Name,
+			InterfaceName: r["name"],
+			Status:        routerOSPoEStatus(r["poe-out-status"]),
+			Class:         -1,
+		}
+		if v := leadingFloat(r["poe-out-power"]); v != nil {
+			port.Power = *v
+		}
+		if v := leadingFloat(r["poe-out-voltage"]); v != nil {
+			port.Voltage = *v
+		}
+		if v := leadingFloat(r["poe-out-current"]); v != nil {
+			port.Current = *v
+		}
+		stats.ports = append(stats.ports, port)
+	}
+	return stats, nil
+}
+
+// routerOSPoEStatus maps a RouterOS poe-out-status such as "powered-on" or
+// "waiting-for-load".
+func routerOSPoEStatus(status string) string {
+	switch status {
+	case "powered-on":
+		return models.PoEStatusDelivering
+	case "waiting-for-load":
+		return models.PoEStatusSearching
+	case "off", "disabled", "":
+		return models.PoEStatusDisabled
+	default:
+		// short-circuit, overload, voltage-too-low, current-too-low, ...
+		return models.PoEStatusFault
+	}
+}
+
+func pollPoEMikroTikSNMP(params *gosnmp.GoSNMP, device string, now time.Time) (*poeStats, error) {
+	ifNames, err := walkIfNames(params)
+	if err != nil {
+		return nil, err
+	}
+
+	ports := make(map[int]*models.PoEPort)
+	port := func(index int) *models.PoEPort {
+		p, ok := ports[index]
+		if !ok {
+			p = &models.PoEPort{Timestamp: now, DeviceName: device, InterfaceName: ifNames[index], Class: -1}
+			ports[index] = p
+		}
+		return p
+	}
+	for _, col := range []struct {
+		oid string
+		set func(p *models.PoEPort, v int)
+	}{
+		{oidMtxrPOEStatus, func(p *models.PoEPort, v int) {
+			// disabled(1), waitingForLoad(2), poweredOn(3), overload(4)
+			switch v {
+			case 2:
+				p.Status = models.PoEStatusSearching
+			case 3:
+				p.Status = models.PoEStatusDelivering
+			case 4:
+				p.Status = models.PoEStatusFault
+			default:
+				p.Status = models.PoEStatusDisabled
+			}
+		}},
+		{oidMtxrPOEVoltage, func(p *models.PoEPort, v int) { p.Voltage = float64(v) / 10 }},
+		{oidMtxrPOECurrent, func(p *models.PoEPort, v int) { p.Current = float64(v) }},
+		{oidMtxrPOEPower, func(p *models.PoEPort, v int) { p.Power = float64(v) / 10 }},
+	} {
+		params.BulkWalk(col.oid, func(pdu gosnmp.SnmpPDU) error {
+			index := 0
+			fmt.Sscanf(pdu.Name, col.oid+".%d", &index)
+			col.set(port(index), models.PduToInt(pdu.Value))
+			return nil
+		})
+	}
+
+	return &poeStats{ports: sortedPoEPorts(ports)}, nil
+}
+
+func pollPoEStandard(params *gosnmp.GoSNMP, device string, now time.Time) (*poeStats, error) {
+	ifNames, err := walkIfNames(params)
+	if err != nil {
+		return nil, err
+	}
+
+	// Most switches number PSE ports after their ifIndex within group 1.
+	ports := make(map[int]*models.PoEPort)
+	port := func(suffix string) *models.PoEPort {
+		group, index := 0, 0
+		fmt.Sscanf(suffix, "%d.%d", &group, &index)
+		key := group<<16 | index
+		p, ok := ports[key]
+		if !ok {
+			name := ifNames[index]
+			if name == "" {
+				name = fmt.Sprintf("port%d", index)
+			}
+			p = &models.PoEPort{Timestamp: now, DeviceName: device, InterfaceName: name, Class: -1}
+			ports[key] = p
+		}
+		return p
+	}
+
+	params.BulkWalk(oidPethPsePortDetectionStatus, func(pdu gosnmp.SnmpPDU) error {
+		// disabled(1), searching(2), deliveringPower(3), fault(4), test(5), otherFault(6)
+		p := port(strings.TrimPrefix(pdu.Name, oidPethPsePortDetectionStatus+"."))
+		switch models.PduToInt(pdu.Value) {
+		case 1:
+			p.Status = models.PoEStatusDisabled
+		case 2, 5:
+			p.Status = models.PoEStatusSearching
+		case 3:
+			p.Status = models.PoEStatusDelivering
+		default:
+			p.Status = models.PoEStatusFault
+		}
+		return nil
+	})
+	params.BulkWalk(oidPethPsePortPowerClassification, func(pdu gosnmp.SnmpPDU) error {
+		// class0(1) .. class4(5)
+		p := port(strings.TrimPrefix(pdu.Name, oidPethPsePortPowerClassification+"."))
+		p.Class = models.PduToInt(pdu.Value) - 1
+		return nil
+	})
+
+	stats := &poeStats{ports: sortedPoEPorts(ports)}
+	if len(stats.ports) == 0 {
+		return stats, nil
+	}
+
+	budget := &models.PoEBudget{Timestamp: now, DeviceName: device}
+	found := false
+	params.BulkWalk(oidPethMainPsePower, func(pdu gosnmp.SnmpPDU) error {
+		budget.Budget += float64(models.PduToInt(pdu.Value))
+		found = true
+		return nil
+	})
+	params.BulkWalk(oidPethMainPseConsumptionPower, func(pdu gosnmp.SnmpPDU) error {
+		budget.Consumption += float64(models.PduToInt(pdu.Value))
+		return nil
+	})
+	if found {
+		stats.budget = budget
+	}
+	return stats, nil
+}
+
+func walkIfNames(params *gosnmp.GoSNMP) (map[int]string, error) {
+	ifNames := make(map[int]string)
+	err := params.BulkWalk(oidIfName, func(pdu gosnmp.SnmpPDU) error {
+		index := 0
+		fmt.Sscanf(pdu.Name, oidIfName+".%d", &index)
+		ifNames[index] = models.PduToString(pdu.Value)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk ifName: %v", err)
+	}
+	return ifNames, nil
+}
+
+func sortedPoEPorts(ports map[int]*models.PoEPort) []models.PoEPort {
+	keys := make([]int, 0, len(ports))
+	for k := range ports {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	result := make([]models.PoEPort, 0, len(keys))
+	for _, k := range keys {
+		result = append(result, *ports[k])
+	}
+	return result
+}
+
+func (e *PollingEngine) pollPoE() {
+	for _, dev := range e.config().Devices {
+		if !dev.PolledBySNMP() || !e.reachable(dev.Name) {
+			continue
+		}
+		stats, err := NewSNMPPoller(dev).PollPoE()
+		if err != nil {
+			log.Printf("Error collecting PoE state from %s: %v", dev.Name, err)
+			continue
+		}
+		for _, p := range stats.ports {
+			if err := e.storage.SavePoEPort(p); err != nil {
+				log.Printf("Error saving PoE port %s/%s: %v", p.DeviceName, p.InterfaceName, err)
+			}
+		}
+		if stats.budget != nil {
+			if err := e.storage.SavePoEBudget(*stats.budget); err != nil {
+				log.Printf("Error saving PoE budget for %s: %v", dev.Name, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package poller
+
+import (
+	"testing"
+
+	"github.com/AMathur20/Home_Network/internal/models"
+)
+
+func TestRouterOSPoEStatus(t *testing.T) {
+	for status, want := range map[string]string{
+		"powered-on":       models.PoEStatusDelivering,
+		"waiting-for-load": models.PoEStatusSearching,
+		"off":              models.PoEStatusDisabled,
+		"short-circuit":    models.PoEStatusFault,
+		"overload":         models.PoEStatusFault,
+	} {
+		if got := routerOSPoEStatus(status); got != want {
+			t.Errorf("routerOSPoEStatus(%q) = %s, want %s", status, got, want)
+		}
+	}
+}
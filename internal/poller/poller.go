@@ -1,19 +1,37 @@
 package poller
 
 import (
+	"fmt"
 	"log"
+	"math/rand/v2"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/AMathur20/Home_Network/internal/models"
+	"github.com/AMathur20/Home_Network/internal/security"
 	"github.com/AMathur20/Home_Network/internal/storage"
 	"github.com/AMathur20/Home_Network/internal/topology"
 )
 
 type PollingEngine struct {
-	config   *models.Config
+	current  atomic.Pointer[models.Config]
 	storage  *storage.DuckDBStorage
-	topology *topology.Topology
-	state    map[string]interfaceState
+	topology atomic.Pointer[topology.Topology]
+	detector *security.Detector
+	reloaded chan struct{}
+
+	mu        sync.Mutex
+	state     map[string]interfaceState
+	bridges   map[string]models.BridgeStatus
+	stp       map[string]*stpState
+	roaming   map[string]string   // Wireless client MAC -> AP/interface
+	optics    map[string][]string // device/interface -> active SFP alarms
+	devices   map[string]*deviceHealth
+	started   bool
+	schedules map[string]*deviceSchedule
 }
 
 type interfaceState struct {
@@ -22,48 +40,274 @@ type interfaceState struct {
 	lastTime      time.Time
 }
 
+// stpInterval is how often spanning-tree state is read. It changes rarely, so
+// it is not worth the extra requests on every live poll.
+const stpInterval = time.Minute
+
+// stpState is the spanning-tree state of a device as of its last read.
+type stpState struct {
+	read   time.Time
+	ports  map[string]string // interface -> STP state
+	failed bool              // the last read failed and was logged
+}
+
+// deviceSchedule is the running poll loop of one device. Closing stop ends it.
+type deviceSchedule struct {
+	dev      models.DeviceConfig
+	interval time.Duration
+	stop     chan struct{}
+}
+
 func NewPollingEngine(cfg *models.Config, s *storage.DuckDBStorage, t *topology.Topology) *PollingEngine {
-	return &PollingEngine{
-		config:   cfg,
-		storage:  s,
-		topology: t,
-		state:    make(map[string]interfaceState),
+	e := &PollingEngine{
+		storage:   s,
+		reloaded:  make(chan struct{}, 1),
+		state:     make(map[string]interfaceState),
+		bridges:   make(map[string]models.BridgeStatus),
+		stp:       make(map[string]*stpState),
+		roaming:   make(map[string]string),
+		optics:    make(map[string][]string),
+		devices:   make(map[string]*deviceHealth),
+		schedules: make(map[string]*deviceSchedule),
 	}
+	e.current.Store(cfg)
+	e.topology.Store(t)
+	return e
+}
+
+// config returns the config currently in effect.
+func (e *PollingEngine) config() *models.Config {
+	return e.current.Load()
+}
+
+// SetDetector enables security checks on every endpoint collection.
+func (e *PollingEngine) SetDetector(d *security.Detector) {
+	e.detector = d
 }
 
 func (e *PollingEngine) ReloadTopology(t *topology.Topology) {
-	e.topology = t
+	e.topology.Store(t)
 	log.Println("Engine topology reloaded.")
 }
 
+// Reload switches the engine to a new config. New devices start polling,
+// removed ones stop, and devices whose settings or interval changed are
+// restarted with the new values.
+func (e *PollingEngine) Reload(cfg *models.Config) {
+	e.current.Store(cfg)
+	e.updateSchedules()
+	select {
+	case e.reloaded <- struct{}{}:
+	default:
+	}
+	log.Printf("Engine config reloaded with %d devices.", len(cfg.Devices))
+}
+
 func (e *PollingEngine) Start() {
-	log.Printf("Starting polling engine with %d devices", len(e.config.Devices))
+	log.Printf("Starting polling engine with %d devices", len(e.config().Devices))
+
+	e.mu.Lock()
+	e.started = true
+	e.mu.Unlock()
+	e.updateSchedules()
 
-	ticker := time.NewTicker(time.Duration(e.config.Poller.Live) * time.Second)
+	// Endpoint, wireless, SFP and PoE tables change slowly and are expensive to
+	// walk, so they are collected on the history interval.
+	history := time.NewTicker(time.Hour)
+	defer history.Stop()
+	var interval time.Duration
+	for {
+		if current := time.Duration(e.config().Poller.History) * time.Second; current != interval {
+			interval = current
+			if interval > 0 {
+				history.Reset(interval)
+			} else {
+				history.Stop()
+			}
+		}
+
+		select {
+		case <-history.C:
+			cfg := e.config()
+			if !cfg.Endpoints.Disabled {
+				go e.pollEndpoints()
+			}
+			if !cfg.Wireless.Disabled {
+				go e.pollWireless()
+			}
+			if !cfg.Optics.Disabled {
+				go e.pollOptics()
+			}
+			if !cfg.PoE.Disabled {
+				go e.pollPoE()
+			}
+		case <-e.reloaded:
+		}
+	}
+}
+
+// interval returns how often a device is polled: its own interval, else its
+// priority class, else the live interval.
+func (e *PollingEngine) interval(dev models.DeviceConfig) time.Duration {
+	poller := e.config().Poller
+	seconds := poller.Live
+	switch {
+	case dev.Interval > 0:
+		seconds = dev.Interval
+	case poller.Priorities[dev.Priority] > 0:
+		seconds = poller.Priorities[dev.Priority]
+	case dev.Priority == models.PriorityHigh:
+		seconds = 2
+	case dev.Priority == models.PriorityLow:
+		seconds = 30
+	}
+	if seconds <= 0 {
+		seconds = 5
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// updateSchedules brings the running poll loops in line with the configured
+// devices. It does nothing until the engine has started.
+func (e *PollingEngine) updateSchedules() {
+	cfg := e.config()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.started {
+		return
+	}
+
+	wanted := make(map[string]models.DeviceConfig)
+	for _, dev := range cfg.Devices {
+		if dev.PolledBySNMP() {
+			wanted[dev.Name] = dev
+		}
+	}
+	for name, s := range e.schedules {
+		dev, ok := wanted[name]
+		if ok && reflect.DeepEqual(dev, s.dev) && e.interval(dev) == s.interval {
+			continue
+		}
+		close(s.stop)
+		delete(e.schedules, name)
+		if !ok {
+			e.forget(name)
+			log.Printf("Stopped polling %s", name)
+		}
+	}
+	for name, dev := range wanted {
+		if _, ok := e.schedules[name]; ok {
+			continue
+		}
+		s := &deviceSchedule{dev: dev, interval: e.interval(dev), stop: make(chan struct{})}
+		e.schedules[name] = s
+		go e.runSchedule(s)
+	}
+}
+
+// forget drops the state kept for a device that is no longer configured. The
+// caller must hold e.mu.
+func (e *PollingEngine) forget(device string) {
+	delete(e.devices, device)
+	delete(e.bridges, device)
+	delete(e.stp, device)
+	for key := range e.state {
+		if strings.HasPrefix(key, device+"/") {
+			delete(e.state, key)
+		}
+	}
+	for key := range e.optics {
+		if strings.HasPrefix(key, device+"/") {
+			delete(e.optics, key)
+		}
+	}
+}
+
+// runSchedule polls a device on its own interval until it is stopped. The
+// first poll is delayed by a random fraction of the interval so devices
+// sharing an interval are spread out rather than all polled in the same
+// instant.
+func (e *PollingEngine) runSchedule(s *deviceSchedule) {
+	delay := time.NewTimer(rand.N(s.interval))
+	defer delay.Stop()
+	select {
+	case <-s.stop:
+		return
+	case <-delay.C:
+	}
+
+	ticker := time.NewTicker(s.interval)
 	defer ticker.Stop()
+	for now := time.Now(); ; {
+		if e.beginPoll(s.dev.Name, now, false) {
+			e.pollDevice(s.dev)
+		}
+		select {
+		case <-s.stop:
+			return
+		case now = <-ticker.C:
+		}
+	}
+}
 
-	for range ticker.C {
-		for _, dev := range e.config.Devices {
-			go e.pollDevice(dev)
+// PollNow polls a device outside the live schedule, e.g. when a trap reports
+// a link change between polls. A device that is backing off is polled anyway,
+// since the trap shows it is reachable again.
+func (e *PollingEngine) PollNow(dev models.DeviceConfig) {
+	if dev.PolledBySNMP() && e.beginPoll(dev.Name, time.Now(), true) {
+		e.pollDevice(dev)
+	}
+}
+
+func (e *PollingEngine) pollEndpoints() {
+	var tables []*endpointTables
+	for _, dev := range e.config().Devices {
+		if !dev.PolledBySNMP() || !e.reachable(dev.Name) {
+			continue
+		}
+		t, err := NewSNMPPoller(dev).PollEndpoints()
+		if err != nil {
+			log.Printf("Error collecting endpoints from %s: %v", dev.Name, err)
+		}
+		if t != nil {
+			tables = append(tables, t)
+		}
+	}
+
+	endpoints := resolveEndpoints(tables, e.topology.Load(), time.Now())
+	if e.detector != nil {
+		e.detector.Process(endpoints)
+	}
+	for _, ep := range endpoints {
+		if err := e.storage.SaveEndpoint(ep); err != nil {
+			log.Printf("Error saving endpoint %s: %v", ep.MAC, err)
 		}
 	}
+	log.Printf("Endpoint inventory updated with %d hosts", len(endpoints))
 }
 
+// pollDevice polls a device's interfaces. The caller must have claimed the
+// poll with beginPoll.
 func (e *PollingEngine) pollDevice(dev models.DeviceConfig) {
-	var metrics []models.InterfaceMetric
-	var err error
+	start := time.Now()
+	p := NewSNMPPoller(dev)
+	p.ReadSTP = e.stpDue(dev.Name, start)
+	metrics, err := p.Poll()
 
-	if dev.Type == models.DeviceTypeMikroTik || dev.SNMP.Version != "" {
-		p := NewSNMPPoller(dev)
-		metrics, err = p.Poll()
+	for _, ev := range e.endPoll(dev, start, time.Now(), err) {
+		log.Printf("Polling event on %s: %s", ev.DeviceName, ev.Message)
+		if err := e.storage.SaveEvent(ev); err != nil {
+			log.Printf("Error saving event for %s: %v", ev.DeviceName, err)
+		}
 	}
-
 	if err != nil {
 		log.Printf("Error polling device %s: %v", dev.Name, err)
 		return
 	}
 
-	for _, m := range metrics {
+	e.mu.Lock()
+	for i := range metrics {
+		m := &metrics[i]
 		key := m.DeviceName + "/" + m.InterfaceName
 		last, ok := e.state[key]
 		if ok {
@@ -83,9 +327,169 @@ func (e *PollingEngine) pollDevice(dev models.DeviceConfig) {
 			lastOutOctets: m.OutOctets,
 			lastTime:      m.Timestamp,
 		}
+	}
+	events := e.applySTP(dev.Name, p, metrics, start)
+	e.mu.Unlock()
 
+	for _, ev := range events {
+		log.Printf("STP event on %s: %s", ev.DeviceName, ev.Message)
+		if err := e.storage.SaveEvent(ev); err != nil {
+			log.Printf("Error saving event for %s: %v", ev.DeviceName, err)
+		}
+	}
+
+	metrics = e.aggregateLags(dev.Name, metrics)
+
+	for _, m := range metrics {
 		if err := e.storage.SaveMetric(m); err != nil {
 			log.Printf("Error saving metric for %s/%s: %v", m.DeviceName, m.InterfaceName, err)
 		}
 	}
 }
+
+// stpDue reports whether spanning-tree state should be read on this poll.
+func (e *PollingEngine) stpDue(device string, now time.Time) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	s, ok := e.stp[device]
+	return !ok || now.Sub(s.read) >= stpInterval
+}
+
+// applySTP records the spanning-tree state read by p and returns its events.
+// Between reads it copies the last port states onto the metrics instead. The
+// caller must hold e.mu.
+func (e *PollingEngine) applySTP(device string, p *SNMPPoller, metrics []models.InterfaceMetric, now time.Time) []models.Event {
+	s, ok := e.stp[device]
+	if !ok {
+		s = &stpState{}
+		e.stp[device] = s
+	}
+	if !p.ReadSTP {
+		for i := range metrics {
+			metrics[i].STPState = s.ports[metrics[i].InterfaceName]
+		}
+		return nil
+	}
+
+	s.read = now
+	if p.STPErr != nil {
+		// Devices without BRIDGE-MIB may fail every read, so only the first
+		// failure is logged.
+		if !s.failed {
+			log.Printf("Could not read spanning-tree state on %s: %v", device, p.STPErr)
+		}
+		s.failed = true
+		return nil
+	}
+	s.failed = false
+	s.ports = make(map[string]string)
+	for _, m := range metrics {
+		if m.STPState != "" {
+			s.ports[m.InterfaceName] = m.STPState
+		}
+	}
+	if p.Bridge == nil {
+		return nil
+	}
+	return e.checkBridge(device, *p.Bridge)
+}
+
+// checkBridge compares spanning-tree state against the previous poll and
+// returns events for a new root bridge or topology change counter increments.
+// The caller must hold e.mu.
+func (e *PollingEngine) checkBridge(device string, bridge models.BridgeStatus) []models.Event {
+	last, ok := e.bridges[device]
+	e.bridges[device] = bridge
+	if !ok {
+		return nil
+	}
+
+	now := time.Now()
+	var events []models.Event
+	if bridge.DesignatedRoot != last.DesignatedRoot {
+		events = append(events, models.Event{
+			Timestamp:  now,
+			DeviceName: device,
+			Kind:       models.EventSTPRootChange,
+			Message:    fmt.Sprintf("root bridge changed from %s to %s", last.DesignatedRoot, bridge.DesignatedRoot),
+		})
+	}
+	if bridge.TopChanges > last.TopChanges {
+		events = append(events, models.Event{
+			Timestamp:  now,
+			DeviceName: device,
+			Kind:       models.EventSTPTopologyChange,
+			Message:    fmt.Sprintf("%d topology change(s), root port %d", bridge.TopChanges-last.TopChanges, bridge.RootPort),
+		})
+	}
+	return events
+}
+
+// aggregateLags rolls member port metrics up to their LAG interface. Members
+// are tagged with their parent so consumers don't double-count throughput. If
+// the device doesn't expose the LAG interface itself, one is synthesized from
+// the member rates.
+func (e *PollingEngine) aggregateLags(device string, metrics []models.InterfaceMetric) []models.InterfaceMetric {
+	topo := e.topology.Load()
+	if topo == nil {
+		return metrics
+	}
+	lags := topo.LagMembers(device)
+	if len(lags) == 0 {
+		return metrics
+	}
+
+	byName := make(map[string]int)
+	for i, m := range metrics {
+		byName[m.InterfaceName] = i
+	}
+
+	for lag, members := range lags {
+		var inSpeed, outSpeed float64
+		var timestamp time.Time
+		up := 0
+		for _, member := range members {
+			i, ok := byName[member]
+			if !ok {
+				continue
+			}
+			metrics[i].Parent = lag
+			inSpeed += metrics[i].InSpeed
+			outSpeed += metrics[i].OutSpeed
+			timestamp = metrics[i].Timestamp
+			if metrics[i].Status == "up" {
+				up++
+			}
+		}
+
+		i, ok := byName[lag]
+		if !ok {
+			if timestamp.IsZero() {
+				continue
+			}
+			metrics = append(metrics, models.InterfaceMetric{
+				DeviceName:    device,
+				InterfaceName: lag,
+				Timestamp:     timestamp,
+				InSpeed:       inSpeed,
+				OutSpeed:      outSpeed,
+			})
+			i = len(metrics) - 1
+			byName[lag] = i
+		}
+
+		agg := &metrics[i]
+		agg.MembersUp = up
+		agg.MembersTotal = len(members)
+		switch {
+		case up == len(members):
+			agg.Status = "up"
+		case up == 0:
+			agg.Status = "down"
+		default:
+			agg.Status = "degraded"
+		}
+	}
+
+	return metrics
+}
@@ -1,10 +1,12 @@
 package poller
 
 import (
+	"errors"
 	"testing"
 	"time"
 
 	"github.com/AMathur20/Home_Network/internal/models"
+	"github.com/AMathur20/Home_Network/internal/topology"
 )
 
 func TestThroughputCalculation(t *testing.T) {
@@ -82,6 +84,127 @@ func TestThroughputRollover(t *testing.T) {
 	}
 }
 
+func TestLagAggregation(t *testing.T) {
+	topo := &topology.Topology{Links: []topology.Link{{
+		SourceDevice:    "core",
+		SourceInterface: "bond1",
+		TargetDevice:    "switch",
+		TargetInterface: "lag1",
+		Type:            topology.LinkTypeLAG,
+		Members: []topology.LinkMember{
+			{SourceInterface: "ether1", TargetInterface: "port1"},
+			{SourceInterface: "ether2", TargetInterface: "port2"},
+		},
+	}, {
+		// The same bond crawled from the switch end
+		SourceDevice:    "switch",
+		SourceInterface: "lag1",
+		TargetDevice:    "core",
+		TargetInterface: "bond1",
+		Type:            topology.LinkTypeLAG,
+		Members: []topology.LinkMember{
+			{SourceInterface: "port1", TargetInterface: "ether1"},
+			{SourceInterface: "port2", TargetInterface: "ether2"},
+		},
+	}}}
+	engine := NewPollingEngine(&models.Config{}, nil, topo)
+
+	now := time.Now()
+	metrics := []models.InterfaceMetric{
+		{DeviceName: "core", InterfaceName: "ether1", Timestamp: now, InSpeed: 100, OutSpeed: 10, Status: "up"},
+		{DeviceName: "core", InterfaceName: "ether2", Timestamp: now, InSpeed: 50, OutSpeed: 5, Status: "down"},
+	}
+
+	metrics = engine.aggregateLags("core", metrics)
+
+	if len(metrics) != 3 {
+		t.Fatalf("Expected synthesized LAG metric, got %d metrics", len(metrics))
+	}
+	if metrics[0].Parent != "bond1" || metrics[1].Parent != "bond1" {
+		t.Errorf("Expected members to be tagged with their LAG, got %q and %q", metrics[0].Parent, metrics[1].Parent)
+	}
+	lag := metrics[2]
+	if lag.InterfaceName != "bond1" || lag.InSpeed != 150 || lag.OutSpeed != 15 {
+		t.Errorf("Unexpected LAG throughput: %+v", lag)
+	}
+	if lag.Status != "degraded" || lag.MembersUp != 1 || lag.MembersTotal != 2 {
+		t.Errorf("Unexpected LAG health: %+v", lag)
+	}
+}
+
+func TestBridgeEvents(t *testing.T) {
+	engine := NewPollingEngine(&models.Config{}, nil, nil)
+
+	bridge := models.BridgeStatus{DesignatedRoot: "8000.aabbccddeeff", RootPort: 1, TopChanges: 4}
+	if events := engine.checkBridge("switch", bridge); len(events) != 0 {
+		t.Errorf("Expected no events on first poll, got %+v", events)
+	}
+	if events := engine.checkBridge("switch", bridge); len(events) != 0 {
+		t.Errorf("Expected no events when nothing changed, got %+v", events)
+	}
+
+	bridge.DesignatedRoot = "1000.112233445566"
+	bridge.TopChanges = 6
+	events := engine.checkBridge("switch", bridge)
+	if len(events) != 2 {
+		t.Fatalf("Expected root change and topology change events, got %+v", events)
+	}
+	if events[0].Kind != models.EventSTPRootChange || events[1].Kind != models.EventSTPTopologyChange {
+		t.Errorf("Unexpected event kinds: %s, %s", events[0].Kind, events[1].Kind)
+	}
+}
+
+func TestSTPCadence(t *testing.T) {
+	engine := NewPollingEngine(&models.Config{}, nil, nil)
+	start := time.Now()
+
+	if !engine.stpDue("switch", start) {
+		t.Fatal("Expected spanning-tree state to be read on the first poll")
+	}
+	read := &SNMPPoller{ReadSTP: true}
+	metrics := []models.InterfaceMetric{{InterfaceName: "ether1", STPState: topology.STPStateBlocking}, {InterfaceName: "ether2"}}
+	engine.applySTP("switch", read, metrics, start)
+
+	if engine.stpDue("switch", start.Add(stpInterval/2)) {
+		t.Error("Expected no read before the STP interval has passed")
+	}
+	metrics = []models.InterfaceMetric{{InterfaceName: "ether1"}, {InterfaceName: "ether2"}}
+	engine.applySTP("switch", &SNMPPoller{}, metrics, start.Add(stpInterval/2))
+	if metrics[0].STPState != topology.STPStateBlocking || metrics[1].STPState != "" {
+		t.Errorf("Expected the last port states to be carried over, got %+v", metrics)
+	}
+
+	if !engine.stpDue("switch", start.Add(stpInterval)) {
+		t.Error("Expected a read once the STP interval has passed")
+	}
+	failed := &SNMPPoller{ReadSTP: true, STPErr: errors.New("timeout")}
+	engine.applySTP("switch", failed, nil, start.Add(stpInterval))
+	if !engine.stp["switch"].failed || engine.stpDue("switch", start.Add(stpInterval*3/2)) {
+		t.Error("Expected a failed read to wait for the next interval")
+	}
+}
+
+func TestPollInterval(t *testing.T) {
+	cfg := &models.Config{Poller: models.IntervalConfig{Live: 5, Priorities: map[string]int{"low": 60}}}
+	engine := NewPollingEngine(cfg, nil, nil)
+
+	tests := []struct {
+		dev  models.DeviceConfig
+		want time.Duration
+	}{
+		{models.DeviceConfig{Name: "switch"}, 5 * time.Second},
+		{models.DeviceConfig{Name: "wan", Priority: models.PriorityHigh}, 2 * time.Second},
+		{models.DeviceConfig{Name: "closet", Priority: models.PriorityLow}, 60 * time.Second},
+		{models.DeviceConfig{Name: "core", Priority: models.PriorityNormal}, 5 * time.Second},
+		{models.DeviceConfig{Name: "ap", Priority: models.PriorityHigh, Interval: 10}, 10 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := engine.interval(tt.dev); got != tt.want {
+			t.Errorf("%s: expected %s, got %s", tt.dev.Name, tt.want, got)
+		}
+	}
+}
+
 // Helper for testing
 func (e *PollingEngine) pollDeviceToUpdateState(m *models.InterfaceMetric) {
 	key := m.DeviceName + "/" + m.InterfaceName
@@ -0,0 +1,58 @@
+package poller
+
+import (
+	"testing"
+
+	"github.com/AMathur20/Home_Network/internal/models"
+)
+
+func TestReloadSchedules(t *testing.T) {
+	snmp := models.SNMPConfig{Version: "v2c", Community: "public"}
+	cfg := &models.Config{Devices: []models.DeviceConfig{
+		{Name: "router", Host: "192.0.2.1", SNMP: snmp, Interval: 3600},
+		{Name: "switch", Host: "192.0.2.2", SNMP: snmp, Interval: 3600},
+		{Name: "ap", Host: "192.0.2.3", Interval: 3600}, // not polled over SNMP
+	}}
+	engine := NewPollingEngine(cfg, nil, nil)
+	engine.started = true
+	engine.updateSchedules()
+
+	if len(engine.schedules) != 2 {
+		t.Fatalf("expected 2 schedules, got %d", len(engine.schedules))
+	}
+	router := engine.schedules["router"]
+	switchSchedule := engine.schedules["switch"]
+	engine.state["switch/ether1"] = interfaceState{}
+
+	changed := snmp
+	changed.Community = "secret"
+	engine.Reload(&models.Config{Devices: []models.DeviceConfig{
+		{Name: "router", Host: "192.0.2.1", SNMP: snmp, Interval: 3600},
+		{Name: "switch", Host: "192.0.2.2", SNMP: changed, Interval: 3600},
+		{Name: "firewall", Host: "192.0.2.4", SNMP: snmp, Interval: 3600},
+	}})
+	if len(engine.schedules) != 3 {
+		t.Fatalf("expected 3 schedules, got %d", len(engine.schedules))
+	}
+	if engine.schedules["router"] != router {
+		t.Error("expected the unchanged device to keep its schedule")
+	}
+	if engine.schedules["switch"] == switchSchedule || engine.schedules["switch"].dev.SNMP.Community != "secret" {
+		t.Error("expected the changed device to be restarted with its new credentials")
+	}
+	select {
+	case <-switchSchedule.stop:
+	default:
+		t.Error("expected the old schedule to be stopped")
+	}
+
+	engine.Reload(&models.Config{Devices: []models.DeviceConfig{
+		{Name: "router", Host: "192.0.2.1", SNMP: snmp, Interval: 60},
+	}})
+	if len(engine.schedules) != 1 || engine.schedules["router"] == router || engine.schedules["router"].interval.Seconds() != 60 {
+		t.Errorf("expected only the router, restarted with its new interval: %+v", engine.schedules)
+	}
+	if _, ok := engine.state["switch/ether1"]; ok {
+		t.Error("expected the removed device's state to be dropped")
+	}
+}
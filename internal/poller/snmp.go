@@ -6,6 +6,8 @@ import (
 	"time"
 
 	"github.com/AMathur20/Home_Network/internal/models"
+	"github.com/AMathur20/Home_Network/internal/snmp"
+	"github.com/AMathur20/Home_Network/internal/topology"
 	"github.com/gosnmp/gosnmp"
 )
 
@@ -20,29 +22,30 @@ const (
 
 type SNMPPoller struct {
 	config models.DeviceConfig
+	// ReadSTP makes Poll also read spanning-tree state.
+	ReadSTP bool
+	// Bridge holds the spanning-tree state seen by the last Poll, nil if the
+	// device does not run STP or it was not read.
+	Bridge *models.BridgeStatus
+	// STPErr is the error from reading spanning-tree state, if any.
+	STPErr error
 }
 
 func NewSNMPPoller(cfg models.DeviceConfig) *SNMPPoller {
 	return &SNMPPoller{config: cfg}
 }
 
-func (p *SNMPPoller) Poll() ([]models.InterfaceMetric, error) {
-	params := &gosnmp.GoSNMP{
-		Target:    p.config.Host,
-		Port:      uint16(p.config.SNMP.Port),
-		Community: p.config.SNMP.Community,
-		Version:   gosnmp.Version2c,
-		Timeout:   time.Duration(2) * time.Second,
-		Retries:   3,
-	}
+func (p *SNMPPoller) connect() (*gosnmp.GoSNMP, error) {
+	return snmp.Dial(p.config)
+}
 
-	err := params.Connect()
+func (p *SNMPPoller) Poll() ([]models.InterfaceMetric, error) {
+	params, err := p.connect()
 	if err != nil {
 		return nil, err
 	}
 	defer params.Conn.Close()
 
-	timestamp := time.Now()
 	metrics := make(map[int]*models.InterfaceMetric)
 
 	// 1. Fetch Interface Names
@@ -52,7 +55,6 @@ func (p *SNMPPoller) Poll() ([]models.InterfaceMetric, error) {
 		metrics[index] = &models.InterfaceMetric{
 			DeviceName:    p.config.Name,
 			InterfaceName: models.PduToString(pdu.Value),
-			Timestamp:     timestamp,
 		}
 		return nil
 	})
@@ -75,7 +77,10 @@ func (p *SNMPPoller) Poll() ([]models.InterfaceMetric, error) {
 		return nil
 	})
 
-	// 3. Fetch Counters (Prefer 64-bit HC counters)
+	// 3. Fetch Counters (Prefer 64-bit HC counters). Rates are computed from
+	// the time between samples, so the timestamp is taken right before the
+	// counters are read rather than at the start of the poll.
+	timestamp := time.Now()
 	useHC := true
 	err = params.BulkWalk(oidIfHCInOctets, func(pdu gosnmp.SnmpPDU) error {
 		index := 0
@@ -119,10 +124,46 @@ func (p *SNMPPoller) Poll() ([]models.InterfaceMetric, error) {
 		})
 	}
 
+	// 4. Spanning-tree port states
+	if p.ReadSTP {
+		stp, err := topology.ReadSTP(params)
+		if err != nil {
+			p.STPErr = err
+		} else if stp != nil {
+			p.Bridge = &stp.Bridge
+			for index, port := range stp.Ports {
+				if m, ok := metrics[index]; ok {
+					m.STPState = port.State
+				}
+			}
+		}
+	}
+
 	result := make([]models.InterfaceMetric, 0, len(metrics))
 	for _, m := range metrics {
+		m.Timestamp = timestamp
 		result = append(result, *m)
 	}
 
 	return result, nil
 }
+
+// InterfaceName looks up the ifName of a single ifIndex.
+func (p *SNMPPoller) InterfaceName(index int) (string, error) {
+	params, err := p.connect()
+	if err != nil {
+		return "", err
+	}
+	defer params.Conn.Close()
+
+	result, err := params.Get([]string{fmt.Sprintf("%s.%d", oidIfName, index)})
+	if err != nil {
+		return "", err
+	}
+	for _, pdu := range result.Variables {
+		if pdu.Type == gosnmp.OctetString {
+			return models.PduToString(pdu.Value), nil
+		}
+	}
+	return "", fmt.Errorf("no ifName for ifIndex %d", index)
+}
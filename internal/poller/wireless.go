@@ -0,0 +1,410 @@
+package poller
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AMathur20/Home_Network/internal/models"
+	"github.com/AMathur20/Home_Network/internal/routeros"
+	"github.com/gosnmp/gosnmp"
+)
+
+const (
+	// MIKROTIK-MIB mtxrWlRtabTable, indexed by MAC.ifIndex
+	oidMtxrWlRtabStrength      = ".1.3.6.1.4.1.14988.1.1.1.2.1.3"
+	oidMtxrWlRtabTxRate        = ".1.3.6.1.4.1.14988.1.1.1.2.1.8"
+	oidMtxrWlRtabRxRate        = ".1.3.6.1.4.1.14988.1.1.1.2.1.9"
+	oidMtxrWlRtabUptime        = ".1.3.6.1.4.1.14988.1.1.1.2.1.11"
+	oidMtxrWlRtabSignalToNoise = ".1.3.6.1.4.1.14988.1.1.1.2.1.12"
+
+	// MIKROTIK-MIB mtxrWlApTable, indexed by ifIndex
+	oidMtxrWlApSsid        = ".1.3.6.1.4.1.14988.1.1.1.3.1.4"
+	oidMtxrWlApClientCount = ".1.3.6.1.4.1.14988.1.1.1.3.1.6"
+	oidMtxrWlApFreq        = ".1.3.6.1.4.1.14988.1.1.1.3.1.7"
+	oidMtxrWlApNoiseFloor  = ".1.3.6.1.4.1.14988.1.1.1.3.1.9"
+
+	// UBNT-UniFi-MIB radio and VAP tables
+	oidUnifiRadioName    = ".1.3.6.1.4.1.41112.1.6.1.1.1.2"
+	oidUnifiRadioRadio   = ".1.3.6.1.4.1.41112.1.6.1.1.1.3"
+	oidUnifiRadioCuTotal = ".1.3.6.1.4.1.41112.1.6.1.1.1.6"
+	oidUnifiVapChannel   = ".1.3.6.1.4.1.41112.1.6.1.2.1.4"
+	oidUnifiVapEssId     = ".1.3.6.1.4.1.41112.1.6.1.2.1.6"
+	oidUnifiVapStations  = ".1.3.6.1.4.1.41112.1.6.1.2.1.8"
+	oidUnifiVapRadio     = ".1.3.6.1.4.1.41112.1.6.1.2.1.9"
+)
+
+// routerOSWireless lists the RouterOS wireless packages from newest to oldest.
+// Only one is installed on a given device.
+var routerOSWireless = []string{"/interface/wifi", "/interface/wifiwave2", "/interface/wireless"}
+
+type wirelessStats struct {
+	clients    []models.WirelessClient
+	interfaces []models.WirelessInterface
+}
+
+// PollWireless reads the AP registration table and radio state. MikroTik
+// devices with an auth block are read over the RouterOS API, which also covers
+// wifiwave2 and the newer wifi package; other devices fall back to the
+// MikroTik or UniFi private MIBs.
+func (p *SNMPPoller) PollWireless() (*wirelessStats, error) {
+	now := time.Now()
+	if p.config.Type == models.DeviceTypeMikroTik && p.config.Auth.Username != "" {
+		return pollWirelessRouterOS(p.config, now)
+	}
+
+	params, err := p.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer params.Conn.Close()
+
+	if p.config.Type == models.DeviceTypeUniFi {
+		return pollWirelessUniFi(params, p.config.Name, now), nil
+	}
+	return pollWirelessMikroTikSNMP(params, p.config.Name, now)
+}
+
+func pollWirelessRouterOS(dev models.DeviceConfig, now time.Time) (*wirelessStats, error) {
+	client, err := routeros.Dial(dev)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	for _, pkg := range routerOSWireless {
+		regs, err := client.Run(pkg + "/registration-table/print")
+		if routeros.IsNoSuchCommand(err) {
+			// Package not installed
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s registrations: %v", pkg, err)
+		}
+		ifaces, err := client.Run(pkg + "/print")
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s interfaces: %v", pkg, err)
+		}
+
+		stats := &wirelessStats{}
+		counts := make(map[string]int)
+		for _, r := range regs {
+			c := parseRegistration(r)
+			c.Timestamp = now
+			c.DeviceName = dev.Name
+			stats.clients = append(stats.clients, c)
+			counts[c.InterfaceName]++
+		}
+
+		for _, i := range ifaces {
+			if i["disabled"] == "true" {
+				continue
+			}
+			w := models.WirelessInterface{
+				Timestamp:          now,
+				DeviceName:         dev.Name,
+				InterfaceName:      i["name"],
+				SSID:               firstNonEmpty(i["ssid"], i["configuration.ssid"]),
+				Clients:            counts[i["name"]],
+				ChannelUtilization: -1,
+			}
+			w.Frequency, _ = strconv.Atoi(i["frequency"])
+			if mon, err := client.Run(pkg+"/monitor", "=numbers="+i["name"], "=once="); err == nil && len(mon) > 0 {
+				w.NoiseFloor = leadingInt(mon[0]["noise-floor"])
+				if f := leadingInt(firstNonEmpty(mon[0]["frequency"], mon[0]["channel"])); f > 0 {
+					w.Frequency = f
+				}
+			}
+			stats.interfaces = append(stats.interfaces, w)
+		}
+		return stats, nil
+	}
+
+	return &wirelessStats{}, nil
+}
+
+// parseRegistration converts a RouterOS registration table entry. The legacy
+// wireless package reports signal-strength ("-62@HT20-7"), signal-to-noise and
+// tx-ccq; wifiwave2 and wifi only report signal.
+func parseRegistration(r map[string]string) models.WirelessClient {
+	c := models.WirelessClient{
+		InterfaceName: r["interface"],
+		MAC:           strings.ToLower(r["mac-address"]),
+		SSID:          r["ssid"],
+		Signal:        leadingInt(firstNonEmpty(r["signal"], r["signal-strength"])),
+		SNR:           leadingInt(r["signal-to-noise"]),
+		TxRate:        parseRate(r["tx-rate"]),
+		RxRate:        parseRate(r["rx-rate"]),
+		CCQ:           leadingInt(r["tx-ccq"]),
+	}
+	if d, err := routeros.ParseDuration(r["uptime"]); err == nil {
+		c.Uptime = int64(d.Seconds())
+	}
+	return c
+}
+
+func pollWirelessMikroTikSNMP(params *gosnmp.GoSNMP, device string, now time.Time) (*wirelessStats, error) {
+	ifNames, err := walkIfNames(params)
+	if err != nil {
+		return nil, err
+	}
+
+	// Registration table rows are keyed by the m1.m2.m3.m4.m5.m6.ifIndex suffix.
+	clients := make(map[string]*models.WirelessClient)
+	order := make([]string, 0)
+	walk := func(oid string, set func(c *models.WirelessClient, v interface{})) {
+		params.BulkWalk(oid, func(pdu gosnmp.SnmpPDU) error {
+			suffix := strings.TrimPrefix(pdu.Name, oid+".")
+			c, ok := clients[suffix]
+			if !ok {
+				parts := strings.Split(suffix, ".")
+				if len(parts) != 7 {
+					return nil
+				}
+				mac, ok := macFromOid(parts[:6])
+				if !ok {
+					return nil
+				}
+				index, _ := strconv.Atoi(parts[6])
+				c = &models.WirelessClient{Timestamp: now, DeviceName: device, InterfaceName: ifNames[index], MAC: mac}
+				clients[suffix] = c
+				order = append(order, suffix)
+			}
+			set(c, pdu.Value)
+			return nil
+		})
+	}
+	walk(oidMtxrWlRtabStrength, func(c *models.WirelessClient, v interface{}) { c.Signal = models.PduToInt(v) })
+	walk(oidMtxrWlRtabSignalToNoise, func(c *models.WirelessClient, v interface{}) { c.SNR = models.PduToInt(v) })
+	walk(oidMtxrWlRtabTxRate, func(c *models.WirelessClient, v interface{}) { c.TxRate = float64(models.PduToUint64(v)) / 1e6 })
+	walk(oidMtxrWlRtabRxRate, func(c *models.WirelessClient, v interface{}) { c.RxRate = float64(models.PduToUint64(v)) / 1e6 })
+	walk(oidMtxrWlRtabUptime, func(c *models.WirelessClient, v interface{}) { c.Uptime = int64(models.PduToUint64(v) / 100) })
+
+	stats := &wirelessStats{}
+	for _, suffix := range order {
+		stats.clients = append(stats.clients, *clients[suffix])
+	}
+
+	radios := make(map[int]*models.WirelessInterface)
+	radioOrder := make([]int, 0)
+	radio := func(index int) *models.WirelessInterface {
+		r, ok := radios[index]
+		if !ok {
+			r = &models.WirelessInterface{Timestamp: now, DeviceName: device, InterfaceName: ifNames[index], ChannelUtilization: -1}
+			radios[index] = r
+			radioOrder = append(radioOrder, index)
+		}
+		return r
+	}
+	for _, col := range []struct {
+		oid string
+		set func(r *models.WirelessInterface, v interface{})
+	}{
+		{oidMtxrWlApSsid, func(r *models.WirelessInterface, v interface{}) { r.SSID = models.PduToString(v) }},
+		{oidMtxrWlApClientCount, func(r *models.WirelessInterface, v interface{}) { r.Clients = models.PduToInt(v) }},
+		{oidMtxrWlApFreq, func(r *models.WirelessInterface, v interface{}) { r.Frequency = models.PduToInt(v) }},
+		{oidMtxrWlApNoiseFloor, func(r *models.WirelessInterface, v interface{}) { r.NoiseFloor = models.PduToInt(v) }},
+	} {
+		params.BulkWalk(col.oid, func(pdu gosnmp.SnmpPDU) error {
+			index := 0
+			fmt.Sscanf(pdu.Name, col.oid+".%d", &index)
+			col.set(radio(index), pdu.Value)
+			return nil
+		})
+	}
+	for _, index := range radioOrder {
+		stats.interfaces = append(stats.interfaces, *radios[index])
+	}
+	return stats, nil
+}
+
+// pollWirelessUniFi reads per-radio client counts and channel utilization.
+// UniFi APs do not expose per-client statistics over SNMP; those are only
+// available from the controller.
+func pollWirelessUniFi(params *gosnmp.GoSNMP, device string, now time.Time) *wirelessStats {
+	type vap struct {
+		essid    string
+		channel  int
+		stations int
+		radio    string
+	}
+	vaps := make(map[string]*vap)
+	get := func(index string) *vap {
+		v, ok := vaps[index]
+		if !ok {
+			v = &vap{}
+			vaps[index] = v
+		}
+		return v
+	}
+	walkIndexed := func(oid string, set func(index string, v interface{})) {
+		params.BulkWalk(oid, func(pdu gosnmp.SnmpPDU) error {
+			set(strings.TrimPrefix(pdu.Name, oid+"."), pdu.Value)
+			return nil
+		})
+	}
+	walkIndexed(oidUnifiVapEssId, func(i string, v interface{}) { get(i).essid = models.PduToString(v) })
+	walkIndexed(oidUnifiVapChannel, func(i string, v interface{}) { get(i).channel = models.PduToInt(v) })
+	walkIndexed(oidUnifiVapStations, func(i string, v interface{}) { get(i).stations = models.PduToInt(v) })
+	walkIndexed(oidUnifiVapRadio, func(i string, v interface{}) { get(i).radio = models.PduToString(v) })
+
+	// Radios are matched to their VAPs by band ("ng", "na", ...).
+	radios := make(map[string]*models.WirelessInterface)
+	order := make([]string, 0)
+	radio := func(index string) *models.WirelessInterface {
+		r, ok := radios[index]
+		if !ok {
+			r = &models.WirelessInterface{Timestamp: now, DeviceName: device, ChannelUtilization: -1}
+			radios[index] = r
+			order = append(order, index)
+		}
+		return r
+	}
+	bands := make(map[string]string)
+	walkIndexed(oidUnifiRadioName, func(i string, v interface{}) { radio(i).InterfaceName = models.PduToString(v) })
+	walkIndexed(oidUnifiRadioRadio, func(i string, v interface{}) { radio(i); bands[i] = models.PduToString(v) })
+	walkIndexed(oidUnifiRadioCuTotal, func(i string, v interface{}) { radio(i).ChannelUtilization = float64(models.PduToInt(v)) })
+
+	stats := &wirelessStats{}
+	for _, index := range order {
+		r := radios[index]
+		var ssids []string
+		for _, v := range vaps {
+			if v.radio != bands[index] {
+				continue
+			}
+			r.Clients += v.stations
+			if r.Frequency == 0 {
+				r.Frequency = channelToFrequency(v.channel)
+			}
+			if v.essid != "" {
+				ssids = append(ssids, v.essid)
+			}
+		}
+		r.SSID = strings.Join(ssids, ",")
+		stats.interfaces = append(stats.interfaces, *r)
+	}
+	return stats
+}
+
+// channelToFrequency returns the centre frequency in MHz of a 2.4 or 5 GHz
+// channel.
+func channelToFrequency(channel int) int {
+	switch {
+	case channel == 14:
+		return 2484
+	case channel >= 1 && channel <= 13:
+		return 2407 + channel*5
+	case channel >= 32 && channel <= 177:
+		return 5000 + channel*5
+	}
+	return 0
+}
+
+// parseRate converts a RouterOS rate such as "866.7Mbps-80MHz/2S/SGI" to Mbps.
+func parseRate(v string) float64 {
+	end := 0
+	for end < len(v) && (v[end] == '.' || (v[end] >= '0' && v[end] <= '9')) {
+		end++
+	}
+	rate, err := strconv.ParseFloat(v[:end], 64)
+	if err != nil {
+		return 0
+	}
+	switch {
+	case strings.HasPrefix(v[end:], "Gbps"):
+		return rate * 1000
+	case strings.HasPrefix(v[end:], "kbps"):
+		return rate / 1000
+	case strings.HasPrefix(v[end:], "bps"):
+		return rate / 1e6
+	}
+	return rate
+}
+
+// leadingInt parses the signed integer at the start of v, ignoring units and
+// suffixes such as "dBm" or "@HT20-7".
+func leadingInt(v string) int {
+	end := 0
+	if end < len(v) && (v[end] == '-' || v[end] == '+') {
+		end++
+	}
+	for end < len(v) && v[end] >= '0' && v[end] <= '9' {
+		end++
+	}
+	n, _ := strconv.Atoi(v[:end])
+	return n
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func (e *PollingEngine) pollWireless() {
+	var clients []models.WirelessClient
+	for _, dev := range e.config().Devices {
+		if dev.Type != models.DeviceTypeMikroTik && dev.Type != models.DeviceTypeUniFi {
+			continue
+		}
+		if !dev.PolledBySNMP() || !e.reachable(dev.Name) {
+			continue
+		}
+		stats, err := NewSNMPPoller(dev).PollWireless()
+		if err != nil {
+			log.Printf("Error collecting wireless clients from %s: %v", dev.Name, err)
+			continue
+		}
+		for _, w := range stats.interfaces {
+			if err := e.storage.SaveWirelessInterface(w); err != nil {
+				log.Printf("Error saving wireless interface %s/%s: %v", w.DeviceName, w.InterfaceName, err)
+			}
+		}
+		for _, c := range stats.clients {
+			if err := e.storage.SaveWirelessClient(c); err != nil {
+				log.Printf("Error saving wireless client %s: %v", c.MAC, err)
+			}
+		}
+		clients = append(clients, stats.clients...)
+	}
+
+	e.mu.Lock()
+	events := e.checkRoaming(clients)
+	e.mu.Unlock()
+
+	for _, ev := range events {
+		if err := e.storage.SaveEvent(ev); err != nil {
+			log.Printf("Error saving event for %s: %v", ev.DeviceName, err)
+		}
+	}
+}
+
+// checkRoaming returns an event for every client that is now associated with
+// a different AP or radio than in the previous collection. The caller must
+// hold e.mu.
+func (e *PollingEngine) checkRoaming(clients []models.WirelessClient) []models.Event {
+	var events []models.Event
+	for _, c := range clients {
+		location := c.DeviceName + "/" + c.InterfaceName
+		last, ok := e.roaming[c.MAC]
+		e.roaming[c.MAC] = location
+		if !ok || last == location {
+			continue
+		}
+		events = append(events, models.Event{
+			Timestamp:     c.Timestamp,
+			DeviceName:    c.DeviceName,
+			InterfaceName: c.InterfaceName,
+			Kind:          models.EventWirelessRoam,
+			MAC:           c.MAC,
+			Message:       fmt.Sprintf("%s roamed from %s to %s (signal %d dBm)", c.MAC, last, location, c.Signal),
+		})
+	}
+	return events
+}
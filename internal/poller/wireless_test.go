@@ -0,0 +1,67 @@
+package poller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/AMathur20/Home_Network/internal/models"
+)
+
+func TestParseRegistration(t *testing.T) {
+	legacy := parseRegistration(map[string]string{
+		"interface":       "wlan1",
+		"mac-address":     "AA:BB:CC:00:00:01",
+		"signal-strength": "-62@HT20-7",
+		"signal-to-noise": "41",
+		"tx-rate":         "130Mbps-20MHz/2S/SGI",
+		"rx-rate":         "1.2Gbps",
+		"tx-ccq":          "87",
+		"uptime":          "1h2m3s",
+	})
+	want := models.WirelessClient{
+		InterfaceName: "wlan1", MAC: "aa:bb:cc:00:00:01", Signal: -62, SNR: 41,
+		TxRate: 130, RxRate: 1200, CCQ: 87, Uptime: 3723,
+	}
+	if legacy != want {
+		t.Errorf("Unexpected legacy registration %+v", legacy)
+	}
+
+	wave2 := parseRegistration(map[string]string{
+		"interface":   "wifi1",
+		"mac-address": "aa:bb:cc:00:00:02",
+		"ssid":        "home",
+		"signal":      "-71",
+		"tx-rate":     "866.7Mbps-80MHz/2S/SGI",
+		"uptime":      "2d",
+	})
+	if wave2.Signal != -71 || wave2.TxRate != 866.7 || wave2.SSID != "home" || wave2.Uptime != 172800 {
+		t.Errorf("Unexpected wifiwave2 registration %+v", wave2)
+	}
+}
+
+func TestChannelToFrequency(t *testing.T) {
+	for channel, want := range map[int]int{1: 2412, 6: 2437, 14: 2484, 36: 5180, 149: 5745, 0: 0} {
+		if got := channelToFrequency(channel); got != want {
+			t.Errorf("channelToFrequency(%d) = %d, want %d", channel, got, want)
+		}
+	}
+}
+
+func TestWirelessRoaming(t *testing.T) {
+	engine := NewPollingEngine(&models.Config{}, nil, nil)
+	now := time.Now()
+
+	client := models.WirelessClient{Timestamp: now, DeviceName: "ap-upstairs", InterfaceName: "wifi1", MAC: "aa:bb:cc:00:00:01", Signal: -60}
+	if events := engine.checkRoaming([]models.WirelessClient{client}); len(events) != 0 {
+		t.Errorf("Expected no event on first sighting, got %+v", events)
+	}
+	if events := engine.checkRoaming([]models.WirelessClient{client}); len(events) != 0 {
+		t.Errorf("Expected no event when staying put, got %+v", events)
+	}
+
+	client.DeviceName = "ap-downstairs"
+	events := engine.checkRoaming([]models.WirelessClient{client})
+	if len(events) != 1 || events[0].Kind != models.EventWirelessRoam || events[0].DeviceName != "ap-downstairs" {
+		t.Errorf("Expected roam event, got %+v", events)
+	}
+}
@@ -0,0 +1,12 @@
+//go:build !linux && !darwin
+
+package probe
+
+import (
+	"errors"
+	"net"
+)
+
+func listenICMP(ipv6 bool) (net.PacketConn, error) {
+	return nil, errors.New("unprivileged ICMP is not supported on this platform")
+}
@@ -0,0 +1,26 @@
+//go:build linux || darwin
+
+package probe
+
+import (
+	"net"
+	"os"
+	"syscall"
+)
+
+// listenICMP opens an unprivileged ICMP datagram ("ping") socket. On Linux
+// the process group must be within net.ipv4.ping_group_range, which Docker
+// allows by default.
+func listenICMP(ipv6 bool) (net.PacketConn, error) {
+	family, proto := syscall.AF_INET, syscall.IPPROTO_ICMP
+	if ipv6 {
+		family, proto = syscall.AF_INET6, syscall.IPPROTO_ICMPV6
+	}
+	fd, err := syscall.Socket(family, syscall.SOCK_DGRAM, proto)
+	if err != nil {
+		return nil, os.NewSyscallError("socket", err)
+	}
+	f := os.NewFile(uintptr(fd), "icmp")
+	defer f.Close()
+	return net.FilePacketConn(f)
+}
@@ -0,0 +1,220 @@
+// Package probe runs active reachability checks (ICMP ping, TCP connect and
+// HTTP) so a device's state doesn't depend on SNMP alone.
+package probe
+
+import (
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/AMathur20/Home_Network/internal/models"
+)
+
+// attemptFunc performs one attempt of a check and returns its round-trip time.
+type attemptFunc func() (time.Duration, error)
+
+// Run executes one round of a probe: count attempts for ICMP and TCP, a single
+// request for HTTP.
+func Run(t models.ProbeTarget, count int, timeout time.Duration) models.ProbeResult {
+	result := models.ProbeResult{
+		Timestamp:  time.Now(),
+		Name:       t.Name,
+		DeviceName: t.Device,
+		Type:       t.Type,
+		Target:     target(t),
+	}
+
+	var rtts []time.Duration
+	var lastErr error
+	attempts := count
+	switch t.Type {
+	case models.ProbeICMP:
+		rtts, lastErr = ping(t.Host, count, timeout)
+	case models.ProbeTCP:
+		rtts, lastErr = repeat(count, func() (time.Duration, error) { return connect(result.Target, timeout) })
+	case models.ProbeHTTP:
+		attempts = 1
+		rtts, lastErr = repeat(1, func() (time.Duration, error) { return get(t, timeout) })
+	default:
+		lastErr = fmt.Errorf("unknown probe type %q", t.Type)
+	}
+
+	summarize(&result, rtts, attempts, lastErr)
+	return result
+}
+
+func target(t models.ProbeTarget) string {
+	switch t.Type {
+	case models.ProbeTCP:
+		return net.JoinHostPort(t.Host, strconv.Itoa(t.Port))
+	case models.ProbeHTTP:
+		if t.URL != "" {
+			return t.URL
+		}
+		return "http://" + t.Host + "/"
+	default:
+		return t.Host
+	}
+}
+
+// summarize fills in RTT, jitter, loss and status from the successful
+// attempts. Jitter is the mean absolute difference between consecutive
+// round-trip times.
+func summarize(r *models.ProbeResult, rtts []time.Duration, attempts int, lastErr error) {
+	if lastErr != nil {
+		r.Error = lastErr.Error()
+	}
+	if attempts > 0 {
+		r.Loss = float64(attempts-len(rtts)) * 100 / float64(attempts)
+	}
+	r.Status = "down"
+	if len(rtts) == 0 {
+		r.Loss = 100
+		return
+	}
+	r.Status = "up"
+
+	var sum, diffs float64
+	for i, rtt := range rtts {
+		ms := float64(rtt) / float64(time.Millisecond)
+		sum += ms
+		if i > 0 {
+			diffs += math.Abs(ms - float64(rtts[i-1])/float64(time.Millisecond))
+		}
+	}
+	r.RTT = sum / float64(len(rtts))
+	if len(rtts) > 1 {
+		r.Jitter = diffs / float64(len(rtts)-1)
+	}
+}
+
+func repeat(count int, attempt attemptFunc) ([]time.Duration, error) {
+	var rtts []time.Duration
+	var lastErr error
+	for i := 0; i < count; i++ {
+		rtt, err := attempt()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		rtts = append(rtts, rtt)
+	}
+	return rtts, lastErr
+}
+
+func connect(addr string, timeout time.Duration) (time.Duration, error) {
+	start := time.Now()
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return 0, err
+	}
+	rtt := time.Since(start)
+	conn.Close()
+	return rtt, nil
+}
+
+func get(t models.ProbeTarget, timeout time.Duration) (time.Duration, error) {
+	client := &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			TLSClientConfig:   &tls.Config{InsecureSkipVerify: t.Insecure},
+			DisableKeepAlives: true,
+		},
+	}
+	start := time.Now()
+	resp, err := client.Get(target(t))
+	if err != nil {
+		return 0, err
+	}
+	rtt := time.Since(start)
+	resp.Body.Close()
+
+	if t.ExpectStatus != 0 && resp.StatusCode != t.ExpectStatus {
+		return 0, fmt.Errorf("got %s, want %d", resp.Status, t.ExpectStatus)
+	}
+	if t.ExpectStatus == 0 && resp.StatusCode >= 400 {
+		return 0, fmt.Errorf("got %s", resp.Status)
+	}
+	return rtt, nil
+}
+
+// ping sends count ICMP echo requests, one at a time, over an unprivileged
+// socket. The kernel assigns the echo identifier, so replies are matched by
+// sequence number.
+func ping(host string, count int, timeout time.Duration) ([]time.Duration, error) {
+	ip, err := resolve(host)
+	if err != nil {
+		return nil, err
+	}
+	ipv6 := ip.To4() == nil
+	conn, err := listenICMP(ipv6)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	echoRequest, echoReply := byte(8), byte(0)
+	if ipv6 {
+		echoRequest, echoReply = 128, 129
+	}
+	dst := &net.UDPAddr{IP: ip}
+
+	var rtts []time.Duration
+	var lastErr error
+	buf := make([]byte, 1500)
+	for seq := 1; seq <= count; seq++ {
+		msg := make([]byte, 16)
+		msg[0] = echoRequest
+		binary.BigEndian.PutUint16(msg[6:], uint16(seq))
+		binary.BigEndian.PutUint64(msg[8:], uint64(time.Now().UnixNano()))
+
+		start := time.Now()
+		if _, err := conn.WriteTo(msg, dst); err != nil {
+			lastErr = err
+			continue
+		}
+		conn.SetReadDeadline(start.Add(timeout))
+		for {
+			n, _, err := conn.ReadFrom(buf)
+			if err != nil {
+				lastErr = errors.New("request timed out")
+				break
+			}
+			reply := buf[:n]
+			// macOS includes the IPv4 header on datagram ICMP sockets.
+			if !ipv6 && n >= 20 && reply[0]>>4 == 4 {
+				reply = reply[int(reply[0]&0x0f)*4:]
+			}
+			if len(reply) >= 8 && reply[0] == echoReply && int(binary.BigEndian.Uint16(reply[6:])) == seq {
+				rtts = append(rtts, time.Since(start))
+				break
+			}
+		}
+	}
+	return rtts, lastErr
+}
+
+func resolve(host string) (net.IP, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return ip, nil
+	}
+	addrs, err := net.LookupIP(host)
+	if err != nil {
+		return nil, err
+	}
+	for _, ip := range addrs {
+		if ip.To4() != nil {
+			return ip, nil
+		}
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("no address for %s", host)
+	}
+	return addrs[0], nil
+}
@@ -0,0 +1,134 @@
+package probe
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/AMathur20/Home_Network/internal/models"
+)
+
+func TestSummarize(t *testing.T) {
+	var r models.ProbeResult
+	rtts := []time.Duration{10 * time.Millisecond, 14 * time.Millisecond, 12 * time.Millisecond}
+	summarize(&r, rtts, 4, errors.New("request timed out"))
+
+	if r.Status != "up" || r.RTT != 12 || r.Jitter != 3 || r.Loss != 25 || r.Error != "request timed out" {
+		t.Errorf("unexpected result: %+v", r)
+	}
+
+	r = models.ProbeResult{}
+	summarize(&r, nil, 5, errors.New("connection refused"))
+	if r.Status != "down" || r.Loss != 100 {
+		t.Errorf("unexpected result: %+v", r)
+	}
+}
+
+func TestRunTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	addr := ln.Addr().(*net.TCPAddr)
+
+	target := models.ProbeTarget{Name: "local", Type: models.ProbeTCP, Host: "127.0.0.1", Port: addr.Port}
+	r := Run(target, 3, time.Second)
+	if r.Status != "up" || r.Loss != 0 || r.Target != "127.0.0.1:"+strconv.Itoa(addr.Port) {
+		t.Errorf("unexpected result: %+v", r)
+	}
+
+	ln.Close()
+	r = Run(target, 2, time.Second)
+	if r.Status != "down" || r.Loss != 100 || r.Error == "" {
+		t.Errorf("expected closed port to be down: %+v", r)
+	}
+}
+
+func TestRunHTTP(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name   string
+		path   string
+		expect int
+		status string
+	}{
+		{"any success", "/", 0, "up"},
+		{"expected status", "/", http.StatusNoContent, "up"},
+		{"unexpected status", "/", http.StatusOK, "down"},
+		{"not found", "/missing", 0, "down"},
+		{"not found expected", "/missing", http.StatusNotFound, "up"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := models.ProbeTarget{Type: models.ProbeHTTP, URL: srv.URL + tt.path, ExpectStatus: tt.expect}
+			r := Run(target, 5, time.Second)
+			if r.Status != tt.status {
+				t.Errorf("got status %s, want %s (%s)", r.Status, tt.status, r.Error)
+			}
+		})
+	}
+}
+
+func TestTargets(t *testing.T) {
+	cfg := &models.Config{
+		Devices: []models.DeviceConfig{{Name: "core-router", Host: "192.168.88.1"}},
+		Probes: models.ProbeConfig{Targets: []models.ProbeTarget{
+			{Device: "core-router", Type: models.ProbeTCP, Port: 8291},
+			{Type: models.ProbeHTTP, URL: "https://example.com/"},
+		}},
+	}
+	targets := Targets(cfg)
+	if len(targets) != 3 {
+		t.Fatalf("expected 3 targets, got %d", len(targets))
+	}
+	if targets[0].Name != "core-router icmp" || targets[0].Host != "192.168.88.1" {
+		t.Errorf("unexpected device probe: %+v", targets[0])
+	}
+	if targets[1].Name != "core-router tcp/8291" || targets[1].Host != "192.168.88.1" {
+		t.Errorf("unexpected tcp probe: %+v", targets[1])
+	}
+	if targets[2].Name != "https://example.com/" {
+		t.Errorf("unexpected http probe: %+v", targets[2])
+	}
+}
+
+func TestDeviceReachability(t *testing.T) {
+	results := []models.ProbeResult{
+		{Name: "core-router icmp", DeviceName: "core-router", Status: "down"},
+		{Name: "core-router tcp/8291", DeviceName: "core-router", Status: "up", RTT: 3},
+		{Name: "core-router https", DeviceName: "core-router", Status: "up", RTT: 1},
+		{Name: "switch icmp", DeviceName: "switch", Status: "down"},
+		{Name: "internet", Status: "up"},
+	}
+	devices := DeviceReachability(results)
+	if len(devices) != 2 {
+		t.Fatalf("expected 2 devices, got %d", len(devices))
+	}
+	if r := devices["core-router"]; r.Status != "up" || r.Name != "core-router https" {
+		t.Errorf("unexpected core-router result: %+v", r)
+	}
+	if r := devices["switch"]; r.Status != "down" {
+		t.Errorf("unexpected switch result: %+v", r)
+	}
+}
@@ -0,0 +1,206 @@
+package probe
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/AMathur20/Home_Network/internal/models"
+	"github.com/AMathur20/Home_Network/internal/storage"
+)
+
+// Prober runs every configured probe on a fixed interval, stores the results
+// and records device_down/device_up events when a device's probes change
+// state.
+type Prober struct {
+	storage  *storage.DuckDBStorage
+	reloaded chan struct{}
+
+	mu       sync.Mutex
+	interval time.Duration
+	timeout  time.Duration
+	count    int
+	targets  []models.ProbeTarget
+	devices  map[string]string // device -> last status
+}
+
+func NewProber(cfg *models.Config, s *storage.DuckDBStorage) *Prober {
+	p := &Prober{
+		storage:  s,
+		reloaded: make(chan struct{}, 1),
+		devices:  make(map[string]string),
+	}
+	p.configure(cfg)
+	return p
+}
+
+// Reload switches to the probes and settings of a new config.
+func (p *Prober) Reload(cfg *models.Config) {
+	p.configure(cfg)
+	select {
+	case p.reloaded <- struct{}{}:
+	default:
+	}
+}
+
+func (p *Prober) configure(cfg *models.Config) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.interval = time.Duration(cfg.Probes.Interval) * time.Second
+	p.timeout = time.Duration(cfg.Probes.Timeout) * time.Second
+	p.count = cfg.Probes.Count
+	p.targets = Targets(cfg)
+	if p.interval <= 0 {
+		p.interval = 30 * time.Second
+	}
+	if p.timeout <= 0 {
+		p.timeout = 2 * time.Second
+	}
+	if p.count <= 0 {
+		p.count = 5
+	}
+}
+
+// Targets returns an ICMP probe for every device followed by the configured
+// targets, with hosts and names filled in.
+func Targets(cfg *models.Config) []models.ProbeTarget {
+	hosts := make(map[string]string)
+	var targets []models.ProbeTarget
+	for _, dev := range cfg.Devices {
+		hosts[dev.Name] = dev.Host
+		targets = append(targets, models.ProbeTarget{
+			Name:   dev.Name + " icmp",
+			Device: dev.Name,
+			Type:   models.ProbeICMP,
+			Host:   dev.Host,
+		})
+	}
+
+	for _, t := range cfg.Probes.Targets {
+		if t.Host == "" {
+			t.Host = hosts[t.Device]
+		}
+		if t.Name == "" {
+			subject := t.Device
+			if subject == "" {
+				subject = t.Host
+			}
+			switch t.Type {
+			case models.ProbeTCP:
+				t.Name = fmt.Sprintf("%s tcp/%d", subject, t.Port)
+			case models.ProbeHTTP:
+				t.Name = target(t)
+				if t.Device != "" {
+					t.Name = t.Device + " " + t.Name
+				}
+			default:
+				t.Name = subject + " " + t.Type
+			}
+		}
+		targets = append(targets, t)
+	}
+	return targets
+}
+
+func (p *Prober) Start() {
+	p.mu.Lock()
+	interval := p.interval
+	log.Printf("Running %d reachability probes every %s", len(p.targets), interval)
+	p.mu.Unlock()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		p.RunOnce()
+		select {
+		case <-ticker.C:
+		case <-p.reloaded:
+			p.mu.Lock()
+			if p.interval != interval {
+				interval = p.interval
+				ticker.Reset(interval)
+			}
+			p.mu.Unlock()
+		}
+	}
+}
+
+// RunOnce runs all probes concurrently and stores their results.
+func (p *Prober) RunOnce() []models.ProbeResult {
+	p.mu.Lock()
+	targets, count, timeout := p.targets, p.count, p.timeout
+	p.mu.Unlock()
+
+	results := make([]models.ProbeResult, len(targets))
+	var wg sync.WaitGroup
+	for i, t := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = Run(t, count, timeout)
+		}()
+	}
+	wg.Wait()
+
+	for _, r := range results {
+		if err := p.storage.SaveProbeResult(r); err != nil {
+			log.Printf("Error saving probe result for %s: %v", r.Name, err)
+		}
+	}
+
+	for _, ev := range p.checkDevices(results) {
+		log.Printf("Reachability event on %s: %s", ev.DeviceName, ev.Message)
+		if err := p.storage.SaveEvent(ev); err != nil {
+			log.Printf("Error saving event for %s: %v", ev.DeviceName, err)
+		}
+	}
+	return results
+}
+
+// checkDevices compares each device's reachability against the previous
+// round. A device found down on the first round is reported too.
+func (p *Prober) checkDevices(results []models.ProbeResult) []models.Event {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var events []models.Event
+	for device, r := range DeviceReachability(results) {
+		last, seen := p.devices[device]
+		p.devices[device] = r.Status
+		if last == r.Status || (!seen && r.Status == "up") {
+			continue
+		}
+		ev := models.Event{Timestamp: r.Timestamp, DeviceName: device}
+		if r.Status == "up" {
+			ev.Kind = models.EventDeviceUp
+			ev.Message = fmt.Sprintf("reachable again (%s, %.1f ms)", r.Name, r.RTT)
+		} else {
+			ev.Kind = models.EventDeviceDown
+			ev.Message = fmt.Sprintf("unreachable: %s", r.Error)
+		}
+		events = append(events, ev)
+	}
+	return events
+}
+
+// DeviceReachability reduces probe results to one per device: a device is up
+// if any of its probes is, represented by its fastest successful probe.
+func DeviceReachability(results []models.ProbeResult) map[string]models.ProbeResult {
+	devices := make(map[string]models.ProbeResult)
+	for _, r := range results {
+		if r.DeviceName == "" {
+			continue
+		}
+		best, ok := devices[r.DeviceName]
+		switch {
+		case !ok:
+		case best.Status != "up" && r.Status == "up":
+		case best.Status == "up" && r.Status == "up" && r.RTT < best.RTT:
+		default:
+			continue
+		}
+		devices[r.DeviceName] = r
+	}
+	return devices
+}
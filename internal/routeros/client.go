@@ -0,0 +1,273 @@
+// Package routeros implements the subset of the MikroTik RouterOS API needed
+// to read tables that are not exposed over SNMP.
+package routeros
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AMathur20/Home_Network/internal/models"
+)
+
+const DefaultPort = 8728
+
+type Client struct {
+	conn    net.Conn
+	reader  *bufio.Reader
+	timeout time.Duration
+}
+
+// Dial connects to the RouterOS API of dev and logs in with its auth block.
+func Dial(dev models.DeviceConfig) (*Client, error) {
+	port := dev.Auth.Port
+	if port == 0 {
+		port = DefaultPort
+	}
+	timeout := 5 * time.Second
+
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(dev.Host, strconv.Itoa(port)), timeout)
+	if err != nil {
+		return nil, err
+	}
+	c := newClient(conn, timeout)
+
+	if _, err := c.Run("/login", "=name="+dev.Auth.Username, "=password="+dev.Auth.Password); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("login failed: %w", err)
+	}
+	return c, nil
+}
+
+func newClient(conn net.Conn, timeout time.Duration) *Client {
+	return &Client{conn: conn, reader: bufio.NewReader(conn), timeout: timeout}
+}
+
+// Run sends a command and collects every !re reply as a key/value map.
+func (c *Client) Run(command string, args ...string) ([]map[string]string, error) {
+	if c.timeout > 0 {
+		c.conn.SetDeadline(time.Now().Add(c.timeout))
+	}
+	if err := c.writeSentence(append([]string{command}, args...)); err != nil {
+		return nil, err
+	}
+
+	var replies []map[string]string
+	for {
+		sentence, err := c.readSentence()
+		if err != nil {
+			return nil, err
+		}
+		if len(sentence) == 0 {
+			continue
+		}
+
+		attrs := parseAttributes(sentence[1:])
+		switch sentence[0] {
+		case "!re":
+			replies = append(replies, attrs)
+		case "!done":
+			return replies, nil
+		case "!trap":
+			// A trap is always followed by !done, which must be drained.
+			if _, err := c.readUntilDone(); err != nil {
+				return nil, err
+			}
+			return nil, &TrapError{Message: attrs["message"]}
+		case "!fatal":
+			return nil, fmt.Errorf("fatal: %s", strings.Join(sentence[1:], " "))
+		}
+	}
+}
+
+// TrapError is the message of a !trap reply, the router refusing a command.
+type TrapError struct {
+	Message string
+}
+
+func (e *TrapError) Error() string {
+	return e.Message
+}
+
+// IsNoSuchCommand reports whether err is the router rejecting a menu it
+// doesn't have, such as /interface/ethernet/poe on hardware without PoE or
+// /interface/wifi without the wifi package.
+func IsNoSuchCommand(err error) bool {
+	var trap *TrapError
+	return errors.As(err, &trap) && strings.HasPrefix(trap.Message, "no such command")
+}
+
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+func (c *Client) readUntilDone() ([]string, error) {
+	for {
+		sentence, err := c.readSentence()
+		if err != nil {
+			return nil, err
+		}
+		if len(sentence) > 0 && sentence[0] == "!done" {
+			return sentence, nil
+		}
+	}
+}
+
+func parseAttributes(words []string) map[string]string {
+	attrs := make(map[string]string)
+	for _, w := range words {
+		if !strings.HasPrefix(w, "=") {
+			continue
+		}
+		kv := strings.SplitN(w[1:], "=", 2)
+		if len(kv) == 2 {
+			attrs[kv[0]] = kv[1]
+		}
+	}
+	return attrs
+}
+
+func (c *Client) writeSentence(words []string) error {
+	var buf []byte
+	for _, w := range words {
+		buf = append(buf, encodeLength(len(w))...)
+		buf = append(buf, w...)
+	}
+	buf = append(buf, 0)
+	_, err := c.conn.Write(buf)
+	return err
+}
+
+func (c *Client) readSentence() ([]string, error) {
+	var words []string
+	for {
+		n, err := c.readLength()
+		if err != nil {
+			return nil, err
+		}
+		if n == 0 {
+			return words, nil
+		}
+		word := make([]byte, n)
+		if _, err := io.ReadFull(c.reader, word); err != nil {
+			return nil, err
+		}
+		words = append(words, string(word))
+	}
+}
+
+// encodeLength implements the variable-length word prefix of the API protocol.
+func encodeLength(n int) []byte {
+	switch {
+	case n < 0x80:
+		return []byte{byte(n)}
+	case n < 0x4000:
+		return []byte{byte(n>>8) | 0x80, byte(n)}
+	case n < 0x200000:
+		return []byte{byte(n>>16) | 0xC0, byte(n >> 8), byte(n)}
+	case n < 0x10000000:
+		return []byte{byte(n>>24) | 0xE0, byte(n >> 16), byte(n >> 8), byte(n)}
+	default:
+		return []byte{0xF0, byte(n >> 24), byte(n >> 16), byte(n >> 8), byte(n)}
+	}
+}
+
+func (c *Client) readLength() (int, error) {
+	first, err := c.reader.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+
+	var extra int
+	var n int
+	switch {
+	case first&0x80 == 0:
+		return int(first), nil
+	case first&0xC0 == 0x80:
+		extra, n = 1, int(first&0x3F)
+	case first&0xE0 == 0xC0:
+		extra, n = 2, int(first&0x1F)
+	case first&0xF0 == 0xE0:
+		extra, n = 3, int(first&0x0F)
+	case first == 0xF0:
+		extra, n = 4, 0
+	default:
+		return 0, fmt.Errorf("invalid word length prefix 0x%02x", first)
+	}
+
+	for i := 0; i < extra; i++ {
+		b, err := c.reader.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		n = n<<8 | int(b)
+	}
+	return n, nil
+}
+
+// ParseList splits a comma separated RouterOS list value such as "ether1,ether2".
+func ParseList(v string) []string {
+	if v == "" {
+		return nil
+	}
+	parts := strings.Split(v, ",")
+	for i := range parts {
+		parts[i] = strings.TrimSpace(parts[i])
+	}
+	return parts
+}
+
+// ParseDuration parses a RouterOS duration such as "1w2d3h4m5s", "150ms" or
+// the older "1d02:03:04" form.
+func ParseDuration(v string) (time.Duration, error) {
+	if v == "" {
+		return 0, errors.New("empty duration")
+	}
+	units := map[string]time.Duration{
+		"w": 7 * 24 * time.Hour, "d": 24 * time.Hour, "h": time.Hour,
+		"m": time.Minute, "s": time.Second, "ms": time.Millisecond, "us": time.Microsecond,
+	}
+
+	var total time.Duration
+	rest := v
+	if clock := strings.LastIndexByte(v, ':'); clock >= 0 {
+		// The clock part is the trailing hh:mm:ss
+		start := strings.LastIndexAny(v[:clock], "wd") + 1
+		parts := strings.Split(v[start:], ":")
+		if len(parts) != 3 {
+			return 0, fmt.Errorf("invalid duration %q", v)
+		}
+		for i, unit := range []time.Duration{time.Hour, time.Minute, time.Second} {
+			n, err := strconv.Atoi(parts[i])
+			if err != nil {
+				return 0, fmt.Errorf("invalid duration %q", v)
+			}
+			total += time.Duration(n) * unit
+		}
+		rest = v[:start]
+	}
+
+	for rest != "" {
+		i := 0
+		for i < len(rest) && rest[i] >= '0' && rest[i] <= '9' {
+			i++
+		}
+		j := i
+		for j < len(rest) && (rest[j] < '0' || rest[j] > '9') {
+			j++
+		}
+		n, err := strconv.Atoi(rest[:i])
+		unit, ok := units[rest[i:j]]
+		if err != nil || !ok {
+			return 0, fmt.Errorf("invalid duration %q", v)
+		}
+		total += time.Duration(n) * unit
+		rest = rest[j:]
+	}
+	return total, nil
+}
@@ -0,0 +1,95 @@
+package routeros
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestLengthEncodingRoundTrip(t *testing.T) {
+	for _, n := range []int{0, 1, 0x7F, 0x80, 0x3FFF, 0x4000, 0x1FFFFF, 0x200000, 0xFFFFFFF, 0x10000000} {
+		server, client := net.Pipe()
+		go func() {
+			server.Write(encodeLength(n))
+			server.Close()
+		}()
+		got, err := newClient(client, 0).readLength()
+		if err != nil {
+			t.Fatalf("readLength(%d): %v", n, err)
+		}
+		if got != n {
+			t.Errorf("Expected length %d, got %d", n, got)
+		}
+		client.Close()
+	}
+}
+
+func TestRun(t *testing.T) {
+	server, conn := net.Pipe()
+	defer conn.Close()
+
+	go func() {
+		fake := newClient(server, 0)
+		cmd, _ := fake.readSentence()
+		if len(cmd) == 0 || cmd[0] != "/interface/bonding/print" {
+			fake.writeSentence([]string{"!fatal", "unexpected command"})
+			return
+		}
+		fake.writeSentence([]string{"!re", "=name=bond1", "=slaves=ether1,ether2"})
+		fake.writeSentence([]string{"!done"})
+	}()
+
+	replies, err := newClient(conn, 0).Run("/interface/bonding/print")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replies) != 1 || replies[0]["name"] != "bond1" {
+		t.Fatalf("Unexpected replies: %+v", replies)
+	}
+	if members := ParseList(replies[0]["slaves"]); len(members) != 2 || members[1] != "ether2" {
+		t.Errorf("Unexpected members: %v", members)
+	}
+}
+
+func TestRunTrap(t *testing.T) {
+	server, conn := net.Pipe()
+	defer conn.Close()
+
+	go func() {
+		fake := newClient(server, 0)
+		fake.readSentence()
+		fake.writeSentence([]string{"!trap", "=message=no such command"})
+		fake.writeSentence([]string{"!done"})
+	}()
+
+	if _, err := newClient(conn, 0).Run("/nope"); err == nil || err.Error() != "no such command" {
+		t.Errorf("Expected trap message as error, got %v", err)
+	} else if !IsNoSuchCommand(err) {
+		t.Errorf("Expected %v to be recognized as a missing menu", err)
+	}
+	if IsNoSuchCommand(errors.New("no such command")) {
+		t.Error("Expected only trap replies to be recognized as a missing menu")
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Duration
+	}{
+		{"1w2d3h4m5s", 9*24*time.Hour + 3*time.Hour + 4*time.Minute + 5*time.Second},
+		{"150ms", 150 * time.Millisecond},
+		{"1d02:03:04", 26*time.Hour + 3*time.Minute + 4*time.Second},
+		{"00:05:10", 5*time.Minute + 10*time.Second},
+	}
+	for _, tt := range tests {
+		got, err := ParseDuration(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseDuration(%q) = %v, %v, want %v", tt.in, got, err, tt.want)
+		}
+	}
+	if _, err := ParseDuration("5x"); err == nil {
+		t.Error("Expected error for unknown unit")
+	}
+}
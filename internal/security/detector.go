@@ -0,0 +1,214 @@
+// Package security flags new, misplaced and flapping MAC addresses in the
+// endpoint inventory.
+package security
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AMathur20/Home_Network/internal/models"
+	"github.com/AMathur20/Home_Network/internal/notify"
+	"github.com/AMathur20/Home_Network/internal/storage"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	defaultFlapCount  = 3
+	defaultFlapWindow = 10 * time.Minute
+)
+
+type knownDevicesFile struct {
+	Devices []models.KnownDevice `yaml:"devices"`
+}
+
+// LoadKnownDevices reads the allowlist. A missing file is an empty allowlist.
+func LoadKnownDevices(path string) ([]models.KnownDevice, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []models.KnownDevice{}, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	var known knownDevicesFile
+	if err := yaml.NewDecoder(file).Decode(&known); err != nil {
+		return nil, err
+	}
+	return known.Devices, nil
+}
+
+type Detector struct {
+	storage    *storage.DuckDBStorage
+	notifier   notify.Notifier
+	known      map[string]models.KnownDevice
+	flapCount  int
+	flapWindow time.Duration
+
+	mu       sync.Mutex
+	loaded   bool
+	adopt    bool                       // Take the next collection as the baseline without new device events
+	baseline map[string]models.Endpoint // Last known location per MAC
+	moves    map[string][]time.Time
+	alerted  map[string]string // mac/kind -> location already reported
+}
+
+// NewDetector creates a detector. notifier may be nil, in which case events
+// are only stored.
+func NewDetector(cfg models.SecurityConfig, known []models.KnownDevice, s *storage.DuckDBStorage, n notify.Notifier) *Detector {
+	d := &Detector{
+		storage:    s,
+		known:      make(map[string]models.KnownDevice),
+		flapCount:  cfg.FlapCount,
+		flapWindow: time.Duration(cfg.FlapWindow) * time.Second,
+		baseline:   make(map[string]models.Endpoint),
+		moves:      make(map[string][]time.Time),
+		alerted:    make(map[string]string),
+	}
+	if cfg.Notify {
+		d.notifier = n
+	}
+	if d.flapCount <= 0 {
+		d.flapCount = defaultFlapCount
+	}
+	if d.flapWindow <= 0 {
+		d.flapWindow = defaultFlapWindow
+	}
+	for _, k := range known {
+		d.known[strings.ToLower(k.MAC)] = k
+	}
+	return d
+}
+
+// Process checks a fresh endpoint collection, stores the resulting events and
+// forwards them to the notifier. It must run before the endpoints are saved so
+// that new MACs are still unknown to storage.
+func (d *Detector) Process(endpoints []models.Endpoint) {
+	d.mu.Lock()
+	if !d.loaded {
+		existing, err := d.storage.SearchEndpoints("", "", 0, 1<<20)
+		if err != nil {
+			d.mu.Unlock()
+			log.Printf("Error loading endpoint baseline: %v", err)
+			return
+		}
+		for _, ep := range existing {
+			d.baseline[ep.MAC] = ep
+		}
+		// On a fresh database every host on the LAN would be new.
+		d.adopt = len(existing) == 0
+		d.loaded = true
+	}
+	events := d.check(endpoints, time.Now())
+	d.mu.Unlock()
+
+	for _, e := range events {
+		log.Printf("Security event %s: %s", e.Kind, e.Message)
+		if err := d.storage.SaveEvent(e); err != nil {
+			log.Printf("Error saving security event: %v", err)
+		}
+		if d.notifier != nil {
+			if err := d.notifier.Notify(e); err != nil {
+				log.Printf("Error sending notification: %v", err)
+			}
+		}
+	}
+}
+
+// check compares endpoints against the baseline and allowlist. The caller must
+// hold d.mu.
+func (d *Detector) check(endpoints []models.Endpoint, now time.Time) []models.Event {
+	var events []models.Event
+
+	for _, ep := range endpoints {
+		known, isKnown := d.known[ep.MAC]
+		prev, seen := d.baseline[ep.MAC]
+		location := ep.DeviceName + "/" + ep.InterfaceName
+
+		event := func(kind, format string, args ...interface{}) {
+			events = append(events, models.Event{
+				Timestamp:     now,
+				DeviceName:    ep.DeviceName,
+				InterfaceName: ep.InterfaceName,
+				Kind:          kind,
+				MAC:           ep.MAC,
+				Message:       fmt.Sprintf(format, args...),
+			})
+		}
+
+		if !seen && !isKnown && !d.adopt {
+			event(models.EventNewDevice, "new device %s on %s vlan %d", describe(ep), location, ep.VLAN)
+		}
+
+		if seen && ep.DeviceName != "" && (prev.DeviceName != ep.DeviceName || prev.InterfaceName != ep.InterfaceName) {
+			moves := append(d.moves[ep.MAC], now)
+			for len(moves) > 0 && now.Sub(moves[0]) > d.flapWindow {
+				moves = moves[1:]
+			}
+			d.moves[ep.MAC] = moves
+			if len(moves) >= d.flapCount {
+				event(models.EventMACFlap, "%s moved %d times in %s, now on %s", describe(ep), len(moves), d.flapWindow, location)
+				delete(d.moves, ep.MAC)
+			}
+		}
+
+		if isKnown {
+			name := known.Name
+			if name == "" {
+				name = ep.MAC
+			}
+			if (known.Device != "" && known.Device != ep.DeviceName) || (known.Interface != "" && known.Interface != ep.InterfaceName) {
+				if d.alert(ep.MAC, models.EventUnexpectedPort, location) {
+					event(models.EventUnexpectedPort, "%s seen on %s, expected %s/%s", name, location, known.Device, known.Interface)
+				}
+			} else {
+				d.clear(ep.MAC, models.EventUnexpectedPort)
+			}
+			if known.VLAN != 0 && ep.VLAN != 0 && known.VLAN != ep.VLAN {
+				if d.alert(ep.MAC, models.EventUnexpectedVLAN, fmt.Sprint(ep.VLAN)) {
+					event(models.EventUnexpectedVLAN, "%s seen on vlan %d, expected vlan %d", name, ep.VLAN, known.VLAN)
+				}
+			} else {
+				d.clear(ep.MAC, models.EventUnexpectedVLAN)
+			}
+		}
+
+		d.baseline[ep.MAC] = ep
+	}
+	if d.adopt && len(endpoints) > 0 {
+		log.Printf("Adopted %d endpoints as the security baseline", len(endpoints))
+		d.adopt = false
+	}
+
+	return events
+}
+
+// alert reports whether value is new for mac/kind, so a misplaced device is
+// reported once per location rather than on every collection.
+func (d *Detector) alert(mac, kind, value string) bool {
+	key := mac + "/" + kind
+	if d.alerted[key] == value {
+		return false
+	}
+	d.alerted[key] = value
+	return true
+}
+
+func (d *Detector) clear(mac, kind string) {
+	delete(d.alerted, mac+"/"+kind)
+}
+
+func describe(ep models.Endpoint) string {
+	parts := []string{ep.MAC}
+	for _, s := range []string{ep.Hostname, ep.IP, ep.Vendor} {
+		if s != "" {
+			parts = append(parts, s)
+		}
+	}
+	return strings.Join(parts, " ")
+}
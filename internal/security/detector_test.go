@@ -0,0 +1,76 @@
+package security
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/AMathur20/Home_Network/internal/models"
+	"github.com/AMathur20/Home_Network/internal/storage"
+)
+
+func TestDetectorCheck(t *testing.T) {
+	known := []models.KnownDevice{
+		{MAC: "00:11:32:AA:BB:CC", Name: "NAS", Device: "switch", Interface: "port7", VLAN: 10},
+	}
+	d := NewDetector(models.SecurityConfig{FlapCount: 2, FlapWindow: 60}, known, nil, nil)
+	now := time.Now()
+
+	events := d.check([]models.Endpoint{
+		{MAC: "00:11:32:aa:bb:cc", DeviceName: "switch", InterfaceName: "port7", VLAN: 10},
+		{MAC: "3a:00:00:00:00:09", DeviceName: "switch", InterfaceName: "port3", VLAN: 10},
+	}, now)
+	if len(events) != 1 || events[0].Kind != models.EventNewDevice || events[0].MAC != "3a:00:00:00:00:09" {
+		t.Fatalf("Expected one new device event, got %+v", events)
+	}
+
+	// The NAS moves to the wrong port and VLAN: reported once.
+	moved := []models.Endpoint{{MAC: "00:11:32:aa:bb:cc", DeviceName: "switch", InterfaceName: "port2", VLAN: 20}}
+	events = d.check(moved, now.Add(time.Second))
+	if len(events) != 2 || events[0].Kind != models.EventUnexpectedPort || events[1].Kind != models.EventUnexpectedVLAN {
+		t.Fatalf("Expected unexpected port and vlan events, got %+v", events)
+	}
+	if events = d.check(moved, now.Add(2*time.Second)); len(events) != 0 {
+		t.Errorf("Expected misplacement to be reported once, got %+v", events)
+	}
+
+	// The phone bounces between ports.
+	d.check([]models.Endpoint{{MAC: "3a:00:00:00:00:09", DeviceName: "switch", InterfaceName: "port4"}}, now.Add(3*time.Second))
+	events = d.check([]models.Endpoint{{MAC: "3a:00:00:00:00:09", DeviceName: "switch", InterfaceName: "port3"}}, now.Add(4*time.Second))
+	if len(events) != 1 || events[0].Kind != models.EventMACFlap {
+		t.Errorf("Expected MAC flap event, got %+v", events)
+	}
+}
+
+func TestDetectorFirstRun(t *testing.T) {
+	s, err := storage.NewDuckDBStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	d := NewDetector(models.SecurityConfig{}, nil, s, nil)
+	events := func() []models.Event {
+		events, err := s.GetEvents("", models.EventNewDevice, "", 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return events
+	}
+
+	// An empty inventory adopts the first collection without alerting.
+	d.Process([]models.Endpoint{
+		{MAC: "3a:00:00:00:00:01", DeviceName: "switch", InterfaceName: "port1"},
+		{MAC: "3a:00:00:00:00:02", DeviceName: "switch", InterfaceName: "port2"},
+	})
+	if e := events(); len(e) != 0 {
+		t.Fatalf("Expected no new device events on first run, got %+v", e)
+	}
+
+	d.Process([]models.Endpoint{
+		{MAC: "3a:00:00:00:00:01", DeviceName: "switch", InterfaceName: "port1"},
+		{MAC: "3a:00:00:00:00:03", DeviceName: "switch", InterfaceName: "port3"},
+	})
+	if e := events(); len(e) != 1 || e[0].MAC != "3a:00:00:00:00:03" {
+		t.Errorf("Expected a new device event for the host seen after the baseline, got %+v", e)
+	}
+}
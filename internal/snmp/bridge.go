@@ -0,0 +1,36 @@
+package snmp
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/AMathur20/Home_Network/internal/models"
+	"github.com/gosnmp/gosnmp"
+)
+
+// BRIDGE-MIB dot1dBasePortIfIndex, indexed by dot1dBasePort
+const oidDot1dBasePortIfIndex = ".1.3.6.1.2.1.17.1.4.1.2"
+
+// BridgePorts maps the bridge port numbers the BRIDGE-MIB and Q-BRIDGE-MIB
+// tables are indexed by to ifIndex.
+func BridgePorts(params *gosnmp.GoSNMP) map[int]int {
+	ports := make(map[int]int)
+	params.BulkWalk(oidDot1dBasePortIfIndex, func(pdu gosnmp.SnmpPDU) error {
+		port := 0
+		fmt.Sscanf(pdu.Name, oidDot1dBasePortIfIndex+".%d", &port)
+		ports[port] = models.PduToInt(pdu.Value)
+		return nil
+	})
+	return ports
+}
+
+// FormatMAC renders a 6-byte PhysAddress as aa:bb:cc:dd:ee:ff. Agents that
+// report the address as text get it lowercased.
+func FormatMAC(val interface{}) string {
+	b, ok := val.([]byte)
+	if !ok || len(b) != 6 {
+		return strings.ToLower(models.PduToString(val))
+	}
+	return net.HardwareAddr(b).String()
+}
@@ -0,0 +1,96 @@
+// Package snmp opens SNMP sessions with a device's configured version and
+// credentials.
+package snmp
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/AMathur20/Home_Network/internal/models"
+	"github.com/gosnmp/gosnmp"
+)
+
+// Params returns the session parameters for host: SNMPv3 if cfg.Version is
+// v3 and v2c otherwise.
+func Params(host string, cfg models.SNMPConfig) (*gosnmp.GoSNMP, error) {
+	port := cfg.Port
+	if port == 0 {
+		port = 161
+	}
+	params := &gosnmp.GoSNMP{
+		Target:    host,
+		Port:      uint16(port),
+		Community: cfg.Community,
+		Version:   gosnmp.Version2c,
+		Timeout:   time.Duration(2) * time.Second,
+		Retries:   3,
+	}
+	if cfg.Version == "v3" {
+		usm, flags, err := USM(cfg.USMUser)
+		if err != nil {
+			return nil, err
+		}
+		params.Version = gosnmp.Version3
+		params.SecurityModel = gosnmp.UserSecurityModel
+		params.MsgFlags = flags
+		params.SecurityParameters = usm
+	}
+	return params, nil
+}
+
+// Dial connects to dev over SNMP. The caller closes params.Conn.
+func Dial(dev models.DeviceConfig) (*gosnmp.GoSNMP, error) {
+	params, err := Params(dev.Host, dev.SNMP)
+	if err != nil {
+		return nil, err
+	}
+	if err := params.Connect(); err != nil {
+		return nil, err
+	}
+	return params, nil
+}
+
+var (
+	authProtocols = map[string]gosnmp.SnmpV3AuthProtocol{
+		"md5": gosnmp.MD5, "sha": gosnmp.SHA, "sha224": gosnmp.SHA224,
+		"sha256": gosnmp.SHA256, "sha384": gosnmp.SHA384, "sha512": gosnmp.SHA512,
+	}
+	privProtocols = map[string]gosnmp.SnmpV3PrivProtocol{
+		"des": gosnmp.DES, "aes": gosnmp.AES, "aes192": gosnmp.AES192, "aes256": gosnmp.AES256,
+	}
+)
+
+// USM returns the security parameters of an SNMPv3 user and the message
+// flags its protocols call for.
+func USM(user models.USMUser) (*gosnmp.UsmSecurityParameters, gosnmp.SnmpV3MsgFlags, error) {
+	usm := &gosnmp.UsmSecurityParameters{
+		UserName:                 user.Username,
+		AuthenticationPassphrase: user.AuthPassphrase,
+		PrivacyPassphrase:        user.PrivPassphrase,
+		AuthenticationProtocol:   gosnmp.NoAuth,
+		PrivacyProtocol:          gosnmp.NoPriv,
+	}
+
+	flags := gosnmp.NoAuthNoPriv
+	if user.AuthProtocol != "" {
+		p, ok := authProtocols[strings.ToLower(user.AuthProtocol)]
+		if !ok {
+			return nil, 0, fmt.Errorf("unknown auth_protocol %q", user.AuthProtocol)
+		}
+		usm.AuthenticationProtocol = p
+		flags = gosnmp.AuthNoPriv
+	}
+	if user.PrivProtocol != "" {
+		p, ok := privProtocols[strings.ToLower(user.PrivProtocol)]
+		if !ok {
+			return nil, 0, fmt.Errorf("unknown priv_protocol %q", user.PrivProtocol)
+		}
+		if flags == gosnmp.NoAuthNoPriv {
+			return nil, 0, fmt.Errorf("priv_protocol requires an auth_protocol")
+		}
+		usm.PrivacyProtocol = p
+		flags = gosnmp.AuthPriv
+	}
+	return usm, flags, nil
+}
@@ -3,6 +3,8 @@ package storage
 import (
 	"database/sql"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/AMathur20/Home_Network/internal/models"
 	_ "github.com/marcboeker/go-duckdb"
@@ -31,6 +33,138 @@ func NewDuckDBStorage(path string) (*DuckDBStorage, error) {
 			status TEXT
 		)`,
 		`CREATE INDEX IF NOT EXISTS idx_metrics_timestamp ON interface_metrics (timestamp)`,
+		`ALTER TABLE interface_metrics ADD COLUMN IF NOT EXISTS parent TEXT`,
+		`ALTER TABLE interface_metrics ADD COLUMN IF NOT EXISTS members_up INTEGER`,
+		`ALTER TABLE interface_metrics ADD COLUMN IF NOT EXISTS members_total INTEGER`,
+		`ALTER TABLE interface_metrics ADD COLUMN IF NOT EXISTS stp_state TEXT`,
+		`CREATE TABLE IF NOT EXISTS events (
+			timestamp TIMESTAMP,
+			device_name TEXT,
+			interface_name TEXT,
+			kind TEXT,
+			message TEXT
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_events_timestamp ON events (timestamp)`,
+		`ALTER TABLE events ADD COLUMN IF NOT EXISTS mac TEXT`,
+		`CREATE TABLE IF NOT EXISTS endpoints (
+			mac TEXT PRIMARY KEY,
+			ip TEXT,
+			hostname TEXT,
+			vendor TEXT,
+			device_name TEXT,
+			interface_name TEXT,
+			vlan INTEGER,
+			first_seen TIMESTAMP,
+			last_seen TIMESTAMP
+		)`,
+		`CREATE TABLE IF NOT EXISTS wireless_clients (
+			timestamp TIMESTAMP,
+			device_name TEXT,
+			interface_name TEXT,
+			mac TEXT,
+			ssid TEXT,
+			signal INTEGER,
+			snr INTEGER,
+			tx_rate DOUBLE,
+			rx_rate DOUBLE,
+			ccq INTEGER,
+			uptime BIGINT
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_wireless_clients_timestamp ON wireless_clients (timestamp)`,
+		`CREATE TABLE IF NOT EXISTS wireless_interfaces (
+			timestamp TIMESTAMP,
+			device_name TEXT,
+			interface_name TEXT,
+			ssid TEXT,
+			frequency INTEGER,
+			clients INTEGER,
+			noise_floor INTEGER,
+			channel_utilization DOUBLE
+		)`,
+		`CREATE TABLE IF NOT EXISTS optical_metrics (
+			timestamp TIMESTAMP,
+			device_name TEXT,
+			interface_name TEXT,
+			temperature DOUBLE,
+			voltage DOUBLE,
+			bias_current DOUBLE,
+			tx_power DOUBLE,
+			rx_power DOUBLE,
+			temp_high DOUBLE,
+			voltage_low DOUBLE,
+			voltage_high DOUBLE,
+			bias_high DOUBLE,
+			tx_power_low DOUBLE,
+			rx_power_low DOUBLE,
+			rx_power_high DOUBLE,
+			alarms TEXT
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_optical_metrics_timestamp ON optical_metrics (timestamp)`,
+		`CREATE TABLE IF NOT EXISTS poe_ports (
+			timestamp TIMESTAMP,
+			device_name TEXT,
+			interface_name TEXT,
+			status TEXT,
+			class INTEGER,
+			power DOUBLE,
+			voltage DOUBLE,
+			current DOUBLE
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_poe_ports_timestamp ON poe_ports (timestamp)`,
+		`CREATE TABLE IF NOT EXISTS poe_budgets (
+			timestamp TIMESTAMP,
+			device_name TEXT,
+			budget DOUBLE,
+			consumption DOUBLE
+		)`,
+		`CREATE TABLE IF NOT EXISTS logs (
+			timestamp TIMESTAMP,
+			device_name TEXT,
+			source TEXT,
+			hostname TEXT,
+			facility INTEGER,
+			severity INTEGER,
+			app_name TEXT,
+			message TEXT
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_logs_timestamp ON logs (timestamp)`,
+		`CREATE TABLE IF NOT EXISTS flows (
+			timestamp TIMESTAMP,
+			device_name TEXT,
+			in_interface TEXT,
+			out_interface TEXT,
+			src_addr TEXT,
+			dst_addr TEXT,
+			protocol INTEGER,
+			port INTEGER,
+			bytes UBIGINT,
+			packets UBIGINT
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_flows_timestamp ON flows (timestamp)`,
+		`CREATE TABLE IF NOT EXISTS probe_results (
+			timestamp TIMESTAMP,
+			name TEXT,
+			device_name TEXT,
+			type TEXT,
+			target TEXT,
+			status TEXT,
+			rtt DOUBLE,
+			jitter DOUBLE,
+			loss DOUBLE,
+			error TEXT
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_probe_results_timestamp ON probe_results (timestamp)`,
+		`CREATE TABLE IF NOT EXISTS topology_events (
+			timestamp TIMESTAMP,
+			kind TEXT,
+			source_device TEXT,
+			source_interface TEXT,
+			target_device TEXT,
+			target_interface TEXT,
+			link_type TEXT,
+			detail TEXT,
+			status TEXT
+		)`,
 	}
 
 	for _, q := range queries {
@@ -44,15 +178,16 @@ func NewDuckDBStorage(path string) (*DuckDBStorage, error) {
 
 func (s *DuckDBStorage) SaveMetric(m models.InterfaceMetric) error {
 	_, err := s.db.Exec(`
-		INSERT INTO interface_metrics (device_name, interface_name, timestamp, in_octets, out_octets, in_speed, out_speed, status)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
-		m.DeviceName, m.InterfaceName, m.Timestamp, m.InOctets, m.OutOctets, m.InSpeed, m.OutSpeed, m.Status)
+		INSERT INTO interface_metrics (device_name, interface_name, timestamp, in_octets, out_octets, in_speed, out_speed, status, parent, members_up, members_total, stp_state)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		m.DeviceName, m.InterfaceName, m.Timestamp, m.InOctets, m.OutOctets, m.InSpeed, m.OutSpeed, m.Status, m.Parent, m.MembersUp, m.MembersTotal, m.STPState)
 	return err
 }
 
 func (s *DuckDBStorage) GetLatestMetrics() ([]models.InterfaceMetric, error) {
 	rows, err := s.db.Query(`
-		SELECT device_name, interface_name, timestamp, in_octets, out_octets, in_speed, out_speed, status
+		SELECT device_name, interface_name, timestamp, in_octets, out_octets, in_speed, out_speed, status,
+			COALESCE(parent, ''), COALESCE(members_up, 0), COALESCE(members_total, 0), COALESCE(stp_state, '')
 		FROM interface_metrics
 		QUALIFY ROW_NUMBER() OVER(PARTITION BY device_name, interface_name ORDER BY timestamp DESC) = 1`)
 	if err != nil {
@@ -63,7 +198,8 @@ func (s *DuckDBStorage) GetLatestMetrics() ([]models.InterfaceMetric, error) {
 	var metrics []models.InterfaceMetric
 	for rows.Next() {
 		var m models.InterfaceMetric
-		err := rows.Scan(&m.DeviceName, &m.InterfaceName, &m.Timestamp, &m.InOctets, &m.OutOctets, &m.InSpeed, &m.OutSpeed, &m.Status)
+		err := rows.Scan(&m.DeviceName, &m.InterfaceName, &m.Timestamp, &m.InOctets, &m.OutOctets, &m.InSpeed, &m.OutSpeed, &m.Status,
+			&m.Parent, &m.MembersUp, &m.MembersTotal, &m.STPState)
 		if err != nil {
 			return nil, err
 		}
@@ -74,7 +210,8 @@ func (s *DuckDBStorage) GetLatestMetrics() ([]models.InterfaceMetric, error) {
 
 func (s *DuckDBStorage) GetMetricHistory(deviceName, interfaceName string, limit int) ([]models.InterfaceMetric, error) {
 	rows, err := s.db.Query(`
-		SELECT device_name, interface_name, timestamp, in_octets, out_octets, in_speed, out_speed, status
+		SELECT device_name, interface_name, timestamp, in_octets, out_octets, in_speed, out_speed, status,
+			COALESCE(parent, ''), COALESCE(members_up, 0), COALESCE(members_total, 0), COALESCE(stp_state, '')
 		FROM interface_metrics
 		WHERE device_name = ? AND interface_name = ?
 		ORDER BY timestamp DESC
@@ -87,15 +224,581 @@ func (s *DuckDBStorage) GetMetricHistory(deviceName, interfaceName string, limit
 	var metrics []models.InterfaceMetric
 	for rows.Next() {
 		var m models.InterfaceMetric
-		err := rows.Scan(&m.DeviceName, &m.InterfaceName, &m.Timestamp, &m.InOctets, &m.OutOctets, &m.InSpeed, &m.OutSpeed, &m.Status)
+		err := rows.Scan(&m.DeviceName, &m.InterfaceName, &m.Timestamp, &m.InOctets, &m.OutOctets, &m.InSpeed, &m.OutSpeed, &m.Status,
+			&m.Parent, &m.MembersUp, &m.MembersTotal, &m.STPState)
+		if err != nil {
+			return nil, err
+		}
+		metrics = append(metrics, m)
+	}
+	return metrics, nil
+}
+
+func (s *DuckDBStorage) SaveTopologyEvent(e models.TopologyEvent) error {
+	_, err := s.db.Exec(`
+		INSERT INTO topology_events (timestamp, kind, source_device, source_interface, target_device, target_interface, link_type, detail, status)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		e.Timestamp, e.Kind, e.SourceDevice, e.SourceInterface, e.TargetDevice, e.TargetInterface, e.LinkType, e.Detail, e.Status)
+	return err
+}
+
+// GetTopologyEvents returns the most recent topology events, optionally
+// filtered by status.
+func (s *DuckDBStorage) GetTopologyEvents(status string, limit int) ([]models.TopologyEvent, error) {
+	rows, err := s.db.Query(`
+		SELECT timestamp, kind, source_device, source_interface, target_device, target_interface, link_type, detail, status
+		FROM topology_events
+		WHERE ? = '' OR status = ?
+		ORDER BY timestamp DESC
+		LIMIT ?`, status, status, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []models.TopologyEvent
+	for rows.Next() {
+		var e models.TopologyEvent
+		err := rows.Scan(&e.Timestamp, &e.Kind, &e.SourceDevice, &e.SourceInterface, &e.TargetDevice, &e.TargetInterface, &e.LinkType, &e.Detail, &e.Status)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, nil
+}
+
+func (s *DuckDBStorage) UpdateTopologyEventStatus(from, to string) error {
+	_, err := s.db.Exec(`UPDATE topology_events SET status = ? WHERE status = ?`, to, from)
+	return err
+}
+
+// SetTopologyEventStatus moves the events for the same change as e, and with
+// its status, to status to.
+func (s *DuckDBStorage) SetTopologyEventStatus(e models.TopologyEvent, to string) error {
+	_, err := s.db.Exec(`
+		UPDATE topology_events SET status = ?
+		WHERE status = ? AND kind = ? AND source_device = ? AND source_interface = ?
+			AND target_device = ? AND target_interface = ? AND link_type = ? AND detail = ?`,
+		to, e.Status, e.Kind, e.SourceDevice, e.SourceInterface, e.TargetDevice, e.TargetInterface, e.LinkType, e.Detail)
+	return err
+}
+
+func (s *DuckDBStorage) SaveEvent(e models.Event) error {
+	_, err := s.db.Exec(`
+		INSERT INTO events (timestamp, device_name, interface_name, kind, mac, message)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		e.Timestamp, e.DeviceName, e.InterfaceName, e.Kind, e.MAC, e.Message)
+	return err
+}
+
+// GetEvents returns the most recent events, optionally filtered by device,
+// kind and MAC.
+func (s *DuckDBStorage) GetEvents(device, kind, mac string, limit int) ([]models.Event, error) {
+	rows, err := s.db.Query(`
+		SELECT timestamp, device_name, interface_name, kind, COALESCE(mac, ''), message
+		FROM events
+		WHERE (? = '' OR device_name = ?) AND (? = '' OR kind = ?) AND (? = '' OR mac = ?)
+		ORDER BY timestamp DESC
+		LIMIT ?`, device, device, kind, kind, mac, mac, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []models.Event
+	for rows.Next() {
+		var e models.Event
+		if err := rows.Scan(&e.Timestamp, &e.DeviceName, &e.InterfaceName, &e.Kind, &e.MAC, &e.Message); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, nil
+}
+
+// GetEventsSince returns a device's events from since onwards, newest first.
+func (s *DuckDBStorage) GetEventsSince(device string, since time.Time, limit int) ([]models.Event, error) {
+	rows, err := s.db.Query(`
+		SELECT timestamp, device_name, interface_name, kind, COALESCE(mac, ''), message
+		FROM events
+		WHERE device_name = ? AND timestamp >= ?
+		ORDER BY timestamp DESC
+		LIMIT ?`, device, since, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []models.Event
+	for rows.Next() {
+		var e models.Event
+		if err := rows.Scan(&e.Timestamp, &e.DeviceName, &e.InterfaceName, &e.Kind, &e.MAC, &e.Message); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, nil
+}
+
+// SaveEndpoint inserts or refreshes an endpoint, keeping its first-seen time
+// and any previously known IP, hostname or VLAN that the new sighting lacks.
+func (s *DuckDBStorage) SaveEndpoint(e models.Endpoint) error {
+	_, err := s.db.Exec(`
+		INSERT INTO endpoints (mac, ip, hostname, vendor, device_name, interface_name, vlan, first_seen, last_seen)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (mac) DO UPDATE SET
+			ip = COALESCE(NULLIF(excluded.ip, ''), endpoints.ip),
+			hostname = COALESCE(NULLIF(excluded.hostname, ''), endpoints.hostname),
+			vendor = COALESCE(NULLIF(excluded.vendor, ''), endpoints.vendor),
+			device_name = COALESCE(NULLIF(excluded.device_name, ''), endpoints.device_name),
+			interface_name = COALESCE(NULLIF(excluded.interface_name, ''), endpoints.interface_name),
+			vlan = CASE WHEN excluded.vlan > 0 THEN excluded.vlan ELSE endpoints.vlan END,
+			last_seen = excluded.last_seen`,
+		e.MAC, e.IP, e.Hostname, e.Vendor, e.DeviceName, e.InterfaceName, e.VLAN, e.FirstSeen, e.LastSeen)
+	return err
+}
+
+// SearchEndpoints matches q against MAC, IP, hostname and vendor, optionally
+// restricted to a device and VLAN (0 for any).
+func (s *DuckDBStorage) SearchEndpoints(q, device string, vlan, limit int) ([]models.Endpoint, error) {
+	pattern := "%" + q + "%"
+	rows, err := s.db.Query(`
+		SELECT mac, ip, hostname, vendor, device_name, interface_name, vlan, first_seen, last_seen
+		FROM endpoints
+		WHERE (mac ILIKE ? OR ip ILIKE ? OR hostname ILIKE ? OR vendor ILIKE ?)
+			AND (? = '' OR device_name = ?)
+			AND (? = 0 OR vlan = ?)
+		ORDER BY last_seen DESC
+		LIMIT ?`, pattern, pattern, pattern, pattern, device, device, vlan, vlan, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var endpoints []models.Endpoint
+	for rows.Next() {
+		var e models.Endpoint
+		if err := rows.Scan(&e.MAC, &e.IP, &e.Hostname, &e.Vendor, &e.DeviceName, &e.InterfaceName, &e.VLAN, &e.FirstSeen, &e.LastSeen); err != nil {
+			return nil, err
+		}
+		endpoints = append(endpoints, e)
+	}
+	return endpoints, nil
+}
+
+func (s *DuckDBStorage) SaveWirelessClient(c models.WirelessClient) error {
+	_, err := s.db.Exec(`
+		INSERT INTO wireless_clients (timestamp, device_name, interface_name, mac, ssid, signal, snr, tx_rate, rx_rate, ccq, uptime)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		c.Timestamp, c.DeviceName, c.InterfaceName, c.MAC, c.SSID, c.Signal, c.SNR, c.TxRate, c.RxRate, c.CCQ, c.Uptime)
+	return err
+}
+
+// GetWirelessClients returns the latest sample of every client seen since
+// since, optionally restricted to one AP.
+func (s *DuckDBStorage) GetWirelessClients(device string, since time.Time) ([]models.WirelessClient, error) {
+	return s.queryWirelessClients(`
+		SELECT timestamp, device_name, interface_name, mac, ssid, signal, snr, tx_rate, rx_rate, ccq, uptime
+		FROM wireless_clients
+		WHERE timestamp >= ? AND (? = '' OR device_name = ?)
+		QUALIFY ROW_NUMBER() OVER(PARTITION BY mac ORDER BY timestamp DESC) = 1
+		ORDER BY device_name, interface_name, signal DESC`, since, device, device)
+}
+
+// GetWirelessClientHistory returns the samples of one client, newest first,
+// across every AP it associated with.
+func (s *DuckDBStorage) GetWirelessClientHistory(mac string, limit int) ([]models.WirelessClient, error) {
+	return s.queryWirelessClients(`
+		SELECT timestamp, device_name, interface_name, mac, ssid, signal, snr, tx_rate, rx_rate, ccq, uptime
+		FROM wireless_clients
+		WHERE mac = ?
+		ORDER BY timestamp DESC
+		LIMIT ?`, mac, limit)
+}
+
+func (s *DuckDBStorage) queryWirelessClients(query string, args ...interface{}) ([]models.WirelessClient, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var clients []models.WirelessClient
+	for rows.Next() {
+		var c models.WirelessClient
+		err := rows.Scan(&c.Timestamp, &c.DeviceName, &c.InterfaceName, &c.MAC, &c.SSID, &c.Signal, &c.SNR, &c.TxRate, &c.RxRate, &c.CCQ, &c.Uptime)
+		if err != nil {
+			return nil, err
+		}
+		clients = append(clients, c)
+	}
+	return clients, nil
+}
+
+func (s *DuckDBStorage) SaveWirelessInterface(w models.WirelessInterface) error {
+	_, err := s.db.Exec(`
+		INSERT INTO wireless_interfaces (timestamp, device_name, interface_name, ssid, frequency, clients, noise_floor, channel_utilization)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		w.Timestamp, w.DeviceName, w.InterfaceName, w.SSID, w.Frequency, w.Clients, w.NoiseFloor, w.ChannelUtilization)
+	return err
+}
+
+// GetWirelessInterfaces returns the latest sample of every AP radio.
+func (s *DuckDBStorage) GetWirelessInterfaces() ([]models.WirelessInterface, error) {
+	rows, err := s.db.Query(`
+		SELECT timestamp, device_name, interface_name, ssid, frequency, clients, noise_floor, channel_utilization
+		FROM wireless_interfaces
+		QUALIFY ROW_NUMBER() OVER(PARTITION BY device_name, interface_name ORDER BY timestamp DESC) = 1
+		ORDER BY device_name, interface_name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ifaces []models.WirelessInterface
+	for rows.Next() {
+		var w models.WirelessInterface
+		err := rows.Scan(&w.Timestamp, &w.DeviceName, &w.InterfaceName, &w.SSID, &w.Frequency, &w.Clients, &w.NoiseFloor, &w.ChannelUtilization)
+		if err != nil {
+			return nil, err
+		}
+		ifaces = append(ifaces, w)
+	}
+	return ifaces, nil
+}
+
+func (s *DuckDBStorage) SaveOpticalMetric(m models.OpticalMetric) error {
+	t := m.Thresholds
+	_, err := s.db.Exec(`
+		INSERT INTO optical_metrics (timestamp, device_name, interface_name, temperature, voltage, bias_current, tx_power, rx_power,
+			temp_high, voltage_low, voltage_high, bias_high, tx_power_low, rx_power_low, rx_power_high, alarms)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		m.Timestamp, m.DeviceName, m.InterfaceName, m.Temperature, m.Voltage, m.BiasCurrent, m.TxPower, m.RxPower,
+		t.TempHigh, t.VoltageLow, t.VoltageHigh, t.BiasHigh, t.TxPowerLow, t.RxPowerLow, t.RxPowerHigh, strings.Join(m.Alarms, ","))
+	return err
+}
+
+const opticalColumns = `timestamp, device_name, interface_name, temperature, voltage, bias_current, tx_power, rx_power,
+	temp_high, voltage_low, voltage_high, bias_high, tx_power_low, rx_power_low, rx_power_high, alarms`
+
+// GetLatestOpticalMetrics returns the latest SFP diagnostics of every port.
+func (s *DuckDBStorage) GetLatestOpticalMetrics() ([]models.OpticalMetric, error) {
+	return s.queryOpticalMetrics(`
+		SELECT ` + opticalColumns + `
+		FROM optical_metrics
+		QUALIFY ROW_NUMBER() OVER(PARTITION BY device_name, interface_name ORDER BY timestamp DESC) = 1
+		ORDER BY device_name, interface_name`)
+}
+
+func (s *DuckDBStorage) GetOpticalHistory(deviceName, interfaceName string, limit int) ([]models.OpticalMetric, error) {
+	return s.queryOpticalMetrics(`
+		SELECT `+opticalColumns+`
+		FROM optical_metrics
+		WHERE device_name = ? AND interface_name = ?
+		ORDER BY timestamp DESC
+		LIMIT ?`, deviceName, interfaceName, limit)
+}
+
+func (s *DuckDBStorage) queryOpticalMetrics(query string, args ...interface{}) ([]models.OpticalMetric, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var metrics []models.OpticalMetric
+	for rows.Next() {
+		var m models.OpticalMetric
+		var alarms string
+		t := &m.Thresholds
+		err := rows.Scan(&m.Timestamp, &m.DeviceName, &m.InterfaceName, &m.Temperature, &m.Voltage, &m.BiasCurrent, &m.TxPower, &m.RxPower,
+			&t.TempHigh, &t.VoltageLow, &t.VoltageHigh, &t.BiasHigh, &t.TxPowerLow, &t.RxPowerLow, &t.RxPowerHigh, &alarms)
 		if err != nil {
 			return nil, err
 		}
+		if alarms != "" {
+			m.Alarms = strings.Split(alarms, ",")
+		}
 		metrics = append(metrics, m)
 	}
 	return metrics, nil
 }
 
+func (s *DuckDBStorage) SavePoEPort(p models.PoEPort) error {
+	_, err := s.db.Exec(`
+		INSERT INTO poe_ports (timestamp, device_name, interface_name, status, class, power, voltage, current)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		p.Timestamp, p.DeviceName, p.InterfaceName, p.Status, p.Class, p.Power, p.Voltage, p.Current)
+	return err
+}
+
+// GetLatestPoEPorts returns the latest sample of every PSE port.
+func (s *DuckDBStorage) GetLatestPoEPorts() ([]models.PoEPort, error) {
+	return s.queryPoEPorts(`
+		SELECT timestamp, device_name, interface_name, status, class, power, voltage, current
+		FROM poe_ports
+		QUALIFY ROW_NUMBER() OVER(PARTITION BY device_name, interface_name ORDER BY timestamp DESC) = 1
+		ORDER BY device_name, interface_name`)
+}
+
+func (s *DuckDBStorage) GetPoEHistory(deviceName, interfaceName string, limit int) ([]models.PoEPort, error) {
+	return s.queryPoEPorts(`
+		SELECT timestamp, device_name, interface_name, status, class, power, voltage, current
+		FROM poe_ports
+		WHERE device_name = ? AND interface_name = ?
+		ORDER BY timestamp DESC
+		LIMIT ?`, deviceName, interfaceName, limit)
+}
+
+func (s *DuckDBStorage) queryPoEPorts(query string, args ...interface{}) ([]models.PoEPort, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ports []models.PoEPort
+	for rows.Next() {
+		var p models.PoEPort
+		if err := rows.Scan(&p.Timestamp, &p.DeviceName, &p.InterfaceName, &p.Status, &p.Class, &p.Power, &p.Voltage, &p.Current); err != nil {
+			return nil, err
+		}
+		ports = append(ports, p)
+	}
+	return ports, nil
+}
+
+func (s *DuckDBStorage) SavePoEBudget(b models.PoEBudget) error {
+	_, err := s.db.Exec(`
+		INSERT INTO poe_budgets (timestamp, device_name, budget, consumption)
+		VALUES (?, ?, ?, ?)`,
+		b.Timestamp, b.DeviceName, b.Budget, b.Consumption)
+	return err
+}
+
+// GetLatestPoEBudgets returns the latest PSE budget of every device.
+func (s *DuckDBStorage) GetLatestPoEBudgets() ([]models.PoEBudget, error) {
+	rows, err := s.db.Query(`
+		SELECT timestamp, device_name, budget, consumption
+		FROM poe_budgets
+		QUALIFY ROW_NUMBER() OVER(PARTITION BY device_name ORDER BY timestamp DESC) = 1
+		ORDER BY device_name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var budgets []models.PoEBudget
+	for rows.Next() {
+		var b models.PoEBudget
+		if err := rows.Scan(&b.Timestamp, &b.DeviceName, &b.Budget, &b.Consumption); err != nil {
+			return nil, err
+		}
+		budgets = append(budgets, b)
+	}
+	return budgets, nil
+}
+
+func (s *DuckDBStorage) SaveLog(m models.LogMessage) error {
+	_, err := s.db.Exec(`
+		INSERT INTO logs (timestamp, device_name, source, hostname, facility, severity, app_name, message)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		m.Timestamp, m.DeviceName, m.Source, m.Hostname, m.Facility, m.Severity, m.AppName, m.Message)
+	return err
+}
+
+// GetLogs returns syslog messages from since onwards, newest first,
+// optionally filtered by device and a substring of the app name or message.
+func (s *DuckDBStorage) GetLogs(device, q string, since time.Time, limit int) ([]models.LogMessage, error) {
+	pattern := "%" + q + "%"
+	rows, err := s.db.Query(`
+		SELECT timestamp, device_name, source, hostname, facility, severity, app_name, message
+		FROM logs
+		WHERE timestamp >= ?
+			AND (? = '' OR device_name = ?)
+			AND (message ILIKE ? OR app_name ILIKE ?)
+		ORDER BY timestamp DESC
+		LIMIT ?`, since, device, device, pattern, pattern, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var logs []models.LogMessage
+	for rows.Next() {
+		var m models.LogMessage
+		if err := rows.Scan(&m.Timestamp, &m.DeviceName, &m.Source, &m.Hostname, &m.Facility, &m.Severity, &m.AppName, &m.Message); err != nil {
+			return nil, err
+		}
+		logs = append(logs, m)
+	}
+	return logs, nil
+}
+
+// DeleteLogsBefore removes syslog messages older than t and returns how many
+// were deleted.
+func (s *DuckDBStorage) DeleteLogsBefore(t time.Time) (int64, error) {
+	res, err := s.db.Exec(`DELETE FROM logs WHERE timestamp < ?`, t)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// SaveFlowSummaries stores one aggregation interval in a single transaction.
+func (s *DuckDBStorage) SaveFlowSummaries(summaries []models.FlowSummary) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare(`
+		INSERT INTO flows (timestamp, device_name, in_interface, out_interface, src_addr, dst_addr, protocol, port, bytes, packets)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+	for _, f := range summaries {
+		if _, err := stmt.Exec(f.Timestamp, f.DeviceName, f.InInterface, f.OutInterface, f.SrcAddr, f.DstAddr, f.Protocol, f.Port, f.Bytes, f.Packets); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetTopFlows returns the top hosts, protocols and service ports by bytes
+// between from and to, optionally restricted to a device and an interface the
+// traffic entered or left by.
+func (s *DuckDBStorage) GetTopFlows(device, iface string, from, to time.Time, limit int) (*models.FlowTop, error) {
+	const where = `timestamp >= ? AND timestamp < ?
+		AND (? = '' OR device_name = ?)
+		AND (? = '' OR in_interface = ? OR out_interface = ?)`
+	args := []interface{}{from, to, device, device, iface, iface, iface}
+	top := &models.FlowTop{
+		Talkers:   []models.FlowTalker{},
+		Protocols: []models.FlowProtocol{},
+		Ports:     []models.FlowPort{},
+	}
+
+	rows, err := s.db.Query(`
+		SELECT addr, CAST(SUM(bytes) AS UBIGINT), CAST(SUM(packets) AS UBIGINT)
+		FROM (
+			SELECT src_addr AS addr, bytes, packets FROM flows WHERE `+where+`
+			UNION ALL
+			SELECT dst_addr AS addr, bytes, packets FROM flows WHERE `+where+`
+		)
+		GROUP BY addr
+		ORDER BY 2 DESC
+		LIMIT ?`, append(append(append([]interface{}{}, args...), args...), limit)...)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var t models.FlowTalker
+		if err := rows.Scan(&t.Address, &t.Bytes, &t.Packets); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		top.Talkers = append(top.Talkers, t)
+	}
+	rows.Close()
+
+	rows, err = s.db.Query(`
+		SELECT protocol, CAST(SUM(bytes) AS UBIGINT), CAST(SUM(packets) AS UBIGINT)
+		FROM flows WHERE `+where+`
+		GROUP BY protocol
+		ORDER BY 2 DESC
+		LIMIT ?`, append(args, limit)...)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var p models.FlowProtocol
+		if err := rows.Scan(&p.Protocol, &p.Bytes, &p.Packets); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		top.Protocols = append(top.Protocols, p)
+	}
+	rows.Close()
+
+	rows, err = s.db.Query(`
+		SELECT protocol, port, CAST(SUM(bytes) AS UBIGINT), CAST(SUM(packets) AS UBIGINT)
+		FROM flows WHERE `+where+` AND port > 0
+		GROUP BY protocol, port
+		ORDER BY 3 DESC
+		LIMIT ?`, append(args, limit)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var p models.FlowPort
+		if err := rows.Scan(&p.Protocol, &p.Port, &p.Bytes, &p.Packets); err != nil {
+			return nil, err
+		}
+		top.Ports = append(top.Ports, p)
+	}
+	return top, nil
+}
+
+// DeleteFlowsBefore removes flow summaries older than t and returns how many
+// were deleted.
+func (s *DuckDBStorage) DeleteFlowsBefore(t time.Time) (int64, error) {
+	res, err := s.db.Exec(`DELETE FROM flows WHERE timestamp < ?`, t)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (s *DuckDBStorage) SaveProbeResult(r models.ProbeResult) error {
+	_, err := s.db.Exec(`
+		INSERT INTO probe_results (timestamp, name, device_name, type, target, status, rtt, jitter, loss, error)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		r.Timestamp, r.Name, r.DeviceName, r.Type, r.Target, r.Status, r.RTT, r.Jitter, r.Loss, r.Error)
+	return err
+}
+
+// GetLatestProbeResults returns the latest result of every probe.
+func (s *DuckDBStorage) GetLatestProbeResults() ([]models.ProbeResult, error) {
+	return s.queryProbeResults(`
+		SELECT timestamp, name, device_name, type, target, status, rtt, jitter, loss, error
+		FROM probe_results
+		QUALIFY ROW_NUMBER() OVER(PARTITION BY name ORDER BY timestamp DESC) = 1
+		ORDER BY name`)
+}
+
+func (s *DuckDBStorage) GetProbeHistory(name string, limit int) ([]models.ProbeResult, error) {
+	return s.queryProbeResults(`
+		SELECT timestamp, name, device_name, type, target, status, rtt, jitter, loss, error
+		FROM probe_results
+		WHERE name = ?
+		ORDER BY timestamp DESC
+		LIMIT ?`, name, limit)
+}
+
+func (s *DuckDBStorage) queryProbeResults(query string, args ...interface{}) ([]models.ProbeResult, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []models.ProbeResult
+	for rows.Next() {
+		var r models.ProbeResult
+		if err := rows.Scan(&r.Timestamp, &r.Name, &r.DeviceName, &r.Type, &r.Target, &r.Status, &r.RTT, &r.Jitter, &r.Loss, &r.Error); err != nil {
+			return nil, err
+		}
+		results = append(results, r)
+	}
+	return results, nil
+}
+
 func (s *DuckDBStorage) Close() error {
 	return s.db.Close()
 }
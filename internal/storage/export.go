@@ -0,0 +1,113 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Export formats
+const (
+	FormatParquet = "parquet"
+	FormatCSV     = "csv"
+)
+
+// Tables returns the names of the tables in the database.
+func (s *DuckDBStorage) Tables() ([]string, error) {
+	rows, err := s.db.Query(`
+		SELECT table_name FROM information_schema.tables
+		WHERE table_schema = 'main' AND table_type = 'BASE TABLE'
+		ORDER BY table_name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		tables = append(tables, name)
+	}
+	return tables, rows.Err()
+}
+
+// Export writes every table to dir as <table>.parquet or <table>.csv and
+// returns the number of rows written per table.
+func (s *DuckDBStorage) Export(dir, format string) (map[string]int64, error) {
+	options := map[string]string{FormatParquet: "FORMAT PARQUET", FormatCSV: "FORMAT CSV, HEADER"}[format]
+	if options == "" {
+		return nil, fmt.Errorf("unknown export format %q (expected parquet or csv)", format)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	tables, err := s.Tables()
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64, len(tables))
+	for _, table := range tables {
+		path := filepath.Join(dir, table+"."+format)
+		res, err := s.db.Exec(fmt.Sprintf(`COPY %s TO %s (%s)`, quoteIdent(table), quoteString(path), options))
+		if err != nil {
+			return nil, fmt.Errorf("exporting %s: %v", table, err)
+		}
+		counts[table], _ = res.RowsAffected()
+	}
+	return counts, nil
+}
+
+// Import appends the rows of tables exported by Export from dir, in a single
+// transaction. Columns are matched by name, so an export from an older schema
+// can be imported into a newer one. Tables without a file are skipped.
+func (s *DuckDBStorage) Import(dir string) (map[string]int64, error) {
+	tables, err := s.Tables()
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int64)
+	for _, table := range tables {
+		var source string
+		if path := filepath.Join(dir, table+"."+FormatParquet); exists(path) {
+			source = fmt.Sprintf(`read_parquet(%s)`, quoteString(path))
+		} else if path := filepath.Join(dir, table+"."+FormatCSV); exists(path) {
+			source = fmt.Sprintf(`read_csv(%s, header = true, allow_quoted_nulls = false)`, quoteString(path))
+		} else {
+			continue
+		}
+		res, err := tx.Exec(fmt.Sprintf(`INSERT INTO %s BY NAME SELECT * FROM %s`, quoteIdent(table), source))
+		if err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("importing %s: %v", table, err)
+		}
+		counts[table], _ = res.RowsAffected()
+	}
+	if len(counts) == 0 {
+		tx.Rollback()
+		return nil, fmt.Errorf("no exported tables found in %s", dir)
+	}
+	return counts, tx.Commit()
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func quoteString(s string) string {
+	return `'` + strings.ReplaceAll(s, `'`, `''`) + `'`
+}
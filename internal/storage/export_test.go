@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/AMathur20/Home_Network/internal/models"
+)
+
+func TestExportImport(t *testing.T) {
+	dir := t.TempDir()
+	src, err := NewDuckDBStorage(filepath.Join(dir, "src.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	if err := src.SaveMetric(models.InterfaceMetric{DeviceName: "core", InterfaceName: "ether1", Timestamp: now, InOctets: 1000, InSpeed: 800, Status: "up"}); err != nil {
+		t.Fatal(err)
+	}
+	// An empty hostname must come back empty, not NULL.
+	if err := src.SaveEndpoint(models.Endpoint{MAC: "aa:bb:cc:dd:ee:ff", IP: "192.168.1.10", DeviceName: "core", InterfaceName: "ether2", VLAN: 20, FirstSeen: now, LastSeen: now}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, format := range []string{FormatParquet, FormatCSV} {
+		t.Run(format, func(t *testing.T) {
+			out := filepath.Join(dir, format)
+			exported, err := src.Export(out, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if exported["interface_metrics"] != 1 || exported["endpoints"] != 1 {
+				t.Errorf("Unexpected export counts %v", exported)
+			}
+
+			dst, err := NewDuckDBStorage(filepath.Join(dir, format+".db"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer dst.Close()
+
+			imported, err := dst.Import(out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if imported["interface_metrics"] != 1 || imported["endpoints"] != 1 {
+				t.Errorf("Unexpected import counts %v", imported)
+			}
+
+			metrics, err := dst.GetLatestMetrics()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(metrics) != 1 || metrics[0].InOctets != 1000 || metrics[0].InSpeed != 800 || !metrics[0].Timestamp.Equal(now) {
+				t.Errorf("Unexpected metrics after import: %+v", metrics)
+			}
+			endpoints, err := dst.SearchEndpoints("", "", 0, 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(endpoints) != 1 || endpoints[0].IP != "192.168.1.10" || endpoints[0].Hostname != "" || endpoints[0].VLAN != 20 {
+				t.Errorf("Unexpected endpoints after import: %+v", endpoints)
+			}
+
+			if _, err := dst.Import(out); err == nil {
+				t.Error("Expected a second import to fail on the endpoints primary key")
+			}
+		})
+	}
+
+	// A table that fails to import rolls back the tables before it.
+	broken := filepath.Join(dir, "broken")
+	if err := os.MkdirAll(broken, 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"endpoints.csv":         "mac,ip,vlan\n11:22:33:44:55:66,192.168.1.11,1\n",
+		"interface_metrics.csv": "device_name,timestamp\ncore,not a time\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(broken, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := src.Import(broken); err == nil {
+		t.Fatal("Expected an invalid timestamp to fail the import")
+	}
+	endpoints, err := src.SearchEndpoints("", "", 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(endpoints) != 1 {
+		t.Errorf("Expected the failed import to be rolled back, got %+v", endpoints)
+	}
+}
@@ -0,0 +1,144 @@
+// Package syslog receives RFC 3164 and RFC 5424 messages from network
+// devices and stores them alongside the polled metrics and events.
+package syslog
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AMathur20/Home_Network/internal/models"
+)
+
+var severityNames = []string{"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug"}
+
+// SeverityName returns the keyword for a syslog severity, e.g. "warning".
+func SeverityName(severity int) string {
+	if severity < 0 || severity >= len(severityNames) {
+		return "unknown"
+	}
+	return severityNames[severity]
+}
+
+// Parse decodes a single syslog message. RFC 5424 is recognised by its
+// version field; anything else is treated as RFC 3164, which in practice
+// covers a lot of variants: RouterOS, for instance, omits the timestamp and
+// hostname unless bsd-syslog is enabled. The returned timestamp is the one in
+// the message, zero if it has none.
+func Parse(data []byte, now time.Time) (models.LogMessage, error) {
+	line := strings.TrimRight(string(data), "\r\n\x00")
+	msg := models.LogMessage{Facility: 1, Severity: 5} // user.notice, RFC 3164 4.3.3
+
+	if strings.HasPrefix(line, "<") {
+		end := strings.IndexByte(line, '>')
+		if end < 2 || end > 4 {
+			return msg, fmt.Errorf("invalid PRI in %q", truncate(line))
+		}
+		pri, err := strconv.Atoi(line[1:end])
+		if err != nil || pri > 191 {
+			return msg, fmt.Errorf("invalid PRI in %q", truncate(line))
+		}
+		msg.Facility, msg.Severity = pri/8, pri%8
+		line = line[end+1:]
+	}
+
+	if strings.HasPrefix(line, "1 ") {
+		return parse5424(msg, line[2:])
+	}
+	return parse3164(msg, line, now), nil
+}
+
+// parse5424 handles "TIMESTAMP HOSTNAME APP-NAME PROCID MSGID SD MSG".
+func parse5424(msg models.LogMessage, line string) (models.LogMessage, error) {
+	fields := strings.SplitN(line, " ", 6)
+	if len(fields) < 6 {
+		return msg, fmt.Errorf("truncated RFC 5424 header in %q", truncate(line))
+	}
+	if fields[0] != "-" {
+		ts, err := time.Parse(time.RFC3339Nano, fields[0])
+		if err != nil {
+			return msg, fmt.Errorf("invalid RFC 5424 timestamp %q", fields[0])
+		}
+		msg.Timestamp = ts
+	}
+	msg.Hostname = nilValue(fields[1])
+	msg.AppName = nilValue(fields[2])
+
+	rest := fields[5]
+	if strings.HasPrefix(rest, "-") {
+		rest = rest[1:]
+	} else {
+		// Skip structured data: one or more [id param="value"] elements,
+		// where values may contain escaped \] and \".
+		for strings.HasPrefix(rest, "[") {
+			i := 1
+			for ; i < len(rest) && rest[i] != ']'; i++ {
+				if rest[i] == '\\' {
+					i++
+				}
+			}
+			if i >= len(rest) {
+				return msg, fmt.Errorf("unterminated structured data in %q", truncate(line))
+			}
+			rest = rest[i+1:]
+		}
+	}
+	msg.Message = strings.TrimPrefix(strings.TrimPrefix(rest, " "), "\ufeff")
+	return msg, nil
+}
+
+// parse3164 handles "Mmm dd hh:mm:ss HOSTNAME TAG[PID]: MSG", tolerating a
+// missing timestamp, hostname or tag.
+func parse3164(msg models.LogMessage, line string, now time.Time) models.LogMessage {
+	if len(line) >= 16 && line[15] == ' ' {
+		if ts, err := time.ParseInLocation(time.Stamp, line[:15], now.Location()); err == nil {
+			// No year in RFC 3164; a date ahead of now is from last year.
+			ts = ts.AddDate(now.Year(), 0, 0)
+			if ts.After(now.Add(24 * time.Hour)) {
+				ts = ts.AddDate(-1, 0, 0)
+			}
+			msg.Timestamp = ts
+			line = line[16:]
+			if host, rest, ok := strings.Cut(line, " "); ok && !isTag(host) {
+				msg.Hostname, line = host, rest
+			}
+		}
+	}
+
+	if tag, rest, ok := strings.Cut(line, ": "); ok && isTag(tag+":") {
+		if i := strings.IndexByte(tag, '['); i > 0 {
+			tag = tag[:i]
+		}
+		msg.AppName, line = tag, rest
+	}
+	msg.Message = line
+	return msg
+}
+
+// isTag reports whether s looks like "sshd:" or "sshd[123]:".
+func isTag(s string) bool {
+	if !strings.HasSuffix(s, ":") || len(s) < 2 || len(s) > 49 {
+		return false
+	}
+	for _, r := range strings.TrimSuffix(s, ":") {
+		if r == ' ' {
+			return false
+		}
+	}
+	return true
+}
+
+func nilValue(s string) string {
+	if s == "-" {
+		return ""
+	}
+	return s
+}
+
+func truncate(s string) string {
+	if len(s) > 64 {
+		return s[:64] + "..."
+	}
+	return s
+}
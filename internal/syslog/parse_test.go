@@ -0,0 +1,68 @@
+package syslog
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	now := time.Date(2024, time.January, 2, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name, line          string
+		severity, facility  int
+		hostname, app, text string
+		timestamp           time.Time
+	}{
+		{
+			"RFC 3164", "<30>Jan  2 09:59:58 switch1 sshd[812]: Accepted publickey for admin",
+			6, 3, "switch1", "sshd", "Accepted publickey for admin",
+			time.Date(2024, time.January, 2, 9, 59, 58, 0, time.UTC),
+		},
+		{
+			"RFC 3164 from last year", "<28>Dec 31 23:00:00 ap1 hostapd: deauthenticated",
+			4, 3, "ap1", "hostapd", "deauthenticated",
+			time.Date(2023, time.December, 31, 23, 0, 0, 0, time.UTC),
+		},
+		{
+			"RouterOS without bsd-syslog", "<30>interface,info ether5 link down",
+			6, 3, "", "", "interface,info ether5 link down", time.Time{},
+		},
+		{
+			"RFC 5424", `<165>1 2024-01-02T09:59:59.5Z core-router netd 42 LINK [ex@32473 iut="3" eventSource="If\]ace"] ether5 link up`,
+			5, 20, "core-router", "netd", "ether5 link up",
+			time.Date(2024, time.January, 2, 9, 59, 59, 500000000, time.UTC),
+		},
+		{
+			"RFC 5424 without structured data", "<14>1 - - app - - - hello\n",
+			6, 1, "", "app", "hello", time.Time{},
+		},
+	}
+	for _, tt := range tests {
+		msg, err := Parse([]byte(tt.line), now)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if msg.Severity != tt.severity || msg.Facility != tt.facility || msg.Hostname != tt.hostname ||
+			msg.AppName != tt.app || msg.Message != tt.text || !msg.Timestamp.Equal(tt.timestamp) {
+			t.Errorf("%s: unexpected message %+v", tt.name, msg)
+		}
+	}
+
+	if _, err := Parse([]byte("<999>garbage"), now); err == nil {
+		t.Error("Expected an error for an invalid PRI")
+	}
+}
+
+func TestReadFrame(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("11 <13>1 - - -\n<13>plain line\n<13>last"))
+	for _, want := range []string{"<13>1 - - -", "<13>plain line\n", "<13>last"} {
+		frame, err := readFrame(r)
+		if err != nil || string(frame) != want {
+			t.Errorf("readFrame() = %q, %v, want %q", frame, err, want)
+		}
+	}
+}
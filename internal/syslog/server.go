@@ -0,0 +1,176 @@
+package syslog
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/AMathur20/Home_Network/internal/models"
+	"github.com/AMathur20/Home_Network/internal/netutil"
+	"github.com/AMathur20/Home_Network/internal/storage"
+)
+
+const maxMessageSize = 64 * 1024
+
+// Server receives syslog over UDP and TCP, attributes each message to the
+// configured device it came from and stores it in the logs table.
+type Server struct {
+	addr      string
+	retention time.Duration
+	storage   *storage.DuckDBStorage
+	devices   *models.DeviceAddresses // source IP -> device
+}
+
+func NewServer(cfg models.SyslogConfig, devices []models.DeviceConfig, s *storage.DuckDBStorage) *Server {
+	addr := cfg.Listen
+	if addr == "" {
+		addr = ":514"
+	}
+	days := cfg.Retention
+	if days <= 0 {
+		days = 30
+	}
+	return &Server{
+		addr:      addr,
+		retention: time.Duration(days) * 24 * time.Hour,
+		storage:   s,
+		devices:   models.NewDeviceAddresses(devices),
+	}
+}
+
+// SetDevices replaces the devices messages are attributed to.
+func (s *Server) SetDevices(devices []models.DeviceConfig) {
+	s.devices.Set(devices)
+}
+
+// Start listens on UDP and TCP and purges expired messages hourly. It only
+// returns if a listener cannot be opened or is closed.
+func (s *Server) Start() error {
+	udp, err := net.ListenPacket("udp", s.addr)
+	if err != nil {
+		return err
+	}
+	tcp, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		udp.Close()
+		return err
+	}
+	log.Printf("Syslog receiver listening on udp/tcp %s (retention %s)", s.addr, s.retention)
+
+	go s.serveTCP(tcp)
+	go s.purge()
+
+	buf := make([]byte, maxMessageSize)
+	var backoff netutil.Backoff
+	for {
+		n, addr, err := udp.ReadFrom(buf)
+		if errors.Is(err, net.ErrClosed) {
+			tcp.Close()
+			return nil
+		}
+		if err != nil {
+			log.Printf("Syslog UDP read error: %v", err)
+			backoff.Wait()
+			continue
+		}
+		backoff.Reset()
+		s.handle(buf[:n], addr.(*net.UDPAddr).IP)
+	}
+}
+
+func (s *Server) serveTCP(l net.Listener) {
+	var backoff netutil.Backoff
+	for {
+		conn, err := l.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return
+		}
+		if err != nil {
+			log.Printf("Syslog TCP accept error: %v", err)
+			backoff.Wait()
+			continue
+		}
+		backoff.Reset()
+		go func() {
+			defer conn.Close()
+			ip := conn.RemoteAddr().(*net.TCPAddr).IP
+			r := bufio.NewReaderSize(conn, maxMessageSize)
+			for {
+				frame, err := readFrame(r)
+				if err != nil {
+					if err != io.EOF {
+						log.Printf("Syslog TCP read error from %s: %v", ip, err)
+					}
+					return
+				}
+				s.handle(frame, ip)
+			}
+		}()
+	}
+}
+
+// readFrame reads one message from a TCP stream, using octet counting
+// ("LEN SP MSG") when the frame starts with a digit and newline delimiting
+// otherwise (RFC 6587). Stray line breaks between frames are skipped.
+func readFrame(r *bufio.Reader) ([]byte, error) {
+	first, err := r.Peek(1)
+	for err == nil && (first[0] == '\n' || first[0] == '\r') {
+		r.Discard(1)
+		first, err = r.Peek(1)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if first[0] >= '1' && first[0] <= '9' {
+		prefix, err := r.ReadString(' ')
+		if err != nil {
+			return nil, err
+		}
+		n, err := strconv.Atoi(prefix[:len(prefix)-1])
+		if err != nil || n > maxMessageSize {
+			return nil, io.ErrUnexpectedEOF
+		}
+		frame := make([]byte, n)
+		_, err = io.ReadFull(r, frame)
+		return frame, err
+	}
+	line, err := r.ReadBytes('\n')
+	if err == io.EOF && len(line) > 0 {
+		err = nil
+	}
+	return line, err
+}
+
+func (s *Server) handle(data []byte, ip net.IP) {
+	now := time.Now()
+	msg, err := Parse(data, now)
+	if err != nil {
+		log.Printf("Dropping syslog message from %s: %v", ip, err)
+		return
+	}
+	msg.Timestamp = now
+	msg.Source = ip.String()
+	if dev, ok := s.devices.Lookup(msg.Source); ok {
+		msg.DeviceName = dev.Name
+	}
+	if err := s.storage.SaveLog(msg); err != nil {
+		log.Printf("Error saving syslog message from %s: %v", ip, err)
+	}
+}
+
+func (s *Server) purge() {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for ; ; <-ticker.C {
+		deleted, err := s.storage.DeleteLogsBefore(time.Now().Add(-s.retention))
+		if err != nil {
+			log.Printf("Error purging syslog messages: %v", err)
+		} else if deleted > 0 {
+			log.Printf("Purged %d syslog messages older than %s", deleted, s.retention)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package syslog
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestServeTCPStopsWhenClosed(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	go func() {
+		(&Server{}).serveTCP(l)
+		close(done)
+	}()
+
+	l.Close()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the accept loop to return once the listener is closed")
+	}
+}
@@ -0,0 +1,140 @@
+package topology
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/AMathur20/Home_Network/internal/models"
+)
+
+// IANAifType values used for classification.
+const (
+	ifTypeEthernetCsmacd = 6
+	ifTypePPP            = 23
+	ifTypeSoftLoopback   = 24
+	ifTypePropVirtual    = 53
+	ifTypeIEEE80211      = 71
+	ifTypeTunnel         = 131
+	ifTypeL2VLAN         = 135
+	ifTypeIEEE8023adLag  = 161
+	ifTypeBridge         = 209
+)
+
+// InterfaceInfo is what the crawler knows about a local interface when it
+// classifies a link.
+type InterfaceInfo struct {
+	Name      string
+	IfType    int
+	HighSpeed uint64 // Mbps, from ifHighSpeed
+	SFP       bool   // An SFP/optical module is present
+}
+
+type classificationRule struct {
+	name     *regexp.Regexp
+	ifType   int
+	minSpeed uint64
+	maxSpeed uint64
+	sfp      *bool
+	linkType LinkType
+}
+
+// Classifier assigns a LinkType to an interface. Configured rules are tried
+// first, then ifType and ifHighSpeed, and finally the interface name.
+type Classifier struct {
+	rules []classificationRule
+}
+
+func NewClassifier(rules []models.ClassificationRule) (*Classifier, error) {
+	c := &Classifier{}
+	for i, r := range rules {
+		if r.Type == "" {
+			return nil, fmt.Errorf("classification rule %d: type is required", i)
+		}
+		rule := classificationRule{
+			ifType:   r.IfType,
+			minSpeed: r.MinSpeed,
+			maxSpeed: r.MaxSpeed,
+			sfp:      r.SFP,
+			linkType: LinkType(r.Type),
+		}
+		if r.Name != "" {
+			re, err := regexp.Compile(r.Name)
+			if err != nil {
+				return nil, fmt.Errorf("classification rule %d: invalid name pattern: %w", i, err)
+			}
+			rule.name = re
+		}
+		c.rules = append(c.rules, rule)
+	}
+	return c, nil
+}
+
+func (c *Classifier) Classify(info InterfaceInfo) LinkType {
+	if c != nil {
+		for _, r := range c.rules {
+			if r.matches(info) {
+				return r.linkType
+			}
+		}
+	}
+
+	switch info.IfType {
+	case ifTypeIEEE80211:
+		return LinkTypeWireless
+	case ifTypeIEEE8023adLag:
+		return LinkTypeLAG
+	case ifTypeTunnel, ifTypePPP:
+		return LinkTypeVPN
+	case ifTypePropVirtual, ifTypeL2VLAN, ifTypeBridge, ifTypeSoftLoopback:
+		return LinkTypeVirtual
+	}
+
+	if t := classifyBySpeed(info.HighSpeed, info.SFP); t != "" {
+		return t
+	}
+	return ClassifyLink(info.Name)
+}
+
+func (r classificationRule) matches(info InterfaceInfo) bool {
+	if r.name != nil && !r.name.MatchString(info.Name) {
+		return false
+	}
+	if r.ifType != 0 && r.ifType != info.IfType {
+		return false
+	}
+	if r.minSpeed != 0 && info.HighSpeed < r.minSpeed {
+		return false
+	}
+	if r.maxSpeed != 0 && info.HighSpeed > r.maxSpeed {
+		return false
+	}
+	if r.sfp != nil && *r.sfp != info.SFP {
+		return false
+	}
+	return true
+}
+
+// classifyBySpeed maps a negotiated ifHighSpeed to a link class. It returns ""
+// when the speed is unknown, e.g. for a port that is down.
+func classifyBySpeed(mbps uint64, sfp bool) LinkType {
+	switch {
+	case mbps == 0:
+		return ""
+	case mbps >= 100000:
+		return LinkType100G
+	case mbps >= 40000:
+		return LinkType40G
+	case mbps >= 25000:
+		return LinkType25G
+	case mbps >= 10000:
+		return LinkType10G
+	case mbps >= 5000:
+		return LinkType5G
+	case mbps >= 2500:
+		return LinkType2500M
+	case sfp:
+		return LinkType1G
+	default:
+		return LinkTypeEthernet
+	}
+}
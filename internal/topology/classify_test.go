@@ -0,0 +1,66 @@
+package topology
+
+import (
+	"testing"
+
+	"github.com/AMathur20/Home_Network/internal/models"
+)
+
+func TestClassifierDefaults(t *testing.T) {
+	c, err := NewClassifier(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		info InterfaceInfo
+		want LinkType
+	}{
+		{InterfaceInfo{Name: "uplink", IfType: ifTypeEthernetCsmacd, HighSpeed: 10000}, LinkType10G},
+		{InterfaceInfo{Name: "ether1", IfType: ifTypeEthernetCsmacd, HighSpeed: 2500}, LinkType2500M},
+		{InterfaceInfo{Name: "ether2", IfType: ifTypeEthernetCsmacd, HighSpeed: 1000}, LinkTypeEthernet},
+		{InterfaceInfo{Name: "ether3", IfType: ifTypeEthernetCsmacd, HighSpeed: 1000, SFP: true}, LinkType1G},
+		{InterfaceInfo{Name: "sfp28-1", IfType: ifTypeEthernetCsmacd, HighSpeed: 25000}, LinkType25G},
+		{InterfaceInfo{Name: "radio0", IfType: ifTypeIEEE80211, HighSpeed: 866}, LinkTypeWireless},
+		{InterfaceInfo{Name: "bond1", IfType: ifTypeIEEE8023adLag, HighSpeed: 2000}, LinkTypeLAG},
+		{InterfaceInfo{Name: "wg0", IfType: ifTypeTunnel}, LinkTypeVPN},
+		{InterfaceInfo{Name: "vlan30", IfType: ifTypeL2VLAN}, LinkTypeVirtual},
+		// No speed data: fall back to the interface name.
+		{InterfaceInfo{Name: "sfp-sfpplus1"}, LinkType10G},
+		{InterfaceInfo{Name: "wlan1"}, LinkTypeWireless},
+		{InterfaceInfo{Name: "lo"}, LinkTypeVirtual},
+		{InterfaceInfo{Name: "Loopback0"}, LinkTypeVirtual},
+		{InterfaceInfo{Name: "lobby-ap"}, LinkTypeEthernet},
+		{InterfaceInfo{Name: "lan-office"}, LinkTypeEthernet},
+	}
+	for _, tt := range tests {
+		if got := c.Classify(tt.info); got != tt.want {
+			t.Errorf("Classify(%+v) = %s, want %s", tt.info, got, tt.want)
+		}
+	}
+}
+
+func TestClassifierRules(t *testing.T) {
+	sfp := true
+	c, err := NewClassifier([]models.ClassificationRule{
+		{Name: "^uplink", Type: "vpn"},
+		{IfType: ifTypeEthernetCsmacd, MinSpeed: 1000, MaxSpeed: 1000, SFP: &sfp, Type: "10g"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := c.Classify(InterfaceInfo{Name: "uplink-isp", IfType: ifTypeEthernetCsmacd, HighSpeed: 1000}); got != LinkTypeVPN {
+		t.Errorf("Expected name rule to win, got %s", got)
+	}
+	if got := c.Classify(InterfaceInfo{Name: "sfp1", IfType: ifTypeEthernetCsmacd, HighSpeed: 1000, SFP: true}); got != LinkType10G {
+		t.Errorf("Expected SFP rule to match, got %s", got)
+	}
+	if got := c.Classify(InterfaceInfo{Name: "ether1", IfType: ifTypeEthernetCsmacd, HighSpeed: 1000}); got != LinkTypeEthernet {
+		t.Errorf("Expected no rule to match, got %s", got)
+	}
+
+	if _, err := NewClassifier([]models.ClassificationRule{{Name: "(", Type: "10g"}}); err == nil {
+		t.Error("Expected invalid pattern to be rejected")
+	}
+}
@@ -3,9 +3,11 @@ package topology
 import (
 	"fmt"
 	"log"
-	"time"
+	"slices"
+	"sync"
 
 	"github.com/AMathur20/Home_Network/internal/models"
+	"github.com/AMathur20/Home_Network/internal/snmp"
 	"github.com/gosnmp/gosnmp"
 )
 
@@ -13,27 +15,78 @@ const (
 	oidLldpRemSysName = ".1.0.8802.1.1.2.1.4.1.1.9"
 	oidLldpRemPortId  = ".1.0.8802.1.1.2.1.4.1.1.7"
 	oidIfName         = ".1.3.6.1.2.1.31.1.1.1.1"
+	oidIfType         = ".1.3.6.1.2.1.2.2.1.3"
+	oidIfHighSpeed    = ".1.3.6.1.2.1.31.1.1.1.15"
 
 	// MikroTik MNDP OIDs
 	// mtxrNeighborTable: .1.3.6.1.4.1.14988.1.1.11.1
 	oidMndpNeighborIdentity  = ".1.3.6.1.4.1.14988.1.1.11.1.3" // Neighbor System Name
 	oidMndpNeighborInterface = ".1.3.6.1.4.1.14988.1.1.11.1.8" // Local Interface on which neighbor was discovered
+
+	// MikroTik optical table, indexed by ifIndex. Present only for ports with an SFP module.
+	oidMtxrOpticalName = ".1.3.6.1.4.1.14988.1.1.19.1.1.2"
 )
 
 type Crawler struct {
-	devices []models.DeviceConfig
+	mu         sync.Mutex
+	devices    []models.DeviceConfig
+	profiles   []models.CredentialProfile
+	classifier *Classifier
+}
+
+func NewCrawler(devices []models.DeviceConfig, classifier *Classifier) *Crawler {
+	return &Crawler{devices: devices, classifier: classifier}
 }
 
-func NewCrawler(devices []models.DeviceConfig) *Crawler {
-	return &Crawler{devices: devices}
+// SetDevices replaces the devices the next discovery starts from.
+func (c *Crawler) SetDevices(devices []models.DeviceConfig) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.devices = devices
+}
+
+// SetCredentials sets the profiles tried against neighbors that aren't in the
+// device list. Neighbors that answer are crawled as well.
+func (c *Crawler) SetCredentials(profiles []models.CredentialProfile) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.profiles = profiles
 }
 
 func (c *Crawler) Discover() (*Topology, error) {
-	log.Println("Starting topology discovery (LLDP + MNDP)...")
+	log.Println("Starting topology discovery (LLDP + MNDP + LAG + VLAN)...")
 
 	links := make([]Link, 0)
+	lags := make(map[string]map[string]string)
+	vlans := make(map[string]*deviceVLANs)
+
+	c.mu.Lock()
+	devices, profiles := slices.Clone(c.devices), c.profiles
+	c.mu.Unlock()
+	known := make(map[string]bool)
+	for _, dev := range devices {
+		known[dev.Name], known[dev.Host] = true, true
+	}
+	// Neighbors found with the discovery credentials are appended as they
+	// are crawled.
+	for i := 0; i < len(devices); i++ {
+		dev := devices[i]
+		// LAG membership, used to fold member links into one logical link
+		members, err := c.discoverLagsForDevice(dev)
+		if err != nil {
+			log.Printf("Error discovering LAG membership for %s: %v", dev.Name, err)
+		} else if len(members) > 0 {
+			lags[dev.Name] = members
+		}
+
+		// Bridge VLAN membership, attached to link endpoints
+		deviceVlans, err := c.discoverVlansForDevice(dev)
+		if err != nil {
+			log.Printf("Error discovering VLANs for %s: %v", dev.Name, err)
+		} else {
+			vlans[dev.Name] = deviceVlans
+		}
 
-	for _, dev := range c.devices {
 		// LLDP discovery (Universal)
 		lldpLinks, err := c.discoverLldpForDevice(dev)
 		if err != nil {
@@ -51,39 +104,40 @@ func (c *Crawler) Discover() (*Topology, error) {
 				links = append(links, mndpLinks...)
 			}
 		}
+
+		if len(profiles) > 0 {
+			devices = append(devices, c.newNeighbors(dev, profiles, known)...)
+		}
 	}
 
-	return &Topology{Links: deduplicateLinks(links)}, nil
+	topo := &Topology{Links: aggregateLinks(deduplicateLinks(links), lags)}
+	topo.VLANs = annotateVlans(topo.Links, vlans)
+	for _, l := range topo.Links {
+		for _, issue := range l.VLANMismatches() {
+			log.Printf("VLAN mismatch: %s", issue)
+		}
+	}
+	return topo, nil
 }
 
 func (c *Crawler) discoverLldpForDevice(dev models.DeviceConfig) ([]Link, error) {
-	params := &gosnmp.GoSNMP{
-		Target:    dev.Host,
-		Port:      uint16(dev.SNMP.Port),
-		Community: dev.SNMP.Community,
-		Version:   gosnmp.Version2c,
-		Timeout:   time.Duration(2) * time.Second,
-		Retries:   3,
+	params, err := snmp.Dial(dev)
+	if err != nil {
+		return nil, err
 	}
+	defer params.Conn.Close()
 
-	err := params.Connect()
+	// 1. Map ifIndex to interface details
+	ifaces, err := walkInterfaces(params)
 	if err != nil {
 		return nil, err
 	}
-	defer params.Conn.Close()
+	log.Printf("Found %d interfaces on %s", len(ifaces), dev.Name)
 
-	// 1. Map ifIndex to ifName
-	ifNames := make(map[int]string)
-	err = params.BulkWalk(oidIfName, func(pdu gosnmp.SnmpPDU) error {
-		index := 0
-		fmt.Sscanf(pdu.Name, oidIfName+".%d", &index)
-		ifNames[index] = models.PduToString(pdu.Value)
-		return nil
-	})
+	stp, err := ReadSTP(params)
 	if err != nil {
-		return nil, fmt.Errorf("failed to walk ifName: %v", err)
+		log.Printf("Could not read spanning-tree state on %s: %v", dev.Name, err)
 	}
-	log.Printf("Found %d interfaces on %s", len(ifNames), dev.Name)
 
 	links := make([]Link, 0)
 
@@ -96,10 +150,11 @@ func (c *Crawler) discoverLldpForDevice(dev models.DeviceConfig) ([]Link, error)
 		var timeMark, localPortNum, remIndex int
 		fmt.Sscanf(suffix, "%d.%d.%d", &timeMark, &localPortNum, &remIndex)
 
-		sourceIface := ifNames[localPortNum]
-		if sourceIface == "" {
-			sourceIface = fmt.Sprintf("port-%d", localPortNum)
+		info := ifaces[localPortNum]
+		if info.Name == "" {
+			info.Name = fmt.Sprintf("port-%d", localPortNum)
 		}
+		sourceIface := info.Name
 
 		targetDevice := models.PduToString(pdu.Value)
 
@@ -111,13 +166,20 @@ func (c *Crawler) discoverLldpForDevice(dev models.DeviceConfig) ([]Link, error)
 			targetIface = models.PduToString(result.Variables[0].Value)
 		}
 
-		links = append(links, Link{
+		link := Link{
 			SourceDevice:    dev.Name,
 			SourceInterface: sourceIface,
 			TargetDevice:    targetDevice,
 			TargetInterface: targetIface,
-			Type:            ClassifyLink(sourceIface),
-		})
+			Type:            c.classifier.Classify(info),
+		}
+		if stp != nil {
+			if port, ok := stp.Ports[localPortNum]; ok {
+				link.STPState = port.State
+				link.STPRole = port.Role
+			}
+		}
+		links = append(links, link)
 
 		return nil
 	})
@@ -126,20 +188,20 @@ func (c *Crawler) discoverLldpForDevice(dev models.DeviceConfig) ([]Link, error)
 }
 
 func (c *Crawler) discoverMndpForDevice(dev models.DeviceConfig) ([]Link, error) {
-	params := &gosnmp.GoSNMP{
-		Target:    dev.Host,
-		Port:      uint16(dev.SNMP.Port),
-		Community: dev.SNMP.Community,
-		Version:   gosnmp.Version2c,
-		Timeout:   time.Duration(2) * time.Second,
-		Retries:   3,
+	params, err := snmp.Dial(dev)
+	if err != nil {
+		return nil, err
 	}
+	defer params.Conn.Close()
 
-	err := params.Connect()
+	ifaces, err := walkInterfaces(params)
 	if err != nil {
 		return nil, err
 	}
-	defer params.Conn.Close()
+	byName := make(map[string]InterfaceInfo)
+	for _, info := range ifaces {
+		byName[info.Name] = info
+	}
 
 	links := make([]Link, 0)
 
@@ -155,13 +217,15 @@ func (c *Crawler) discoverMndpForDevice(dev models.DeviceConfig) ([]Link, error)
 		if err == nil && len(result.Variables) > 0 {
 			sourceIface = models.PduToString(result.Variables[0].Value)
 		}
+		info := byName[sourceIface]
+		info.Name = sourceIface
 
 		links = append(links, Link{
 			SourceDevice:    dev.Name,
 			SourceInterface: sourceIface,
 			TargetDevice:    targetDevice,
 			TargetInterface: "unknown", // MNDP often doesn't provide remote port ID via SNMP easily
-			Type:            ClassifyLink(sourceIface),
+			Type:            c.classifier.Classify(info),
 		})
 
 		return nil
@@ -171,6 +235,44 @@ func (c *Crawler) discoverMndpForDevice(dev models.DeviceConfig) ([]Link, error)
 	return links, err
 }
 
+// walkInterfaces collects name, ifType, speed and SFP presence per ifIndex.
+// Only ifName is required; the other tables are best effort.
+func walkInterfaces(params *gosnmp.GoSNMP) (map[int]InterfaceInfo, error) {
+	ifaces := make(map[int]InterfaceInfo)
+	err := params.BulkWalk(oidIfName, func(pdu gosnmp.SnmpPDU) error {
+		index := 0
+		fmt.Sscanf(pdu.Name, oidIfName+".%d", &index)
+		ifaces[index] = InterfaceInfo{Name: models.PduToString(pdu.Value)}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk ifName: %v", err)
+	}
+
+	update := func(oid string, fn func(info *InterfaceInfo, pdu gosnmp.SnmpPDU)) {
+		params.BulkWalk(oid, func(pdu gosnmp.SnmpPDU) error {
+			index := 0
+			fmt.Sscanf(pdu.Name, oid+".%d", &index)
+			if info, ok := ifaces[index]; ok {
+				fn(&info, pdu)
+				ifaces[index] = info
+			}
+			return nil
+		})
+	}
+	update(oidIfType, func(info *InterfaceInfo, pdu gosnmp.SnmpPDU) {
+		info.IfType = models.PduToInt(pdu.Value)
+	})
+	update(oidIfHighSpeed, func(info *InterfaceInfo, pdu gosnmp.SnmpPDU) {
+		info.HighSpeed = models.PduToUint64(pdu.Value)
+	})
+	update(oidMtxrOpticalName, func(info *InterfaceInfo, pdu gosnmp.SnmpPDU) {
+		info.SFP = true
+	})
+
+	return ifaces, nil
+}
+
 func deduplicateLinks(links []Link) []Link {
 	type key struct {
 		srcDev, srcIf, tgtDev string
@@ -0,0 +1,104 @@
+package topology
+
+import (
+	"fmt"
+	"slices"
+)
+
+type ChangeKind string
+
+const (
+	ChangeAdded   ChangeKind = "added"
+	ChangeRemoved ChangeKind = "removed"
+	ChangeChanged ChangeKind = "changed"
+)
+
+type Change struct {
+	Kind   ChangeKind
+	Link   Link
+	Detail string
+}
+
+// DiffTopology compares a discovery result against the current topology.
+// Manual links are never reported as removed or changed, nor are changes to
+// manual fields, and links already marked stale are not reported as removed
+// again.
+func DiffTopology(current, discovered *Topology) []Change {
+	changes := make([]Change, 0)
+
+	currentIndex := make(map[linkKey]int)
+	for i, l := range current.Links {
+		currentIndex[keyOf(l)] = i
+	}
+	discoveredIndex := make(map[linkKey]int)
+	for i, l := range discovered.Links {
+		discoveredIndex[keyOf(l)] = i
+	}
+
+	// Both ends of a new cable are discovered; report it once.
+	addedIndex := make(map[linkKey]int)
+	for _, d := range discovered.Links {
+		i := findLink(currentIndex, d)
+		if i < 0 {
+			if findLink(addedIndex, d) < 0 {
+				addedIndex[keyOf(d)] = len(changes)
+				changes = append(changes, Change{Kind: ChangeAdded, Link: d})
+			}
+			continue
+		}
+
+		c := current.Links[i]
+		if c.Manual || c.SourceDevice != d.SourceDevice {
+			continue
+		}
+		if c.Stale {
+			changes = append(changes, Change{Kind: ChangeAdded, Link: d, Detail: "link seen again"})
+			continue
+		}
+
+		var detail string
+		if d.TargetInterface != "unknown" && c.TargetInterface != d.TargetInterface && !c.isManual("target_interface") {
+			detail = fmt.Sprintf("target interface %s -> %s", c.TargetInterface, d.TargetInterface)
+		}
+		if c.Type != d.Type && !c.isManual("type") {
+			if detail != "" {
+				detail += ", "
+			}
+			detail += fmt.Sprintf("type %s -> %s", c.Type, d.Type)
+		}
+		if len(c.Members) != len(d.Members) && !c.isManual("members") {
+			if detail != "" {
+				detail += ", "
+			}
+			detail += fmt.Sprintf("members %d -> %d", len(c.Members), len(d.Members))
+		}
+		if (!sameVLANs(c.SourceVLANs, d.SourceVLANs) && !c.isManual("source_vlans")) ||
+			(!sameVLANs(c.TargetVLANs, d.TargetVLANs) && !c.isManual("target_vlans")) {
+			if detail != "" {
+				detail += ", "
+			}
+			detail += "vlan membership"
+		}
+		if detail != "" {
+			changes = append(changes, Change{Kind: ChangeChanged, Link: d, Detail: detail})
+		}
+	}
+
+	for _, c := range current.Links {
+		if c.Manual || c.Stale {
+			continue
+		}
+		if findLink(discoveredIndex, c) < 0 {
+			changes = append(changes, Change{Kind: ChangeRemoved, Link: c})
+		}
+	}
+
+	return changes
+}
+
+func sameVLANs(a, b *VLANMembership) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.PVID == b.PVID && slices.Equal(a.Tagged, b.Tagged) && slices.Equal(a.Untagged, b.Untagged)
+}
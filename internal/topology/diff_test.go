@@ -0,0 +1,35 @@
+package topology
+
+import "testing"
+
+func TestDiffTopology(t *testing.T) {
+	current := &Topology{Links: []Link{
+		{SourceDevice: "core", SourceInterface: "ether1", TargetDevice: "switch", TargetInterface: "port1", Type: LinkTypeEthernet},
+		{SourceDevice: "core", SourceInterface: "ether2", TargetDevice: "nas", TargetInterface: "eth0", Type: LinkType10G, Manual: true},
+		{SourceDevice: "core", SourceInterface: "ether3", TargetDevice: "old-ap", TargetInterface: "eth0", Type: LinkTypeEthernet},
+	}}
+	discovered := &Topology{Links: []Link{
+		{SourceDevice: "core", SourceInterface: "ether1", TargetDevice: "switch", TargetInterface: "port2", Type: LinkTypeEthernet},
+		{SourceDevice: "switch", SourceInterface: "port2", TargetDevice: "core", TargetInterface: "ether1", Type: LinkTypeEthernet},
+		{SourceDevice: "core", SourceInterface: "wlan1", TargetDevice: "laptop", TargetInterface: "unknown", Type: LinkTypeWireless},
+		{SourceDevice: "core", SourceInterface: "ether4", TargetDevice: "ap", TargetInterface: "eth0", Type: LinkTypeEthernet},
+		{SourceDevice: "ap", SourceInterface: "eth0", TargetDevice: "core", TargetInterface: "ether4", Type: LinkTypeEthernet},
+	}}
+
+	changes := DiffTopology(current, discovered)
+
+	kinds := make(map[ChangeKind][]Link)
+	for _, c := range changes {
+		kinds[c.Kind] = append(kinds[c.Kind], c.Link)
+	}
+
+	if len(kinds[ChangeAdded]) != 2 || kinds[ChangeAdded][0].TargetDevice != "laptop" || kinds[ChangeAdded][1].TargetDevice != "ap" {
+		t.Errorf("Expected laptop and ap links to be added once each, got %+v", kinds[ChangeAdded])
+	}
+	if len(kinds[ChangeChanged]) != 1 || kinds[ChangeChanged][0].TargetInterface != "port2" {
+		t.Errorf("Expected switch link to be changed, got %+v", kinds[ChangeChanged])
+	}
+	if len(kinds[ChangeRemoved]) != 1 || kinds[ChangeRemoved][0].TargetDevice != "old-ap" {
+		t.Errorf("Expected old-ap link to be removed, got %+v", kinds[ChangeRemoved])
+	}
+}
@@ -0,0 +1,136 @@
+package topology
+
+import (
+	"github.com/AMathur20/Home_Network/internal/models"
+)
+
+// GraphNode is a Node as served by the API, flagged with whether HNM polls it.
+type GraphNode struct {
+	Node
+	Polled bool `json:"polled"`
+	// PoweredBy names the PoE port ("device/interface") feeding this node.
+	PoweredBy string `json:"powered_by,omitempty"`
+	// Status and RTT (ms) come from the node's reachability probes.
+	Status string  `json:"status,omitempty"`
+	RTT    float64 `json:"rtt,omitempty"`
+}
+
+type Graph struct {
+	Nodes []GraphNode `json:"nodes"`
+	Edges []Link      `json:"edges"`
+	VLANs []VLAN      `json:"vlans"`
+}
+
+// BuildGraph resolves the full set of nodes for a topology. Declared nodes come
+// first, followed by configured devices and finally any link endpoints that are
+// neither, such as neighbors discovered via LLDP that HNM does not poll. Edge
+// endpoints naming a config device are rewritten to the node that claims it.
+func BuildGraph(topo *Topology, devices []models.DeviceConfig) *Graph {
+	polled := make(map[string]models.DeviceConfig)
+	for _, dev := range devices {
+		polled[dev.Name] = dev
+	}
+
+	graph := &Graph{Nodes: make([]GraphNode, 0), Edges: make([]Link, 0, len(topo.Links)), VLANs: topo.VLANs}
+	if graph.VLANs == nil {
+		graph.VLANs = []VLAN{}
+	}
+	seen := make(map[string]bool)
+	// Maps config device names to the ID of the node that claimed them.
+	claimed := make(map[string]string)
+
+	add := func(n Node) {
+		if n.ID == "" || seen[n.ID] {
+			return
+		}
+		seen[n.ID] = true
+		if n.Name == "" {
+			n.Name = n.ID
+		}
+
+		device := n.Device
+		if device == "" {
+			device = n.ID
+		}
+		dev, ok := polled[device]
+		if ok && n.Vendor == "" {
+			n.Vendor = string(dev.Type)
+		}
+		if ok {
+			claimed[dev.Name] = n.ID
+			n.Device = dev.Name
+		}
+		graph.Nodes = append(graph.Nodes, GraphNode{Node: n, Polled: ok})
+	}
+
+	for _, n := range topo.Nodes {
+		add(n)
+	}
+	for _, dev := range devices {
+		if _, ok := claimed[dev.Name]; !ok {
+			add(Node{ID: dev.Name})
+		}
+	}
+	for _, l := range topo.Links {
+		if id, ok := claimed[l.SourceDevice]; ok {
+			l.SourceDevice = id
+		}
+		if id, ok := claimed[l.TargetDevice]; ok {
+			l.TargetDevice = id
+		}
+		add(Node{ID: l.SourceDevice})
+		add(Node{ID: l.TargetDevice})
+		l.VLANIssues = l.VLANMismatches()
+		graph.Edges = append(graph.Edges, l)
+	}
+
+	return graph
+}
+
+// PoweringPorts maps each device to the PoE port that powers it. delivering
+// holds the "device/interface" keys of PSE ports currently delivering power;
+// the device at the far end of a link from such a port is powered by it.
+func PoweringPorts(topo *Topology, delivering map[string]bool) map[string]string {
+	powering := make(map[string]string)
+	for _, l := range topo.Links {
+		src := l.SourceDevice + "/" + l.SourceInterface
+		tgt := l.TargetDevice + "/" + l.TargetInterface
+		if delivering[src] {
+			powering[l.TargetDevice] = src
+		}
+		if delivering[tgt] {
+			powering[l.SourceDevice] = tgt
+		}
+	}
+	return powering
+}
+
+// SetPoweredBy annotates nodes with their powering port. powering is keyed by
+// the device names used in links, which match either a node ID or the config
+// device it claims.
+func (g *Graph) SetPoweredBy(powering map[string]string) {
+	for i := range g.Nodes {
+		n := &g.Nodes[i]
+		if port, ok := powering[n.ID]; ok {
+			n.PoweredBy = port
+		} else if port, ok := powering[n.Device]; ok && n.Device != "" {
+			n.PoweredBy = port
+		}
+	}
+}
+
+// SetReachability annotates nodes with their latest probe result, keyed by
+// config device name like SetPoweredBy.
+func (g *Graph) SetReachability(results map[string]models.ProbeResult) {
+	for i := range g.Nodes {
+		n := &g.Nodes[i]
+		r, ok := results[n.ID]
+		if !ok && n.Device != "" {
+			r, ok = results[n.Device]
+		}
+		if ok {
+			n.Status = r.Status
+			n.RTT = r.RTT
+		}
+	}
+}
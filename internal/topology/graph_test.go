@@ -0,0 +1,73 @@
+package topology
+
+import (
+	"testing"
+
+	"github.com/AMathur20/Home_Network/internal/models"
+)
+
+func TestBuildGraph(t *testing.T) {
+	topo := &Topology{
+		Nodes: []Node{
+			{ID: "core", Name: "Core Router", Role: NodeRoleRouter, Device: "mikrotik-core"},
+		},
+		Links: []Link{
+			{SourceDevice: "mikrotik-core", SourceInterface: "ether1", TargetDevice: "closet-switch", TargetInterface: "port1", Type: LinkTypeEthernet},
+		},
+	}
+	devices := []models.DeviceConfig{
+		{Name: "mikrotik-core", Type: models.DeviceTypeMikroTik},
+		{Name: "unifi-ap", Type: models.DeviceTypeUniFi},
+	}
+
+	graph := BuildGraph(topo, devices)
+
+	if len(graph.Nodes) != 3 {
+		t.Fatalf("Expected 3 nodes, got %+v", graph.Nodes)
+	}
+	if n := graph.Nodes[0]; n.ID != "core" || !n.Polled || n.Vendor != "mikrotik" {
+		t.Errorf("Expected declared node to be linked to its device, got %+v", n)
+	}
+	if n := graph.Nodes[1]; n.ID != "unifi-ap" || !n.Polled {
+		t.Errorf("Expected configured device to become a node, got %+v", n)
+	}
+	if n := graph.Nodes[2]; n.ID != "closet-switch" || n.Polled {
+		t.Errorf("Expected unpolled neighbor to become a node, got %+v", n)
+	}
+	if e := graph.Edges[0]; e.SourceDevice != "core" {
+		t.Errorf("Expected edge endpoint to be rewritten to node ID, got %+v", e)
+	}
+}
+
+func TestPoweredBy(t *testing.T) {
+	topo := &Topology{
+		Nodes: []Node{
+			{ID: "switch", Device: "closet-switch"},
+		},
+		Links: []Link{
+			{SourceDevice: "closet-switch", SourceInterface: "ether5", TargetDevice: "ap-upstairs", TargetInterface: "eth0"},
+			{SourceDevice: "cam-door", SourceInterface: "eth0", TargetDevice: "closet-switch", TargetInterface: "ether6"},
+			{SourceDevice: "core", SourceInterface: "ether2", TargetDevice: "closet-switch", TargetInterface: "ether1"},
+		},
+	}
+	delivering := map[string]bool{"closet-switch/ether5": true, "closet-switch/ether6": true, "core/ether2": true}
+
+	graph := BuildGraph(topo, []models.DeviceConfig{{Name: "closet-switch"}})
+	graph.SetPoweredBy(PoweringPorts(topo, delivering))
+
+	powered := make(map[string]string)
+	for _, n := range graph.Nodes {
+		powered[n.ID] = n.PoweredBy
+	}
+	want := map[string]string{
+		"switch":      "core/ether2",
+		"ap-upstairs": "closet-switch/ether5",
+		"cam-door":    "closet-switch/ether6",
+		"core":        "",
+	}
+	for id, port := range want {
+		if powered[id] != port {
+			t.Errorf("Expected %s to be powered by %q, got %q", id, port, powered[id])
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package topology
+
+import (
+	"fmt"
+	"log"
+	"slices"
+
+	"github.com/AMathur20/Home_Network/internal/models"
+	"github.com/AMathur20/Home_Network/internal/routeros"
+	"github.com/AMathur20/Home_Network/internal/snmp"
+	"github.com/gosnmp/gosnmp"
+)
+
+const (
+	// IEEE8023-LAG-MIB dot3adAggPortAttachedAggID, indexed by port ifIndex
+	oidDot3adAggPortAttachedAggID = ".1.2.840.10006.300.43.1.2.1.1.13"
+	// IF-MIB ifStackStatus, indexed by higherLayer.lowerLayer
+	oidIfStackStatus = ".1.3.6.1.2.1.31.1.2.1.3"
+)
+
+// discoverLagsForDevice returns a map of member interface name to LAG name.
+// The RouterOS API is preferred on MikroTik when credentials are configured.
+func (c *Crawler) discoverLagsForDevice(dev models.DeviceConfig) (map[string]string, error) {
+	if dev.Type == models.DeviceTypeMikroTik && dev.Auth.Username != "" {
+		members, err := discoverLagsRouterOS(dev)
+		if err == nil {
+			return members, nil
+		}
+		log.Printf("RouterOS API bonding lookup failed for %s, falling back to SNMP: %v", dev.Name, err)
+	}
+
+	params, err := snmp.Dial(dev)
+	if err != nil {
+		return nil, err
+	}
+	defer params.Conn.Close()
+
+	ifaces, err := walkInterfaces(params)
+	if err != nil {
+		return nil, err
+	}
+
+	members := make(map[string]string)
+
+	// 1. IEEE8023-LAG-MIB: each port points at the aggregator it is attached to
+	params.BulkWalk(oidDot3adAggPortAttachedAggID, func(pdu gosnmp.SnmpPDU) error {
+		port := 0
+		fmt.Sscanf(pdu.Name, oidDot3adAggPortAttachedAggID+".%d", &port)
+		agg := models.PduToInt(pdu.Value)
+		if agg != 0 && agg != port && ifaces[port].Name != "" && ifaces[agg].Name != "" {
+			members[ifaces[port].Name] = ifaces[agg].Name
+		}
+		return nil
+	})
+
+	// 2. ifStackTable: lower layers stacked under a LAG interface
+	params.BulkWalk(oidIfStackStatus, func(pdu gosnmp.SnmpPDU) error {
+		var higher, lower int
+		fmt.Sscanf(pdu.Name, oidIfStackStatus+".%d.%d", &higher, &lower)
+		if higher == 0 || lower == 0 {
+			return nil
+		}
+		upper, ok := ifaces[higher]
+		if !ok || ifaces[lower].Name == "" {
+			return nil
+		}
+		if upper.IfType == ifTypeIEEE8023adLag || ClassifyLink(upper.Name) == LinkTypeLAG {
+			if _, exists := members[ifaces[lower].Name]; !exists {
+				members[ifaces[lower].Name] = upper.Name
+			}
+		}
+		return nil
+	})
+
+	return members, nil
+}
+
+func discoverLagsRouterOS(dev models.DeviceConfig) (map[string]string, error) {
+	client, err := routeros.Dial(dev)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	bonds, err := client.Run("/interface/bonding/print")
+	if err != nil {
+		return nil, err
+	}
+
+	members := make(map[string]string)
+	for _, b := range bonds {
+		for _, slave := range routeros.ParseList(b["slaves"]) {
+			members[slave] = b["name"]
+		}
+	}
+	return members, nil
+}
+
+// aggregateLinks collapses parallel links between the same pair of devices
+// whose source ports belong to one LAG into a single logical link. lags maps
+// device name to its member->LAG table.
+func aggregateLinks(links []Link, lags map[string]map[string]string) []Link {
+	type groupKey struct {
+		srcDev, lag, tgtDev string
+	}
+	groups := make(map[groupKey]int)
+	result := make([]Link, 0, len(links))
+
+	for _, l := range links {
+		lag, isMember := lags[l.SourceDevice][l.SourceInterface]
+		if !isMember {
+			if l.Type != LinkTypeLAG {
+				result = append(result, l)
+				continue
+			}
+			// The LAG interface itself was reported as the local port.
+			lag = l.SourceInterface
+		}
+
+		k := groupKey{l.SourceDevice, lag, l.TargetDevice}
+		i, ok := groups[k]
+		if !ok {
+			i = len(result)
+			groups[k] = i
+			result = append(result, Link{
+				SourceDevice:    l.SourceDevice,
+				SourceInterface: lag,
+				TargetDevice:    l.TargetDevice,
+				TargetInterface: "unknown",
+				Type:            LinkTypeLAG,
+			})
+		}
+
+		agg := &result[i]
+		if remote, ok := lags[l.TargetDevice][l.TargetInterface]; ok {
+			agg.TargetInterface = remote
+		} else if agg.TargetInterface == "unknown" && !isMember {
+			agg.TargetInterface = l.TargetInterface
+		}
+		if isMember {
+			agg.Members = append(agg.Members, LinkMember{
+				SourceInterface: l.SourceInterface,
+				TargetInterface: l.TargetInterface,
+			})
+		}
+	}
+
+	return result
+}
+
+// LagMembers returns the member ports of every LAG terminating on device,
+// keyed by the LAG interface name. A LAG crawled from both ends shows up as a
+// link in each direction; its members are only listed once.
+func (t *Topology) LagMembers(device string) map[string][]string {
+	lags := make(map[string][]string)
+	add := func(lag, member string) {
+		if !slices.Contains(lags[lag], member) {
+			lags[lag] = append(lags[lag], member)
+		}
+	}
+	for _, l := range t.Links {
+		if len(l.Members) == 0 {
+			continue
+		}
+		for _, m := range l.Members {
+			if l.SourceDevice == device {
+				add(l.SourceInterface, m.SourceInterface)
+			}
+			if l.TargetDevice == device && l.TargetInterface != "unknown" && m.TargetInterface != "unknown" {
+				add(l.TargetInterface, m.TargetInterface)
+			}
+		}
+	}
+	return lags
+}
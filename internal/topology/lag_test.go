@@ -0,0 +1,36 @@
+package topology
+
+import "testing"
+
+func TestAggregateLinks(t *testing.T) {
+	links := []Link{
+		{SourceDevice: "core", SourceInterface: "ether1", TargetDevice: "switch", TargetInterface: "port1", Type: LinkTypeEthernet},
+		{SourceDevice: "core", SourceInterface: "ether2", TargetDevice: "switch", TargetInterface: "port2", Type: LinkTypeEthernet},
+		{SourceDevice: "core", SourceInterface: "ether3", TargetDevice: "nas", TargetInterface: "eth0", Type: LinkTypeEthernet},
+	}
+	lags := map[string]map[string]string{
+		"core":   {"ether1": "bond1", "ether2": "bond1"},
+		"switch": {"port1": "lag1", "port2": "lag1"},
+	}
+
+	result := aggregateLinks(links, lags)
+
+	if len(result) != 2 {
+		t.Fatalf("Expected 2 links, got %+v", result)
+	}
+	lag := result[0]
+	if lag.Type != LinkTypeLAG || lag.SourceInterface != "bond1" || lag.TargetInterface != "lag1" {
+		t.Errorf("Unexpected aggregated link: %+v", lag)
+	}
+	if len(lag.Members) != 2 || lag.Members[1].SourceInterface != "ether2" || lag.Members[1].TargetInterface != "port2" {
+		t.Errorf("Unexpected members: %+v", lag.Members)
+	}
+	if result[1].SourceInterface != "ether3" {
+		t.Errorf("Expected non-member link to be kept, got %+v", result[1])
+	}
+
+	members := (&Topology{Links: result}).LagMembers("switch")
+	if len(members["lag1"]) != 2 {
+		t.Errorf("Expected far-end LAG members to be resolved, got %+v", members)
+	}
+}
@@ -0,0 +1,283 @@
+package topology
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+type linkKey struct {
+	srcDev, srcIf, tgtDev string
+}
+
+func keyOf(l Link) linkKey {
+	return linkKey{l.SourceDevice, l.SourceInterface, l.TargetDevice}
+}
+
+// findLink returns the index of the link matching l in either direction, or -1.
+func findLink(index map[linkKey]int, l Link) int {
+	if i, ok := index[keyOf(l)]; ok {
+		return i
+	}
+	if i, ok := index[linkKey{l.TargetDevice, l.TargetInterface, l.SourceDevice}]; ok {
+		return i
+	}
+	return -1
+}
+
+// overridableFields are the link fields discovery updates, which can be
+// listed in ManualFields to keep a hand-edited value.
+var overridableFields = []string{"target_interface", "type", "stp_state", "stp_role", "members", "source_vlans", "target_vlans"}
+
+// isManual reports whether field was set by hand, on its own or as part of a
+// manual link.
+func (l *Link) isManual(field string) bool {
+	return l.Manual || slices.Contains(l.ManualFields, field)
+}
+
+// update sets a field of l to a discovered value unless it was set by hand.
+func update[T any](l *Link, field string, dst *T, value T) {
+	if !l.isManual(field) {
+		*dst = value
+	}
+}
+
+// MergeTopology folds a fresh discovery result into the current topology.
+// Manual links and fields are kept untouched, discovered links are added or
+// updated in place, and auto-discovered links that were not seen again are
+// marked stale.
+func MergeTopology(current, discovered *Topology) *Topology {
+	merged := &Topology{
+		Nodes: current.Nodes,
+		Links: make([]Link, 0, len(current.Links)+len(discovered.Links)),
+	}
+	index := make(map[linkKey]int)
+	seen := make(map[int]bool)
+
+	for i, l := range current.Links {
+		merged.Links = append(merged.Links, l)
+		index[keyOf(l)] = i
+	}
+
+	for _, d := range discovered.Links {
+		i := findLink(index, d)
+		if i < 0 {
+			index[keyOf(d)] = len(merged.Links)
+			seen[len(merged.Links)] = true
+			merged.Links = append(merged.Links, d)
+			continue
+		}
+		seen[i] = true
+
+		existing := &merged.Links[i]
+		if existing.Manual {
+			continue
+		}
+		if existing.SourceDevice == d.SourceDevice {
+			if d.TargetInterface != "unknown" {
+				update(existing, "target_interface", &existing.TargetInterface, d.TargetInterface)
+			}
+			update(existing, "type", &existing.Type, d.Type)
+			update(existing, "members", &existing.Members, d.Members)
+			update(existing, "stp_state", &existing.STPState, d.STPState)
+			update(existing, "stp_role", &existing.STPRole, d.STPRole)
+			update(existing, "source_vlans", &existing.SourceVLANs, d.SourceVLANs)
+			update(existing, "target_vlans", &existing.TargetVLANs, d.TargetVLANs)
+		} else {
+			update(existing, "source_vlans", &existing.SourceVLANs, d.TargetVLANs)
+			update(existing, "target_vlans", &existing.TargetVLANs, d.SourceVLANs)
+			// Seen from the far end: only fill in what we didn't know.
+			if d.SourceInterface != "unknown" && existing.TargetInterface == "unknown" {
+				update(existing, "target_interface", &existing.TargetInterface, d.SourceInterface)
+			}
+		}
+		existing.Stale = false
+	}
+
+	for i := range merged.Links {
+		if !merged.Links[i].Manual && !seen[i] {
+			merged.Links[i].Stale = true
+		}
+	}
+
+	merged.VLANs = mergeVLANs(current.VLANs, discovered.VLANs)
+
+	return merged
+}
+
+// mergeVLANs adds newly discovered VLANs to the inventory. Names already in
+// the inventory are kept so they can be edited by hand.
+func mergeVLANs(current, discovered []VLAN) []VLAN {
+	merged := append([]VLAN{}, current...)
+	index := make(map[int]int)
+	for i, v := range merged {
+		index[v.ID] = i
+	}
+	for _, v := range discovered {
+		i, ok := index[v.ID]
+		if !ok {
+			index[v.ID] = len(merged)
+			merged = append(merged, v)
+			continue
+		}
+		if merged[i].Name == "" {
+			merged[i].Name = v.Name
+		}
+	}
+	if len(merged) == 0 {
+		return nil
+	}
+	return merged
+}
+
+// UpdateTopologyFile writes topo back to path while keeping the comments and
+// ordering of the existing file. Links already present are updated in place,
+// manual links are left alone and new links are appended at the end. VLANs
+// missing from the inventory are appended likewise.
+func UpdateTopologyFile(path string, topo *Topology) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return SaveTopology(path, topo)
+		}
+		return err
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return SaveTopology(path, topo)
+	}
+	root := doc.Content[0]
+
+	linksNode := mappingValue(root, "links")
+	if linksNode == nil || linksNode.Kind != yaml.SequenceNode {
+		linksNode = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		setMappingValue(root, "links", linksNode)
+	}
+
+	nodes := make(map[linkKey]*yaml.Node)
+	for _, item := range linksNode.Content {
+		var l Link
+		if err := item.Decode(&l); err != nil {
+			continue
+		}
+		nodes[keyOf(l)] = item
+	}
+
+	knownKeys := yamlKeys(reflect.TypeOf(Link{}))
+	for _, l := range topo.Links {
+		var encoded yaml.Node
+		if err := encoded.Encode(l); err != nil {
+			return err
+		}
+
+		item, ok := nodes[keyOf(l)]
+		if !ok {
+			linksNode.Content = append(linksNode.Content, &encoded)
+			continue
+		}
+		if l.Manual {
+			continue
+		}
+
+		for i := 0; i+1 < len(encoded.Content); i += 2 {
+			setMappingValue(item, encoded.Content[i].Value, encoded.Content[i+1])
+		}
+		// Fields dropped by omitempty must be removed from the file as well.
+		for key := range knownKeys {
+			if mappingValue(&encoded, key) == nil {
+				removeMappingKey(item, key)
+			}
+		}
+	}
+
+	if len(topo.VLANs) > 0 {
+		vlansNode := mappingValue(root, "vlans")
+		if vlansNode == nil || vlansNode.Kind != yaml.SequenceNode {
+			vlansNode = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+			setMappingValue(root, "vlans", vlansNode)
+		}
+		existing := make(map[int]*yaml.Node)
+		for _, item := range vlansNode.Content {
+			var v VLAN
+			if err := item.Decode(&v); err == nil {
+				existing[v.ID] = item
+			}
+		}
+		for _, v := range topo.VLANs {
+			var encoded yaml.Node
+			if err := encoded.Encode(v); err != nil {
+				return err
+			}
+			item, ok := existing[v.ID]
+			if !ok {
+				vlansNode.Content = append(vlansNode.Content, &encoded)
+			} else if v.Name != "" && mappingValue(item, "name") == nil {
+				setMappingValue(item, "name", mappingValue(&encoded, "name"))
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return err
+	}
+	if err := encoder.Close(); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0644)
+}
+
+func mappingValue(m *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// setMappingValue replaces the value for key, keeping any comments attached to
+// the old value, or appends the pair if the key is missing.
+func setMappingValue(m *yaml.Node, key string, value *yaml.Node) {
+	if existing := mappingValue(m, key); existing != nil {
+		head, line, foot := existing.HeadComment, existing.LineComment, existing.FootComment
+		*existing = *value
+		existing.HeadComment, existing.LineComment, existing.FootComment = head, line, foot
+		return
+	}
+	m.Content = append(m.Content,
+		&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key},
+		value,
+	)
+}
+
+func removeMappingKey(m *yaml.Node, key string) {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			m.Content = append(m.Content[:i], m.Content[i+2:]...)
+			return
+		}
+	}
+}
+
+// yamlKeys returns the yaml field names declared on a struct type.
+func yamlKeys(t reflect.Type) map[string]bool {
+	keys := make(map[string]bool)
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0]
+		if name != "" && name != "-" {
+			keys[name] = true
+		}
+	}
+	return keys
+}
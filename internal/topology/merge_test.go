@@ -0,0 +1,104 @@
+package topology
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMergeTopology(t *testing.T) {
+	current := &Topology{Links: []Link{
+		{SourceDevice: "core", SourceInterface: "ether1", TargetDevice: "switch", TargetInterface: "unknown", Type: LinkTypeEthernet},
+		{SourceDevice: "core", SourceInterface: "ether2", TargetDevice: "nas", TargetInterface: "eth0", Type: LinkType10G, Manual: true},
+		{SourceDevice: "core", SourceInterface: "ether3", TargetDevice: "old-ap", TargetInterface: "eth0", Type: LinkTypeEthernet},
+		{SourceDevice: "core", SourceInterface: "ether4", TargetDevice: "ap", TargetInterface: "unknown", Type: LinkTypeWireless, ManualFields: []string{"type"}},
+	}}
+	discovered := &Topology{Links: []Link{
+		{SourceDevice: "core", SourceInterface: "ether1", TargetDevice: "switch", TargetInterface: "port1", Type: LinkType1G},
+		{SourceDevice: "core", SourceInterface: "ether2", TargetDevice: "nas", TargetInterface: "eth1", Type: LinkTypeEthernet},
+		{SourceDevice: "core", SourceInterface: "wlan1", TargetDevice: "laptop", TargetInterface: "unknown", Type: LinkTypeWireless},
+		{SourceDevice: "core", SourceInterface: "ether4", TargetDevice: "ap", TargetInterface: "eth0", Type: LinkType1G},
+	}}
+
+	merged := MergeTopology(current, discovered)
+
+	if len(merged.Links) != 5 {
+		t.Fatalf("Expected 5 links, got %d", len(merged.Links))
+	}
+	if l := merged.Links[0]; l.TargetInterface != "port1" || l.Type != LinkType1G || l.Stale {
+		t.Errorf("Expected discovered link to be updated, got %+v", l)
+	}
+	if l := merged.Links[1]; l.TargetInterface != "eth0" || l.Type != LinkType10G {
+		t.Errorf("Expected manual link to win, got %+v", l)
+	}
+	if l := merged.Links[2]; !l.Stale {
+		t.Errorf("Expected vanished link to be marked stale, got %+v", l)
+	}
+	if l := merged.Links[3]; l.TargetInterface != "eth0" || l.Type != LinkTypeWireless {
+		t.Errorf("Expected manual field to win and the rest to be updated, got %+v", l)
+	}
+	if changes := DiffTopology(current, discovered); len(changes) != 4 || changes[2].Detail != "target interface unknown -> eth0" {
+		t.Errorf("Expected manual field not to be reported as changed, got %+v", changes)
+	}
+	if l := merged.Links[4]; l.TargetDevice != "laptop" {
+		t.Errorf("Expected new link to be appended, got %+v", l)
+	}
+}
+
+func TestUpdateTopologyFilePreservesComments(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "topology.yaml")
+	original := `# Home lab map
+links:
+  # Uplink to the closet switch
+  - source_device: core
+    source_interface: ether1
+    target_device: switch
+    target_interface: unknown
+    type: ethernet
+  - source_device: core
+    source_interface: ether2
+    target_device: nas
+    target_interface: eth0 # bonded later
+    type: 10g
+    manual: true
+`
+	if err := os.WriteFile(path, []byte(original), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	current, err := LoadTopology(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	discovered := &Topology{Links: []Link{
+		{SourceDevice: "core", SourceInterface: "ether1", TargetDevice: "switch", TargetInterface: "port1", Type: LinkType1G},
+		{SourceDevice: "core", SourceInterface: "wlan1", TargetDevice: "laptop", TargetInterface: "unknown", Type: LinkTypeWireless},
+	}}
+
+	if err := UpdateTopologyFile(path, MergeTopology(current, discovered)); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(data)
+	for _, want := range []string{"# Home lab map", "# Uplink to the closet switch", "# bonded later", "target_interface: port1", "target_device: laptop"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+	if strings.Index(out, "ether1") > strings.Index(out, "ether2") || strings.Index(out, "ether2") > strings.Index(out, "wlan1") {
+		t.Errorf("Expected link order to be preserved, got:\n%s", out)
+	}
+
+	reloaded, err := LoadTopology(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reloaded.Links) != 3 || !reloaded.Links[1].Manual || reloaded.Links[1].Stale {
+		t.Errorf("Unexpected reloaded topology: %+v", reloaded.Links)
+	}
+}
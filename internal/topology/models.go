@@ -1,40 +1,111 @@
 package topology
 
 import (
+	"regexp"
 	"strings"
 )
 
 type LinkType string
 
 const (
+	LinkType100G     LinkType = "100g"
+	LinkType40G      LinkType = "40g"
+	LinkType25G      LinkType = "25g"
 	LinkType10G      LinkType = "10g"
-	LinkType1G       LinkType = "1g"
+	LinkType5G       LinkType = "5g"
+	LinkType2500M    LinkType = "2.5g"
+	LinkType1G       LinkType = "1g" // 1G SFP
 	LinkTypeEthernet LinkType = "ethernet"
 	LinkTypeWireless LinkType = "wireless"
+	LinkTypeLAG      LinkType = "lag"
+	LinkTypeVPN      LinkType = "vpn"
+	LinkTypeVirtual  LinkType = "virtual"
 )
 
 type Link struct {
-	SourceDevice    string   `yaml:"source_device"`
-	SourceInterface string   `yaml:"source_interface"`
-	TargetDevice    string   `yaml:"target_device"`
-	TargetInterface string   `yaml:"target_interface"`
-	Type            LinkType `yaml:"type"`
-	Manual          bool     `yaml:"manual,omitempty"` // True if manually added/overridden
+	SourceDevice    string   `yaml:"source_device" json:"source_device"`
+	SourceInterface string   `yaml:"source_interface" json:"source_interface"`
+	TargetDevice    string   `yaml:"target_device" json:"target_device"`
+	TargetInterface string   `yaml:"target_interface" json:"target_interface"`
+	Type            LinkType `yaml:"type" json:"type"`
+	Manual          bool     `yaml:"manual,omitempty" json:"manual,omitempty"`       // True if manually added/overridden
+	Stale           bool     `yaml:"stale,omitempty" json:"stale,omitempty"`         // True if no longer seen by discovery
+	STPState        string   `yaml:"stp_state,omitempty" json:"stp_state,omitempty"` // Spanning-tree state of the source port
+	STPRole         string   `yaml:"stp_role,omitempty" json:"stp_role,omitempty"`
+	// ManualFields lists the fields of a discovered link that were edited by
+	// hand, by YAML key, e.g. [type]. Discovery leaves them alone.
+	ManualFields []string `yaml:"manual_fields,omitempty" json:"manual_fields,omitempty"`
+	// Members lists the physical ports of an aggregated (LAG) link.
+	Members []LinkMember `yaml:"members,omitempty" json:"members,omitempty"`
+	// VLAN membership of each end, as read from the bridge VLAN tables.
+	SourceVLANs *VLANMembership `yaml:"source_vlans,omitempty" json:"source_vlans,omitempty"`
+	TargetVLANs *VLANMembership `yaml:"target_vlans,omitempty" json:"target_vlans,omitempty"`
+	// VLANIssues is computed for the API and never stored.
+	VLANIssues []string `yaml:"-" json:"vlan_issues,omitempty"`
+}
+
+type LinkMember struct {
+	SourceInterface string `yaml:"source_interface" json:"source_interface"`
+	TargetInterface string `yaml:"target_interface" json:"target_interface"`
+}
+
+type NodeRole string
+
+const (
+	NodeRoleRouter NodeRole = "router"
+	NodeRoleSwitch NodeRole = "switch"
+	NodeRoleAP     NodeRole = "ap"
+	NodeRoleServer NodeRole = "server"
+	NodeRoleClient NodeRole = "client"
+)
+
+var nodeRoles = []NodeRole{NodeRoleRouter, NodeRoleSwitch, NodeRoleAP, NodeRoleServer, NodeRoleClient}
+
+// Node describes a device on the map. ID matches the device names used by
+// links; Device names the entry in config.yaml that polls it, if any.
+type Node struct {
+	ID     string   `yaml:"id" json:"id"`
+	Name   string   `yaml:"name,omitempty" json:"name,omitempty"`
+	Role   NodeRole `yaml:"role,omitempty" json:"role,omitempty"`
+	Vendor string   `yaml:"vendor,omitempty" json:"vendor,omitempty"`
+	Site   string   `yaml:"site,omitempty" json:"site,omitempty"`
+	Rack   string   `yaml:"rack,omitempty" json:"rack,omitempty"`
+	Icon   string   `yaml:"icon,omitempty" json:"icon,omitempty"`
+	Device string   `yaml:"device,omitempty" json:"device,omitempty"`
 }
 
 type Topology struct {
-	Links []Link `yaml:"links"`
+	Nodes []Node `yaml:"nodes,omitempty" json:"nodes"`
+	Links []Link `yaml:"links" json:"links"`
+	VLANs []VLAN `yaml:"vlans,omitempty" json:"vlans,omitempty"`
 }
 
+// loopbackName matches lo, lo0 and loopback0 but not names such as lobby-ap.
+var loopbackName = regexp.MustCompile(`^lo(opback)?[0-9]*$`)
+
+// ClassifyLink guesses a link type from the interface name alone. It is the
+// fallback used by Classifier when no speed or ifType data is available.
 func ClassifyLink(ifDescr string) LinkType {
 	lower := strings.ToLower(ifDescr)
 	switch {
+	case strings.Contains(lower, "qsfp28"), strings.Contains(lower, "100g"):
+		return LinkType100G
+	case strings.Contains(lower, "sfp28"), strings.Contains(lower, "25g"):
+		return LinkType25G
 	case strings.Contains(lower, "sfpplus"), strings.Contains(lower, "10g"):
 		return LinkType10G
 	case strings.Contains(lower, "sfp"):
 		return LinkType1G
 	case strings.Contains(lower, "wlan"), strings.Contains(lower, "wifi"):
 		return LinkTypeWireless
+	case strings.HasPrefix(lower, "bond"), strings.HasPrefix(lower, "lag"), strings.HasPrefix(lower, "port-channel"):
+		return LinkTypeLAG
+	case strings.HasPrefix(lower, "wg"), strings.HasPrefix(lower, "wireguard"), strings.HasPrefix(lower, "ovpn"),
+		strings.HasPrefix(lower, "l2tp"), strings.HasPrefix(lower, "gre"), strings.HasPrefix(lower, "eoip"),
+		strings.HasPrefix(lower, "pppoe"), strings.HasPrefix(lower, "tun"):
+		return LinkTypeVPN
+	case strings.HasPrefix(lower, "vlan"), strings.HasPrefix(lower, "bridge"), loopbackName.MatchString(lower):
+		return LinkTypeVirtual
 	default:
 		return LinkTypeEthernet
 	}
@@ -0,0 +1,165 @@
+package topology
+
+import (
+	"log"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/AMathur20/Home_Network/internal/models"
+	"github.com/AMathur20/Home_Network/internal/snmp"
+	"github.com/gosnmp/gosnmp"
+)
+
+const (
+	// lldpRemManAddrIfSubtype, indexed by the remote table index followed by
+	// the address subtype and the length-prefixed address.
+	oidLldpRemManAddrIfSubtype = ".1.0.8802.1.1.2.1.4.2.1.3"
+	// mtxrNeighborIpAddress, indexed like the MNDP identity column.
+	oidMndpNeighborAddress = ".1.3.6.1.4.1.14988.1.1.11.1.2"
+	oidSysName             = ".1.3.6.1.2.1.1.5.0"
+)
+
+// neighbor is a device seen in LLDP or MNDP with a management address.
+type neighbor struct {
+	Name     string
+	Address  string
+	MikroTik bool // Seen via MNDP
+}
+
+// newNeighbors returns the neighbors of dev that aren't known yet and answer
+// SNMP with one of the discovery credential profiles, so they can be crawled
+// in turn. Every neighbor tried is added to known.
+func (c *Crawler) newNeighbors(dev models.DeviceConfig, profiles []models.CredentialProfile, known map[string]bool) []models.DeviceConfig {
+	neighbors, err := neighborAddresses(dev)
+	if err != nil {
+		log.Printf("Error reading neighbor addresses on %s: %v", dev.Name, err)
+		return nil
+	}
+
+	var found []models.DeviceConfig
+	for _, n := range neighbors {
+		if known[n.Name] || known[n.Address] {
+			continue
+		}
+		known[n.Name], known[n.Address] = true, true
+		neighbor, ok := tryProfiles(n, profiles)
+		if !ok {
+			log.Printf("No discovery credentials work for neighbor %s (%s)", n.Name, n.Address)
+			continue
+		}
+		log.Printf("Neighbor %s (%s) answers with credentials %q; add it to devices to poll it", n.Name, n.Address, neighbor.Credentials[0])
+		found = append(found, neighbor)
+	}
+	return found
+}
+
+// tryProfiles returns the neighbor as a device with the first profile it
+// answers SNMP with.
+func tryProfiles(n neighbor, profiles []models.CredentialProfile) (models.DeviceConfig, bool) {
+	for _, p := range profiles {
+		dev := models.DeviceConfig{
+			Name:        n.Name,
+			Host:        n.Address,
+			Type:        models.DeviceTypeGeneric,
+			Credentials: []string{p.Name},
+			SNMP:        p.SNMP,
+			Auth:        p.Auth,
+		}
+		if n.MikroTik {
+			dev.Type = models.DeviceTypeMikroTik
+		}
+		params, err := snmp.Params(dev.Host, dev.SNMP)
+		if err != nil {
+			continue
+		}
+		// A wrong community or user only shows as a timeout, so don't retry.
+		params.Retries = 0
+		if err := params.Connect(); err != nil {
+			continue
+		}
+		result, err := params.Get([]string{oidSysName})
+		params.Conn.Close()
+		if err == nil && result.Error == gosnmp.NoError && len(result.Variables) > 0 {
+			return dev, true
+		}
+	}
+	return models.DeviceConfig{}, false
+}
+
+// neighborAddresses reads the IPv4 management addresses LLDP neighbors
+// advertise and, on MikroTik, the addresses in the MNDP table.
+func neighborAddresses(dev models.DeviceConfig) ([]neighbor, error) {
+	params, err := snmp.Dial(dev)
+	if err != nil {
+		return nil, err
+	}
+	defer params.Conn.Close()
+
+	// Remote table index (timeMark.localPort.remIndex) -> system name
+	names := make(map[string]string)
+	err = params.BulkWalk(oidLldpRemSysName, func(pdu gosnmp.SnmpPDU) error {
+		names[strings.TrimPrefix(pdu.Name, oidLldpRemSysName+".")] = models.PduToString(pdu.Value)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var neighbors []neighbor
+	err = params.BulkWalk(oidLldpRemManAddrIfSubtype, func(pdu gosnmp.SnmpPDU) error {
+		index, addr, ok := parseManAddr(strings.TrimPrefix(pdu.Name, oidLldpRemManAddrIfSubtype+"."))
+		if ok {
+			neighbors = append(neighbors, neighbor{Name: firstNonEmpty(names[index], addr), Address: addr})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if dev.Type == models.DeviceTypeMikroTik {
+		identities := make(map[string]string)
+		params.BulkWalk(oidMndpNeighborIdentity, func(pdu gosnmp.SnmpPDU) error {
+			identities[strings.TrimPrefix(pdu.Name, oidMndpNeighborIdentity+".")] = models.PduToString(pdu.Value)
+			return nil
+		})
+		params.BulkWalk(oidMndpNeighborAddress, func(pdu gosnmp.SnmpPDU) error {
+			addr, _ := pdu.Value.(string)
+			if net.ParseIP(addr).To4() == nil || addr == "0.0.0.0" {
+				return nil
+			}
+			index := strings.TrimPrefix(pdu.Name, oidMndpNeighborAddress+".")
+			neighbors = append(neighbors, neighbor{Name: firstNonEmpty(identities[index], addr), Address: addr, MikroTik: true})
+			return nil
+		})
+	}
+	return neighbors, nil
+}
+
+// parseManAddr splits an lldpRemManAddrTable index into the remote table
+// index and the address, if it is IPv4: timeMark.localPort.remIndex.1.4.a.b.c.d
+func parseManAddr(suffix string) (index, addr string, ok bool) {
+	parts := strings.Split(suffix, ".")
+	if len(parts) != 9 || parts[3] != "1" || parts[4] != "4" {
+		return "", "", false
+	}
+	octets := make([]string, 4)
+	for i, p := range parts[5:] {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 || n > 255 {
+			return "", "", false
+		}
+		octets[i] = strconv.Itoa(n)
+	}
+	return strings.Join(parts[:3], "."), strings.Join(octets, "."), true
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,18 @@
+package topology
+
+import "testing"
+
+func TestParseManAddr(t *testing.T) {
+	index, addr, ok := parseManAddr("0.3.1.1.4.192.168.88.2")
+	if !ok || index != "0.3.1" || addr != "192.168.88.2" {
+		t.Errorf("unexpected result %q, %q, %v", index, addr, ok)
+	}
+
+	// IPv6 (subtype 2) and MAC-only entries are skipped.
+	if _, _, ok := parseManAddr("0.3.1.2.16.254.128.0.0.0.0.0.0.2.12.66.255.254.1.2.3"); ok {
+		t.Error("expected an IPv6 address to be skipped")
+	}
+	if _, _, ok := parseManAddr("0.3.1.1.4.192.168.88"); ok {
+		t.Error("expected a truncated index to be rejected")
+	}
+}
@@ -0,0 +1,71 @@
+package topology
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/AMathur20/Home_Network/internal/config"
+)
+
+// check reports entries the map can't draw: links without both endpoints,
+// nodes without a unique ID and nodes with an unknown role.
+func check(topo *Topology) *config.Errors {
+	errs := &config.Errors{}
+	ids := make(map[string]bool)
+	for i, n := range topo.Nodes {
+		path := fmt.Sprintf("nodes[%d].id", i)
+		switch {
+		case n.ID == "":
+			errs.Add(path, "is required")
+		case ids[n.ID]:
+			errs.Add(path, "duplicate node id %q", n.ID)
+		}
+		ids[n.ID] = true
+		if n.Role != "" && !slices.Contains(nodeRoles, n.Role) {
+			roles := make([]string, len(nodeRoles))
+			for j, role := range nodeRoles {
+				roles[j] = string(role)
+			}
+			errs.Add(fmt.Sprintf("nodes[%d].role", i), "unknown role %q (expected one of %s)", n.Role, strings.Join(roles, ", "))
+		}
+	}
+	for i, l := range topo.Links {
+		path := fmt.Sprintf("links[%d]", i)
+		if l.SourceDevice == "" {
+			errs.Add(path+".source_device", "is required")
+		}
+		if l.TargetDevice == "" {
+			errs.Add(path+".target_device", "is required")
+		}
+		for j, field := range l.ManualFields {
+			if !slices.Contains(overridableFields, field) {
+				errs.Add(fmt.Sprintf("%s.manual_fields[%d]", path, j), "unknown field %q (expected one of %s)", field, strings.Join(overridableFields, ", "))
+			}
+		}
+	}
+	return errs
+}
+
+// Reload loads and validates the topology file for a hot reload or the
+// validate command. Unlike LoadTopology, unknown keys are rejected and a
+// missing file is an error, so deleting or replacing the file never swaps in
+// an empty topology.
+func Reload(path string) (*Topology, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	var topo Topology
+	root, err := config.Decode(path, &topo)
+	if err != nil {
+		return nil, err
+	}
+	errs := check(&topo)
+	errs.File = path
+	errs.Locate(root)
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+	return &topo, nil
+}
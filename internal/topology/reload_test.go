@@ -0,0 +1,42 @@
+package topology
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "topology.yaml")
+	if _, err := Reload(path); err == nil {
+		t.Error("expected a missing file to be rejected")
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		valid   bool
+	}{
+		{"valid", "nodes:\n  - id: router\n    role: router\nlinks:\n  - source_device: router\n    target_device: switch\n", true},
+		{"empty", "", false},
+		{"bad yaml", "links: [\n", false},
+		{"duplicate node", "nodes:\n  - id: router\n  - id: router\nlinks: []\n", false},
+		{"unknown role", "nodes:\n  - id: router\n    role: firewall\nlinks: []\n", false},
+		{"missing endpoint", "links:\n  - source_device: router\n", false},
+		{"unknown manual field", "links:\n  - source_device: router\n    target_device: switch\n    manual_fields: [speed]\n", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := Reload(path)
+			if tt.valid && err != nil {
+				t.Errorf("expected valid topology, got %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package topology
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/AMathur20/Home_Network/internal/models"
+	"github.com/AMathur20/Home_Network/internal/storage"
+)
+
+var ErrNoPendingChanges = errors.New("no pending topology changes")
+
+// Rediscovery periodically re-runs the crawler and diffs the result against
+// topology.yaml. Changes are either applied straight away or held until they
+// are approved through the API.
+type Rediscovery struct {
+	path      string
+	crawler   *Crawler
+	storage   *storage.DuckDBStorage
+	interval  time.Duration
+	autoApply bool
+
+	mu      sync.Mutex
+	pending *Topology
+	// The pending events of the pending result, without timestamps
+	pendingEvents map[models.TopologyEvent]bool
+}
+
+func NewRediscovery(path string, crawler *Crawler, cfg models.DiscoveryConfig, s *storage.DuckDBStorage) *Rediscovery {
+	return &Rediscovery{
+		path:      path,
+		crawler:   crawler,
+		storage:   s,
+		interval:  time.Duration(cfg.Interval) * time.Second,
+		autoApply: cfg.AutoApply,
+	}
+}
+
+func (r *Rediscovery) Start() {
+	if r.interval <= 0 {
+		return
+	}
+	log.Printf("Scheduled rediscovery every %s (auto-apply: %v)", r.interval, r.autoApply)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := r.RunOnce(); err != nil {
+			log.Printf("Scheduled rediscovery failed: %v", err)
+		}
+	}
+}
+
+func (r *Rediscovery) RunOnce() error {
+	discovered, err := r.crawler.Discover()
+	if err != nil {
+		return err
+	}
+
+	// Trap-triggered runs, the schedule and Approve may overlap; the diff and
+	// any write must see the same file.
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current, err := LoadTopology(r.path)
+	if err != nil {
+		return err
+	}
+	changes := DiffTopology(current, discovered)
+	if len(changes) == 0 {
+		log.Println("Rediscovery found no topology changes")
+		// The network is back to what the file says; nothing is left to approve.
+		r.supersedePending(nil)
+		return nil
+	}
+	log.Printf("Rediscovery found %d topology changes", len(changes))
+
+	now := time.Now()
+	if r.autoApply {
+		if err := UpdateTopologyFile(r.path, MergeTopology(current, discovered)); err != nil {
+			return err
+		}
+		for _, c := range changes {
+			r.saveEvent(changeToEvent(c, now, models.TopologyEventApplied))
+		}
+		r.supersedePending(nil)
+		return nil
+	}
+
+	// Changes that are still pending keep their event; pending changes that
+	// weren't found again are superseded.
+	events := make(map[models.TopologyEvent]bool, len(changes))
+	for _, c := range changes {
+		events[changeToEvent(c, time.Time{}, models.TopologyEventPending)] = true
+	}
+	previous := r.pendingEvents
+	r.supersedePending(events)
+	for _, c := range changes {
+		if e := changeToEvent(c, time.Time{}, models.TopologyEventPending); !previous[e] {
+			e.Timestamp = now
+			r.saveEvent(e)
+		}
+	}
+	r.pending = discovered
+	r.pendingEvents = events
+	return nil
+}
+
+// supersedePending marks the pending events that aren't in keep superseded and
+// drops the pending result. The caller must hold r.mu.
+func (r *Rediscovery) supersedePending(keep map[models.TopologyEvent]bool) {
+	for e := range r.pendingEvents {
+		if !keep[e] {
+			if err := r.storage.SetTopologyEventStatus(e, models.TopologyEventSuperseded); err != nil {
+				log.Printf("Error superseding pending topology event: %v", err)
+			}
+		}
+	}
+	r.pending, r.pendingEvents = nil, nil
+}
+
+func (r *Rediscovery) saveEvent(e models.TopologyEvent) {
+	if err := r.storage.SaveTopologyEvent(e); err != nil {
+		log.Printf("Error saving topology event: %v", err)
+	}
+}
+
+// HasPending reports whether a discovery result is waiting for approval.
+func (r *Rediscovery) HasPending() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.pending != nil
+}
+
+// Approve merges the pending discovery result into topology.yaml.
+func (r *Rediscovery) Approve() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.pending == nil {
+		return ErrNoPendingChanges
+	}
+	current, err := LoadTopology(r.path)
+	if err != nil {
+		return err
+	}
+	if err := UpdateTopologyFile(r.path, MergeTopology(current, r.pending)); err != nil {
+		return err
+	}
+	r.pending, r.pendingEvents = nil, nil
+	return r.storage.UpdateTopologyEventStatus(models.TopologyEventPending, models.TopologyEventApplied)
+}
+
+// Reject discards the pending discovery result.
+func (r *Rediscovery) Reject() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.pending == nil {
+		return ErrNoPendingChanges
+	}
+	r.pending, r.pendingEvents = nil, nil
+	return r.storage.UpdateTopologyEventStatus(models.TopologyEventPending, models.TopologyEventRejected)
+}
+
+func changeToEvent(c Change, ts time.Time, status string) models.TopologyEvent {
+	return models.TopologyEvent{
+		Timestamp:       ts,
+		Kind:            string(c.Kind),
+		SourceDevice:    c.Link.SourceDevice,
+		SourceInterface: c.Link.SourceInterface,
+		TargetDevice:    c.Link.TargetDevice,
+		TargetInterface: c.Link.TargetInterface,
+		LinkType:        string(c.Link.Type),
+		Detail:          c.Detail,
+		Status:          status,
+	}
+}
@@ -0,0 +1,72 @@
+package topology
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/AMathur20/Home_Network/internal/models"
+	"github.com/AMathur20/Home_Network/internal/storage"
+)
+
+func TestRediscoveryPending(t *testing.T) {
+	dir := t.TempDir()
+	s, err := storage.NewDuckDBStorage(filepath.Join(dir, "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	path := filepath.Join(dir, "topology.yaml")
+	write := func(content string) {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("links:\n  - source_device: core\n    target_device: switch\n  - source_device: core\n    target_device: ap\n")
+
+	// With no devices to crawl, every link is reported as removed.
+	r := NewRediscovery(path, NewCrawler(nil, nil), models.DiscoveryConfig{}, s)
+	count := func(status string) int {
+		events, err := s.GetTopologyEvents(status, 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(events)
+	}
+
+	for range 2 {
+		if err := r.RunOnce(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := count(models.TopologyEventPending); n != 2 {
+		t.Errorf("Expected pending changes to be recorded once, got %d pending events", n)
+	}
+
+	write("links:\n  - source_device: core\n    target_device: switch\n")
+	if err := r.RunOnce(); err != nil {
+		t.Fatal(err)
+	}
+	if n := count(models.TopologyEventPending); n != 1 {
+		t.Errorf("Expected 1 pending event, got %d", n)
+	}
+	if n := count(models.TopologyEventSuperseded); n != 1 {
+		t.Errorf("Expected the change no longer found to be superseded, got %d", n)
+	}
+
+	// The file now matches the network: nothing is left to approve.
+	write("links: []\n")
+	if err := r.RunOnce(); err != nil {
+		t.Fatal(err)
+	}
+	if r.HasPending() {
+		t.Error("Expected no pending result once discovery matches the file")
+	}
+	if n := count(models.TopologyEventPending); n != 0 {
+		t.Errorf("Expected no pending events, got %d", n)
+	}
+	if n := count(models.TopologyEventSuperseded); n != 2 {
+		t.Errorf("Expected every pending event to be superseded, got %d", n)
+	}
+}
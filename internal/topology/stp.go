@@ -0,0 +1,149 @@
+package topology
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/AMathur20/Home_Network/internal/models"
+	"github.com/AMathur20/Home_Network/internal/snmp"
+	"github.com/gosnmp/gosnmp"
+)
+
+const (
+	// BRIDGE-MIB
+	oidDot1dBaseBridgeAddress       = ".1.3.6.1.2.1.17.1.1.0"
+	oidDot1dStpTopChanges           = ".1.3.6.1.2.1.17.2.4.0"
+	oidDot1dStpDesignatedRoot       = ".1.3.6.1.2.1.17.2.5.0"
+	oidDot1dStpRootPort             = ".1.3.6.1.2.1.17.2.7.0"
+	oidDot1dStpPortState            = ".1.3.6.1.2.1.17.2.15.1.3"
+	oidDot1dStpPortDesignatedBridge = ".1.3.6.1.2.1.17.2.15.1.8"
+	// RSTP-MIB
+	oidDot1dStpVersion = ".1.3.6.1.2.1.17.2.16.0"
+)
+
+const (
+	STPStateForwarding = "forwarding"
+	STPStateBlocking   = "blocking"
+	STPStateDiscarding = "discarding"
+
+	STPRoleRoot       = "root"
+	STPRoleDesignated = "designated"
+	STPRoleAlternate  = "alternate"
+	STPRoleBackup     = "backup"
+	STPRoleDisabled   = "disabled"
+)
+
+type STPPort struct {
+	State string
+	Role  string
+}
+
+// STPInfo is the spanning-tree view of one bridge. Ports are keyed by ifIndex.
+type STPInfo struct {
+	Bridge models.BridgeStatus
+	Ports  map[int]STPPort
+}
+
+// ReadSTP reads bridge and per-port spanning-tree state over SNMP. It returns
+// nil without error when the device does not run STP.
+func ReadSTP(params *gosnmp.GoSNMP) (*STPInfo, error) {
+	result, err := params.Get([]string{
+		oidDot1dBaseBridgeAddress,
+		oidDot1dStpDesignatedRoot,
+		oidDot1dStpRootPort,
+		oidDot1dStpTopChanges,
+		oidDot1dStpVersion,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	info := &STPInfo{Ports: make(map[int]STPPort)}
+	var baseAddress string
+	var version int
+	for _, v := range result.Variables {
+		if v.Type == gosnmp.NoSuchObject || v.Type == gosnmp.NoSuchInstance {
+			continue
+		}
+		switch v.Name {
+		case oidDot1dBaseBridgeAddress:
+			baseAddress = snmp.FormatMAC(v.Value)
+			info.Bridge.BridgeAddress = baseAddress
+		case oidDot1dStpDesignatedRoot:
+			info.Bridge.DesignatedRoot = formatBridgeID(v.Value)
+		case oidDot1dStpRootPort:
+			info.Bridge.RootPort = models.PduToInt(v.Value)
+		case oidDot1dStpTopChanges:
+			info.Bridge.TopChanges = models.PduToUint64(v.Value)
+		case oidDot1dStpVersion:
+			version = models.PduToInt(v.Value)
+		}
+	}
+	if info.Bridge.DesignatedRoot == "" {
+		return nil, nil
+	}
+	rstp := version >= 2
+
+	// Map dot1dBasePort to ifIndex
+	ifIndexes := snmp.BridgePorts(params)
+
+	designated := make(map[int]string)
+	params.BulkWalk(oidDot1dStpPortDesignatedBridge, func(pdu gosnmp.SnmpPDU) error {
+		port := 0
+		fmt.Sscanf(pdu.Name, oidDot1dStpPortDesignatedBridge+".%d", &port)
+		designated[port] = formatBridgeID(pdu.Value)
+		return nil
+	})
+
+	err = params.BulkWalk(oidDot1dStpPortState, func(pdu gosnmp.SnmpPDU) error {
+		port := 0
+		fmt.Sscanf(pdu.Name, oidDot1dStpPortState+".%d", &port)
+		ifIndex, ok := ifIndexes[port]
+		if !ok {
+			return nil
+		}
+
+		state, role := classifySTPPort(models.PduToInt(pdu.Value), rstp)
+		switch {
+		case port == info.Bridge.RootPort:
+			role = STPRoleRoot
+		case role == STPRoleAlternate && baseAddress != "" && strings.HasSuffix(designated[port], strings.ReplaceAll(baseAddress, ":", "")):
+			// Blocked by another port of this same bridge
+			role = STPRoleBackup
+		}
+		info.Ports[ifIndex] = STPPort{State: state, Role: role}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return info, nil
+}
+
+// classifySTPPort maps a dot1dStpPortState value to a state and a default role.
+// RSTP bridges report discarding ports through the legacy blocking value.
+func classifySTPPort(state int, rstp bool) (string, string) {
+	switch state {
+	case 5: // forwarding
+		return STPStateForwarding, STPRoleDesignated
+	case 2: // blocking
+		if rstp {
+			return STPStateDiscarding, STPRoleAlternate
+		}
+		return STPStateBlocking, STPRoleAlternate
+	case 1: // disabled
+		return STPStateDiscarding, STPRoleDisabled
+	default: // listening, learning, broken
+		return STPStateDiscarding, STPRoleDesignated
+	}
+}
+
+// formatBridgeID renders an 8 byte BridgeId as "priority.mac", e.g. "8000.aabbccddeeff".
+func formatBridgeID(val interface{}) string {
+	b, ok := val.([]byte)
+	if !ok || len(b) != 8 {
+		return models.PduToString(val)
+	}
+	return fmt.Sprintf("%02x%02x.%x", b[0], b[1], b[2:])
+}
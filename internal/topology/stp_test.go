@@ -0,0 +1,31 @@
+package topology
+
+import "testing"
+
+func TestClassifySTPPort(t *testing.T) {
+	tests := []struct {
+		state     int
+		rstp      bool
+		wantState string
+		wantRole  string
+	}{
+		{5, false, STPStateForwarding, STPRoleDesignated},
+		{2, false, STPStateBlocking, STPRoleAlternate},
+		{2, true, STPStateDiscarding, STPRoleAlternate},
+		{4, true, STPStateDiscarding, STPRoleDesignated},
+		{1, false, STPStateDiscarding, STPRoleDisabled},
+	}
+	for _, tt := range tests {
+		state, role := classifySTPPort(tt.state, tt.rstp)
+		if state != tt.wantState || role != tt.wantRole {
+			t.Errorf("classifySTPPort(%d, %v) = %s/%s, want %s/%s", tt.state, tt.rstp, state, role, tt.wantState, tt.wantRole)
+		}
+	}
+}
+
+func TestFormatBridgeID(t *testing.T) {
+	id := formatBridgeID([]byte{0x80, 0x00, 0xaa, 0xbb, 0xcc, 0xdd, 0xee, 0xff})
+	if id != "8000.aabbccddeeff" {
+		t.Errorf("Unexpected bridge ID %q", id)
+	}
+}
@@ -0,0 +1,374 @@
+package topology
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/AMathur20/Home_Network/internal/models"
+	"github.com/AMathur20/Home_Network/internal/routeros"
+	"github.com/AMathur20/Home_Network/internal/snmp"
+	"github.com/gosnmp/gosnmp"
+)
+
+const (
+	// Q-BRIDGE-MIB
+	oidDot1qVlanStaticName          = ".1.3.6.1.2.1.17.7.1.4.3.1.1"
+	oidDot1qVlanStaticEgressPorts   = ".1.3.6.1.2.1.17.7.1.4.3.1.2"
+	oidDot1qVlanStaticUntaggedPorts = ".1.3.6.1.2.1.17.7.1.4.3.1.4"
+	oidDot1qPvid                    = ".1.3.6.1.2.1.17.7.1.4.5.1.1"
+)
+
+// VLANMembership describes the VLANs configured on one end of a link.
+type VLANMembership struct {
+	PVID     int   `yaml:"pvid,omitempty" json:"pvid,omitempty"`
+	Tagged   []int `yaml:"tagged,omitempty,flow" json:"tagged,omitempty"`
+	Untagged []int `yaml:"untagged,omitempty,flow" json:"untagged,omitempty"`
+}
+
+type VLAN struct {
+	ID   int    `yaml:"id" json:"id"`
+	Name string `yaml:"name,omitempty" json:"name,omitempty"`
+}
+
+// Carries reports whether the port passes traffic for vlan.
+func (m *VLANMembership) Carries(vlan int) bool {
+	if m == nil {
+		return false
+	}
+	return m.PVID == vlan || containsInt(m.Tagged, vlan) || containsInt(m.Untagged, vlan)
+}
+
+func (m *VLANMembership) all() []int {
+	seen := make(map[int]bool)
+	var ids []int
+	for _, list := range [][]int{m.Tagged, m.Untagged, {m.PVID}} {
+		for _, v := range list {
+			if v != 0 && !seen[v] {
+				seen[v] = true
+				ids = append(ids, v)
+			}
+		}
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// CarriesVLAN reports whether a link carries vlan. A side without VLAN data
+// (e.g. an unmanaged neighbor) is assumed to pass whatever the other side sends.
+func (l Link) CarriesVLAN(vlan int) bool {
+	switch {
+	case l.SourceVLANs == nil && l.TargetVLANs == nil:
+		return false
+	case l.SourceVLANs == nil:
+		return l.TargetVLANs.Carries(vlan)
+	case l.TargetVLANs == nil:
+		return l.SourceVLANs.Carries(vlan)
+	default:
+		return l.SourceVLANs.Carries(vlan) || l.TargetVLANs.Carries(vlan)
+	}
+}
+
+// FilterVLAN returns a copy of the topology holding only the links that carry
+// vlan, for the per-VLAN overlay.
+func (t *Topology) FilterVLAN(vlan int) *Topology {
+	filtered := &Topology{Nodes: t.Nodes, VLANs: t.VLANs, Links: make([]Link, 0)}
+	for _, l := range t.Links {
+		if l.CarriesVLAN(vlan) {
+			filtered.Links = append(filtered.Links, l)
+		}
+	}
+	return filtered
+}
+
+// VLANMismatches lists trunk/access mismatches between the two ends of a link:
+// VLANs present on only one side, and VLANs tagged on one side but untagged
+// on the other.
+func (l Link) VLANMismatches() []string {
+	if l.SourceVLANs == nil || l.TargetVLANs == nil {
+		return nil
+	}
+	src, tgt := l.SourceVLANs, l.TargetVLANs
+	srcEnd := l.SourceDevice + "/" + l.SourceInterface
+	tgtEnd := l.TargetDevice + "/" + l.TargetInterface
+
+	var issues []string
+	for _, v := range src.all() {
+		if !tgt.Carries(v) {
+			issues = append(issues, fmt.Sprintf("vlan %d only on %s", v, srcEnd))
+		}
+	}
+	for _, v := range tgt.all() {
+		if !src.Carries(v) {
+			issues = append(issues, fmt.Sprintf("vlan %d only on %s", v, tgtEnd))
+		}
+	}
+	for _, v := range src.Tagged {
+		if containsInt(tgt.Untagged, v) {
+			issues = append(issues, fmt.Sprintf("vlan %d tagged on %s but untagged on %s", v, srcEnd, tgtEnd))
+		}
+	}
+	for _, v := range tgt.Tagged {
+		if containsInt(src.Untagged, v) {
+			issues = append(issues, fmt.Sprintf("vlan %d tagged on %s but untagged on %s", v, tgtEnd, srcEnd))
+		}
+	}
+	return issues
+}
+
+// deviceVLANs is the VLAN configuration read from one device.
+type deviceVLANs struct {
+	ports map[string]*VLANMembership // By interface name
+	names map[int]string
+}
+
+// discoverVlansForDevice reads bridge VLAN membership. The RouterOS API is
+// preferred on MikroTik when credentials are configured.
+func (c *Crawler) discoverVlansForDevice(dev models.DeviceConfig) (*deviceVLANs, error) {
+	if dev.Type == models.DeviceTypeMikroTik && dev.Auth.Username != "" {
+		vlans, err := discoverVlansRouterOS(dev)
+		if err == nil {
+			return vlans, nil
+		}
+		log.Printf("RouterOS API bridge VLAN lookup failed for %s, falling back to SNMP: %v", dev.Name, err)
+	}
+
+	params, err := snmp.Dial(dev)
+	if err != nil {
+		return nil, err
+	}
+	defer params.Conn.Close()
+
+	ifaces, err := walkInterfaces(params)
+	if err != nil {
+		return nil, err
+	}
+
+	// Map dot1dBasePort to interface name
+	portNames := make(map[int]string)
+	for port, ifIndex := range snmp.BridgePorts(params) {
+		portNames[port] = ifaces[ifIndex].Name
+	}
+
+	vlans := &deviceVLANs{ports: make(map[string]*VLANMembership), names: make(map[int]string)}
+	port := func(name string) *VLANMembership {
+		m, ok := vlans.ports[name]
+		if !ok {
+			m = &VLANMembership{}
+			vlans.ports[name] = m
+		}
+		return m
+	}
+
+	params.BulkWalk(oidDot1qVlanStaticName, func(pdu gosnmp.SnmpPDU) error {
+		id := 0
+		fmt.Sscanf(pdu.Name, oidDot1qVlanStaticName+".%d", &id)
+		vlans.names[id] = models.PduToString(pdu.Value)
+		return nil
+	})
+
+	untagged := make(map[int]map[int]bool)
+	params.BulkWalk(oidDot1qVlanStaticUntaggedPorts, func(pdu gosnmp.SnmpPDU) error {
+		id := 0
+		fmt.Sscanf(pdu.Name, oidDot1qVlanStaticUntaggedPorts+".%d", &id)
+		untagged[id] = make(map[int]bool)
+		for _, p := range decodePortList(pdu.Value) {
+			untagged[id][p] = true
+		}
+		return nil
+	})
+
+	params.BulkWalk(oidDot1qVlanStaticEgressPorts, func(pdu gosnmp.SnmpPDU) error {
+		id := 0
+		fmt.Sscanf(pdu.Name, oidDot1qVlanStaticEgressPorts+".%d", &id)
+		if _, ok := vlans.names[id]; !ok {
+			vlans.names[id] = ""
+		}
+		for _, p := range decodePortList(pdu.Value) {
+			name := portNames[p]
+			if name == "" {
+				continue
+			}
+			if untagged[id][p] {
+				port(name).Untagged = append(port(name).Untagged, id)
+			} else {
+				port(name).Tagged = append(port(name).Tagged, id)
+			}
+		}
+		return nil
+	})
+
+	params.BulkWalk(oidDot1qPvid, func(pdu gosnmp.SnmpPDU) error {
+		p := 0
+		fmt.Sscanf(pdu.Name, oidDot1qPvid+".%d", &p)
+		if name := portNames[p]; name != "" {
+			port(name).PVID = models.PduToInt(pdu.Value)
+		}
+		return nil
+	})
+
+	return vlans, nil
+}
+
+func discoverVlansRouterOS(dev models.DeviceConfig) (*deviceVLANs, error) {
+	client, err := routeros.Dial(dev)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	vlans := &deviceVLANs{ports: make(map[string]*VLANMembership), names: make(map[int]string)}
+	port := func(name string) *VLANMembership {
+		m, ok := vlans.ports[name]
+		if !ok {
+			m = &VLANMembership{}
+			vlans.ports[name] = m
+		}
+		return m
+	}
+
+	entries, err := client.Run("/interface/bridge/vlan/print")
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		for _, id := range parseVLANIDs(e["vlan-ids"]) {
+			if _, ok := vlans.names[id]; !ok {
+				vlans.names[id] = ""
+			}
+			for _, name := range routeros.ParseList(e["tagged"]) {
+				port(name).Tagged = append(port(name).Tagged, id)
+			}
+			for _, name := range routeros.ParseList(e["untagged"]) {
+				port(name).Untagged = append(port(name).Untagged, id)
+			}
+		}
+	}
+
+	ports, err := client.Run("/interface/bridge/port/print")
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range ports {
+		if pvid, err := strconv.Atoi(p["pvid"]); err == nil {
+			port(p["interface"]).PVID = pvid
+		}
+	}
+
+	// VLAN interfaces give names to the IDs
+	ifaces, err := client.Run("/interface/vlan/print")
+	if err == nil {
+		for _, i := range ifaces {
+			if id, err := strconv.Atoi(i["vlan-id"]); err == nil {
+				vlans.names[id] = i["name"]
+			}
+		}
+	}
+
+	return vlans, nil
+}
+
+// annotateVlans attaches VLAN membership to both ends of every link and
+// returns the VLAN inventory. A LAG without its own membership inherits that
+// of its first member port.
+func annotateVlans(links []Link, vlans map[string]*deviceVLANs) []VLAN {
+	for _, dv := range vlans {
+		for _, m := range dv.ports {
+			sort.Ints(m.Tagged)
+			sort.Ints(m.Untagged)
+		}
+	}
+
+	lookup := func(device, iface string, members []string) *VLANMembership {
+		dv, ok := vlans[device]
+		if !ok {
+			return nil
+		}
+		if m, ok := dv.ports[iface]; ok {
+			return m
+		}
+		for _, member := range members {
+			if m, ok := dv.ports[member]; ok {
+				return m
+			}
+		}
+		return nil
+	}
+
+	for i := range links {
+		l := &links[i]
+		var srcMembers, tgtMembers []string
+		for _, m := range l.Members {
+			srcMembers = append(srcMembers, m.SourceInterface)
+			tgtMembers = append(tgtMembers, m.TargetInterface)
+		}
+		l.SourceVLANs = lookup(l.SourceDevice, l.SourceInterface, srcMembers)
+		l.TargetVLANs = lookup(l.TargetDevice, l.TargetInterface, tgtMembers)
+	}
+
+	names := make(map[int]string)
+	for _, dv := range vlans {
+		for id, name := range dv.names {
+			if names[id] == "" {
+				names[id] = name
+			}
+		}
+	}
+	inventory := make([]VLAN, 0, len(names))
+	for id, name := range names {
+		inventory = append(inventory, VLAN{ID: id, Name: name})
+	}
+	sort.Slice(inventory, func(i, j int) bool { return inventory[i].ID < inventory[j].ID })
+	return inventory
+}
+
+// decodePortList expands a Q-BRIDGE PortList bitmap into port numbers. The
+// most significant bit of the first octet is port 1.
+func decodePortList(val interface{}) []int {
+	b, ok := val.([]byte)
+	if !ok {
+		return nil
+	}
+	var ports []int
+	for i, octet := range b {
+		for bit := 0; bit < 8; bit++ {
+			if octet&(0x80>>bit) != 0 {
+				ports = append(ports, i*8+bit+1)
+			}
+		}
+	}
+	return ports
+}
+
+// parseVLANIDs expands a RouterOS vlan-ids value such as "10,20,100-102".
+func parseVLANIDs(v string) []int {
+	var ids []int
+	for _, part := range routeros.ParseList(v) {
+		if lo, hi, ok := strings.Cut(part, "-"); ok {
+			start, err1 := strconv.Atoi(lo)
+			end, err2 := strconv.Atoi(hi)
+			if err1 != nil || err2 != nil {
+				continue
+			}
+			for id := start; id <= end; id++ {
+				ids = append(ids, id)
+			}
+			continue
+		}
+		if id, err := strconv.Atoi(part); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func containsInt(list []int, v int) bool {
+	for _, x := range list {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,77 @@
+package topology
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDecodePortList(t *testing.T) {
+	ports := decodePortList([]byte{0x81, 0x40})
+	if !reflect.DeepEqual(ports, []int{1, 8, 10}) {
+		t.Errorf("Unexpected ports %v", ports)
+	}
+}
+
+func TestParseVLANIDs(t *testing.T) {
+	ids := parseVLANIDs("10,20,100-102")
+	if !reflect.DeepEqual(ids, []int{10, 20, 100, 101, 102}) {
+		t.Errorf("Unexpected VLAN IDs %v", ids)
+	}
+}
+
+func TestAnnotateVlans(t *testing.T) {
+	links := []Link{
+		{SourceDevice: "core", SourceInterface: "ether1", TargetDevice: "switch", TargetInterface: "port1"},
+		{SourceDevice: "core", SourceInterface: "bond1", TargetDevice: "nas", TargetInterface: "bond0", Type: LinkTypeLAG,
+			Members: []LinkMember{{SourceInterface: "ether3", TargetInterface: "eth0"}}},
+	}
+	vlans := map[string]*deviceVLANs{
+		"core": {
+			ports: map[string]*VLANMembership{
+				"ether1": {PVID: 1, Tagged: []int{30, 10}, Untagged: []int{1}},
+				"ether3": {PVID: 20, Untagged: []int{20}},
+			},
+			names: map[int]string{10: "", 20: "servers", 30: "iot"},
+		},
+		"switch": {
+			ports: map[string]*VLANMembership{"port1": {PVID: 1, Tagged: []int{10}, Untagged: []int{1, 30}}},
+			names: map[int]string{10: "lan"},
+		},
+	}
+
+	inventory := annotateVlans(links, vlans)
+
+	want := []VLAN{{ID: 10, Name: "lan"}, {ID: 20, Name: "servers"}, {ID: 30, Name: "iot"}}
+	if !reflect.DeepEqual(inventory, want) {
+		t.Errorf("Unexpected inventory %+v", inventory)
+	}
+	if l := links[0]; !reflect.DeepEqual(l.SourceVLANs.Tagged, []int{10, 30}) || l.TargetVLANs == nil {
+		t.Errorf("Expected both ends annotated, got %+v / %+v", l.SourceVLANs, l.TargetVLANs)
+	}
+	if l := links[1]; l.SourceVLANs == nil || l.SourceVLANs.PVID != 20 || l.TargetVLANs != nil {
+		t.Errorf("Expected LAG to inherit member port VLANs, got %+v / %+v", l.SourceVLANs, l.TargetVLANs)
+	}
+
+	issues := links[0].VLANMismatches()
+	if len(issues) != 1 || issues[0] != "vlan 30 tagged on core/ether1 but untagged on switch/port1" {
+		t.Errorf("Unexpected mismatches %v", issues)
+	}
+}
+
+func TestFilterVLAN(t *testing.T) {
+	topo := &Topology{Links: []Link{
+		{SourceDevice: "core", SourceInterface: "ether1", TargetDevice: "switch", TargetInterface: "port1",
+			SourceVLANs: &VLANMembership{Tagged: []int{10, 30}}, TargetVLANs: &VLANMembership{Tagged: []int{10}}},
+		{SourceDevice: "core", SourceInterface: "ether2", TargetDevice: "ap", TargetInterface: "eth0",
+			SourceVLANs: &VLANMembership{PVID: 30, Untagged: []int{30}}},
+		{SourceDevice: "core", SourceInterface: "ether3", TargetDevice: "nas", TargetInterface: "eth0"},
+	}}
+
+	filtered := topo.FilterVLAN(30)
+	if len(filtered.Links) != 2 {
+		t.Fatalf("Expected 2 links carrying vlan 30, got %d", len(filtered.Links))
+	}
+	if issues := filtered.Links[0].VLANMismatches(); len(issues) != 1 || issues[0] != "vlan 30 only on core/ether1" {
+		t.Errorf("Unexpected mismatches %v", issues)
+	}
+}
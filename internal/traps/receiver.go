@@ -0,0 +1,286 @@
+// Package traps receives SNMP traps and informs so that link changes and
+// reboots are seen as they happen rather than on the next poll.
+package traps
+
+import (
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AMathur20/Home_Network/internal/models"
+	"github.com/AMathur20/Home_Network/internal/poller"
+	"github.com/AMathur20/Home_Network/internal/snmp"
+	"github.com/AMathur20/Home_Network/internal/storage"
+	"github.com/gosnmp/gosnmp"
+)
+
+const (
+	oidSnmpTrapOID     = ".1.3.6.1.6.3.1.1.4.1.0"
+	oidSnmpTrapAddress = ".1.3.6.1.6.3.18.1.3.0" // Set by trap relays/proxies
+	oidColdStart       = ".1.3.6.1.6.3.1.1.5.1"
+	oidWarmStart       = ".1.3.6.1.6.3.1.1.5.2"
+	oidLinkDown        = ".1.3.6.1.6.3.1.1.5.3"
+	oidLinkUp          = ".1.3.6.1.6.3.1.1.5.4"
+	oidLLDPRemChange   = ".1.0.8802.1.1.2.0.0.1"
+
+	oidIfIndex = ".1.3.6.1.2.1.2.2.1.1"
+	oidIfDescr = ".1.3.6.1.2.1.2.2.1.2"
+	oidIfName  = ".1.3.6.1.2.1.31.1.1.1.1"
+)
+
+// rediscoverDelay coalesces the burst of lldpRemTablesChange notifications
+// that a single cabling change produces on both ends.
+const rediscoverDelay = 30 * time.Second
+
+// Receiver listens for traps from configured devices, stores them as events
+// and triggers a re-poll or rediscovery of the device they came from.
+type Receiver struct {
+	cfg      models.TrapConfig
+	storage  *storage.DuckDBStorage
+	devices  *models.DeviceAddresses // source IP -> device
+	listener *gosnmp.TrapListener
+
+	// Repoll is called after a link or restart trap from a device.
+	Repoll func(dev models.DeviceConfig)
+	// Rediscover is called, debounced, after LLDP remote table changes.
+	Rediscover func()
+
+	mu          sync.Mutex
+	rediscovery *time.Timer
+
+	namesMu sync.Mutex
+	ifNames map[string]map[int]string // device -> ifIndex -> ifName, "" while resolving or if it failed
+}
+
+func NewReceiver(cfg models.TrapConfig, devices []models.DeviceConfig, s *storage.DuckDBStorage) (*Receiver, error) {
+	params := &gosnmp.GoSNMP{Version: gosnmp.Version2c, Community: cfg.Community}
+	if cfg.V3 != nil {
+		usm, flags, err := usmParameters(*cfg.V3)
+		if err != nil {
+			return nil, err
+		}
+		params.Version = gosnmp.Version3
+		params.SecurityModel = gosnmp.UserSecurityModel
+		params.MsgFlags = flags
+		params.SecurityParameters = usm
+	}
+
+	r := &Receiver{
+		cfg:      cfg,
+		storage:  s,
+		devices:  models.NewDeviceAddresses(devices),
+		listener: gosnmp.NewTrapListener(),
+		ifNames:  make(map[string]map[int]string),
+	}
+	r.listener.Params = params
+	r.listener.OnNewTrap = r.handle
+
+	return r, nil
+}
+
+// SetDevices replaces the devices traps are accepted from.
+func (r *Receiver) SetDevices(devices []models.DeviceConfig) {
+	r.devices.Set(devices)
+	r.namesMu.Lock()
+	r.ifNames = make(map[string]map[int]string)
+	r.namesMu.Unlock()
+}
+
+// Start listens until Close is called.
+func (r *Receiver) Start() error {
+	addr := r.cfg.Listen
+	if addr == "" {
+		addr = ":162"
+	}
+	log.Printf("SNMP trap receiver listening on udp %s", addr)
+	return r.listener.Listen(addr)
+}
+
+func (r *Receiver) Close() {
+	r.listener.Close()
+}
+
+func (r *Receiver) handle(packet *gosnmp.SnmpPacket, addr *net.UDPAddr) {
+	if packet.Version != gosnmp.Version3 && r.cfg.Community != "" && packet.Community != r.cfg.Community {
+		log.Printf("Ignoring trap from %s with wrong community", addr.IP)
+		return
+	}
+
+	source := r.source(packet, addr)
+	dev, ok := r.devices.Lookup(source)
+	if !ok {
+		log.Printf("Ignoring trap from unknown host %s", source)
+		return
+	}
+
+	ev, ok := decodeTrap(packet, dev.Name, time.Now(), func(index int) string {
+		if name := r.interfaceName(dev, index); name != "" {
+			return name
+		}
+		return fmt.Sprintf("ifIndex %d", index)
+	})
+	if !ok {
+		return
+	}
+
+	log.Printf("Trap from %s: %s", dev.Name, ev.Message)
+	if err := r.storage.SaveEvent(ev); err != nil {
+		log.Printf("Error saving trap event for %s: %v", dev.Name, err)
+	}
+
+	switch ev.Kind {
+	case models.EventLLDPChange:
+		r.scheduleRediscovery()
+	default:
+		if r.Repoll != nil {
+			go r.Repoll(dev)
+		}
+	}
+}
+
+// interfaceName returns the cached name of an ifIndex. An unknown index is
+// resolved in the background, since this runs on the listener goroutine that
+// also answers informs; until then, and if it fails, "" is returned.
+func (r *Receiver) interfaceName(dev models.DeviceConfig, index int) string {
+	if !dev.PolledBySNMP() {
+		return ""
+	}
+	r.namesMu.Lock()
+	defer r.namesMu.Unlock()
+	names, ok := r.ifNames[dev.Name]
+	if !ok {
+		names = make(map[int]string)
+		r.ifNames[dev.Name] = names
+	}
+	if name, ok := names[index]; ok {
+		return name
+	}
+	names[index] = ""
+	go func() {
+		name, err := poller.NewSNMPPoller(dev).InterfaceName(index)
+		if err != nil {
+			log.Printf("Failed to resolve ifIndex %d on %s: %v", index, dev.Name, err)
+			return
+		}
+		r.namesMu.Lock()
+		defer r.namesMu.Unlock()
+		if names, ok := r.ifNames[dev.Name]; ok {
+			names[index] = name
+		}
+	}()
+	return ""
+}
+
+// source returns the address a trap is attributed to: the sender, or the
+// snmpTrapAddress it carries when relayed. The relayed address is only trusted
+// from a configured device or in an authenticated v3 packet, so that any host
+// can't pass off traps as a device's and trigger repolls.
+func (r *Receiver) source(packet *gosnmp.SnmpPacket, addr *net.UDPAddr) string {
+	sender := addr.IP.String()
+	for _, v := range packet.Variables {
+		if v.Name != oidSnmpTrapAddress {
+			continue
+		}
+		ip, ok := v.Value.(string)
+		if !ok || ip == "" || ip == sender {
+			break
+		}
+		authenticated := packet.Version == gosnmp.Version3 && r.cfg.V3 != nil && packet.MsgFlags&gosnmp.AuthNoPriv != 0
+		if _, relay := r.devices.Lookup(sender); relay || authenticated {
+			return ip
+		}
+		log.Printf("Ignoring snmpTrapAddress %s in trap from unconfigured host %s", ip, sender)
+	}
+	return sender
+}
+
+func (r *Receiver) scheduleRediscovery() {
+	if r.Rediscover == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.rediscovery != nil {
+		r.rediscovery.Stop()
+	}
+	r.rediscovery = time.AfterFunc(rediscoverDelay, r.Rediscover)
+}
+
+// decodeTrap maps a linkUp/linkDown, coldStart/warmStart or
+// lldpRemTablesChange notification to an event. ifName resolves the ifIndex of
+// link traps that don't carry the interface name.
+func decodeTrap(packet *gosnmp.SnmpPacket, device string, now time.Time, ifName func(index int) string) (models.Event, bool) {
+	trapOID := ""
+	if packet.PDUType == gosnmp.Trap && packet.GenericTrap >= 0 && packet.GenericTrap <= 3 {
+		// SNMPv1 generic traps 0-3 map onto snmpTraps.1-4 (RFC 3584).
+		trapOID = fmt.Sprintf(".1.3.6.1.6.3.1.1.5.%d", packet.GenericTrap+1)
+	}
+
+	index := 0
+	name, descr := "", ""
+	for _, v := range packet.Variables {
+		switch {
+		case v.Name == oidSnmpTrapOID:
+			trapOID = "." + strings.TrimPrefix(models.PduToString(v.Value), ".")
+		case strings.HasPrefix(v.Name, oidIfIndex+"."):
+			index = models.PduToInt(v.Value)
+		case strings.HasPrefix(v.Name, oidIfName+"."):
+			name = models.PduToString(v.Value)
+		case strings.HasPrefix(v.Name, oidIfDescr+"."):
+			descr = models.PduToString(v.Value)
+		}
+	}
+
+	ev := models.Event{Timestamp: now, DeviceName: device}
+	switch trapOID {
+	case oidLinkDown, oidLinkUp:
+		ev.Kind, ev.Message = models.EventLinkUp, "link up"
+		if trapOID == oidLinkDown {
+			ev.Kind, ev.Message = models.EventLinkDown, "link down"
+		}
+		ev.InterfaceName = firstNonEmpty(name, descr)
+		if ev.InterfaceName == "" && index > 0 {
+			ev.InterfaceName = ifName(index)
+		}
+		if ev.InterfaceName != "" {
+			ev.Message += " on " + ev.InterfaceName
+		}
+	case oidColdStart:
+		ev.Kind, ev.Message = models.EventColdStart, "device restarted (cold start)"
+	case oidWarmStart:
+		ev.Kind, ev.Message = models.EventWarmStart, "agent reinitialized (warm start)"
+	case oidLLDPRemChange:
+		ev.Kind, ev.Message = models.EventLLDPChange, "LLDP neighbor table changed"
+	default:
+		return models.Event{}, false
+	}
+	return ev, true
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func usmParameters(cfg models.TrapV3Config) (*gosnmp.UsmSecurityParameters, gosnmp.SnmpV3MsgFlags, error) {
+	usm, flags, err := snmp.USM(cfg.USMUser)
+	if err != nil {
+		return nil, 0, fmt.Errorf("traps.v3: %v", err)
+	}
+	if cfg.EngineID != "" {
+		id, err := hex.DecodeString(strings.TrimPrefix(cfg.EngineID, "0x"))
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid trap engine_id %q: %v", cfg.EngineID, err)
+		}
+		usm.AuthoritativeEngineID = string(id)
+	}
+	return usm, flags, nil
+}
@@ -0,0 +1,93 @@
+package traps
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/AMathur20/Home_Network/internal/models"
+	"github.com/gosnmp/gosnmp"
+)
+
+func TestDecodeTrap(t *testing.T) {
+	now := time.Now()
+	resolve := func(index int) string {
+		if index == 5 {
+			return "ether5"
+		}
+		return ""
+	}
+	v2 := func(trapOID string, vars ...gosnmp.SnmpPDU) *gosnmp.SnmpPacket {
+		return &gosnmp.SnmpPacket{
+			Version: gosnmp.Version2c,
+			PDUType: gosnmp.SNMPv2Trap,
+			Variables: append([]gosnmp.SnmpPDU{
+				{Name: ".1.3.6.1.2.1.1.3.0", Type: gosnmp.TimeTicks, Value: uint32(1234)},
+				{Name: oidSnmpTrapOID, Type: gosnmp.ObjectIdentifier, Value: trapOID},
+			}, vars...),
+		}
+	}
+
+	tests := []struct {
+		name   string
+		packet *gosnmp.SnmpPacket
+		kind   string
+		iface  string
+	}{
+		{"linkDown with ifName", v2(oidLinkDown,
+			gosnmp.SnmpPDU{Name: oidIfIndex + ".3", Type: gosnmp.Integer, Value: 3},
+			gosnmp.SnmpPDU{Name: oidIfName + ".3", Type: gosnmp.OctetString, Value: []byte("sfp-sfpplus1")},
+		), models.EventLinkDown, "sfp-sfpplus1"},
+		{"linkUp resolved by ifIndex", v2(oidLinkUp,
+			gosnmp.SnmpPDU{Name: oidIfIndex + ".5", Type: gosnmp.Integer, Value: 5},
+		), models.EventLinkUp, "ether5"},
+		{"coldStart", v2(oidColdStart), models.EventColdStart, ""},
+		{"lldpRemTablesChange", v2(oidLLDPRemChange), models.EventLLDPChange, ""},
+		{"v1 warmStart", &gosnmp.SnmpPacket{
+			Version: gosnmp.Version1, PDUType: gosnmp.Trap,
+			SnmpTrap: gosnmp.SnmpTrap{GenericTrap: 1},
+		}, models.EventWarmStart, ""},
+	}
+	for _, tt := range tests {
+		ev, ok := decodeTrap(tt.packet, "core-router", now, resolve)
+		if !ok || ev.Kind != tt.kind || ev.InterfaceName != tt.iface || ev.DeviceName != "core-router" {
+			t.Errorf("%s: unexpected event %+v (ok=%v)", tt.name, ev, ok)
+		}
+	}
+
+	if _, ok := decodeTrap(v2(".1.3.6.1.4.1.14988.1.1.0.1"), "core-router", now, resolve); ok {
+		t.Error("Expected vendor trap to be ignored")
+	}
+}
+
+func TestTrapSource(t *testing.T) {
+	devices := []models.DeviceConfig{
+		{Name: "core", Host: "192.168.1.1"},
+		{Name: "switch", Host: "192.168.1.2"},
+	}
+	r, err := NewReceiver(models.TrapConfig{}, devices, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	relayed := func(version gosnmp.SnmpVersion, flags gosnmp.SnmpV3MsgFlags) *gosnmp.SnmpPacket {
+		return &gosnmp.SnmpPacket{Version: version, MsgFlags: flags, Variables: []gosnmp.SnmpPDU{
+			{Name: oidSnmpTrapAddress, Type: gosnmp.IPAddress, Value: "192.168.1.2"},
+		}}
+	}
+	from := func(ip string) *net.UDPAddr { return &net.UDPAddr{IP: net.ParseIP(ip)} }
+
+	if got := r.source(relayed(gosnmp.Version2c, 0), from("192.168.1.1")); got != "192.168.1.2" {
+		t.Errorf("Expected a configured device to relay traps, got %s", got)
+	}
+	if got := r.source(relayed(gosnmp.Version2c, 0), from("192.168.1.50")); got != "192.168.1.50" {
+		t.Errorf("Expected snmpTrapAddress from an unknown host to be ignored, got %s", got)
+	}
+
+	r.cfg.V3 = &models.TrapV3Config{}
+	if got := r.source(relayed(gosnmp.Version3, gosnmp.AuthPriv), from("192.168.1.50")); got != "192.168.1.2" {
+		t.Errorf("Expected an authenticated v3 trap to be attributed to its snmpTrapAddress, got %s", got)
+	}
+	if got := r.source(relayed(gosnmp.Version3, gosnmp.NoAuthNoPriv), from("192.168.1.50")); got != "192.168.1.50" {
+		t.Errorf("Expected an unauthenticated v3 trap's snmpTrapAddress to be ignored, got %s", got)
+	}
+}
@@ -0,0 +1,98 @@
+// Package watch reports changes to config files, including saves by editors
+// that write a temporary file and rename it over the original.
+package watch
+
+import (
+	"log"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/AMathur20/Home_Network/internal/models"
+	"github.com/fsnotify/fsnotify"
+)
+
+// Delay is how long File waits for a burst of events to settle.
+const Delay = 500 * time.Millisecond
+
+// File calls callback after path is created, written, renamed or removed. It
+// watches the parent directory rather than the file, so it keeps working when
+// an editor replaces the file and starts working when a missing file is
+// created. Events within delay of each other are coalesced into one call, and
+// calls never overlap.
+func File(path string, delay time.Duration, callback func()) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	target := filepath.Clean(path)
+	if err := watcher.Add(filepath.Dir(target)); err != nil {
+		watcher.Close()
+		return err
+	}
+	log.Printf("Watching %s", path)
+
+	go func() {
+		defer watcher.Close()
+		timer := time.NewTimer(delay)
+		timer.Stop()
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(event.Name) != target || !event.Has(fsnotify.Create|fsnotify.Write|fsnotify.Rename|fsnotify.Remove) {
+					continue
+				}
+				timer.Reset(delay)
+			case <-timer.C:
+				log.Printf("%s changed, reloading...", path)
+				callback()
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Printf("Error watching %s: %v", path, err)
+			}
+		}
+	}()
+	return nil
+}
+
+// Status records the outcome of the latest reload of a file for the API.
+type Status struct {
+	mu     sync.Mutex
+	status models.ReloadStatus
+}
+
+func NewStatus(path string) *Status {
+	now := time.Now()
+	return &Status{status: models.ReloadStatus{File: path, LoadedAt: now, CheckedAt: now}}
+}
+
+// Loaded records a successful reload.
+func (s *Status) Loaded(restartRequired []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	s.status.LoadedAt = now
+	s.status.CheckedAt = now
+	s.status.Error = ""
+	s.status.RestartRequired = restartRequired
+}
+
+// Failed records a rejected reload. The previously loaded file stays in
+// effect.
+func (s *Status) Failed(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status.CheckedAt = time.Now()
+	s.status.Error = err.Error()
+}
+
+func (s *Status) Get() models.ReloadStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.status
+}
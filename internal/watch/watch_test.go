@@ -0,0 +1,87 @@
+package watch
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "topology.yaml")
+	changes := make(chan struct{}, 10)
+	if err := File(path, 50*time.Millisecond, func() { changes <- struct{}{} }); err != nil {
+		t.Fatal(err)
+	}
+
+	expect := func(step string) {
+		t.Helper()
+		select {
+		case <-changes:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("%s: no change reported", step)
+		}
+		select {
+		case <-changes:
+			t.Fatalf("%s: burst was not coalesced", step)
+		case <-time.After(150 * time.Millisecond):
+		}
+	}
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The file doesn't exist when the watch starts.
+	write("topology.yaml", "links: []\n")
+	expect("create")
+
+	// Truncate and write in several steps, like a plain save.
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("links:\n")
+	f.WriteString("  []\n")
+	f.Close()
+	expect("write")
+
+	// Write a temporary file and rename it over the original, like vim.
+	write(".topology.yaml.swp", "links: []\nnodes: []\n")
+	if err := os.Rename(filepath.Join(dir, ".topology.yaml.swp"), path); err != nil {
+		t.Fatal(err)
+	}
+	expect("rename")
+
+	// The watch survives the replacement.
+	write("topology.yaml", "links: []\n")
+	expect("write after rename")
+
+	// Other files in the directory are ignored.
+	write("config.yaml", "devices: []\n")
+	select {
+	case <-changes:
+		t.Fatal("change to another file was reported")
+	case <-time.After(150 * time.Millisecond):
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	expect("remove")
+}
+
+func TestStatus(t *testing.T) {
+	s := NewStatus("config.yaml")
+	s.Failed(os.ErrNotExist)
+	if got := s.Get(); got.Error == "" || got.CheckedAt.Before(got.LoadedAt) {
+		t.Errorf("expected a rejected reload: %+v", got)
+	}
+	s.Loaded([]string{"syslog"})
+	if got := s.Get(); got.Error != "" || len(got.RestartRequired) != 1 {
+		t.Errorf("expected a successful reload: %+v", got)
+	}
+}
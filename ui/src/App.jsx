@@ -1,4 +1,5 @@
-import React, { useState } from 'react';
+import React, { useEffect, useState } from 'react';
+import axios from 'axios';
 import { Activity, LayoutGrid, Map as MapIcon, Settings } from 'lucide-react';
 import NetworkMap from './components/NetworkMap';
 import PriorityList from './components/PriorityList';
@@ -17,21 +18,19 @@ const App = () => {
         ]);
         
         const mData = metricsRes.data || [];
-        const tData = topoRes.data || { links: [] };
-        
-        const devices = new Set();
-        mData.forEach(m => devices.add(m.DeviceName));
+        const tData = topoRes.data || { nodes: [], edges: [] };
         
         let totalBps = 0;
         let offline = 0;
         mData.forEach(m => {
           if (m.Status === 'down') offline++;
-          totalBps += m.InSpeed + m.OutSpeed;
+          // LAG members are already counted in their parent interface
+          if (!m.Parent) totalBps += m.InSpeed + m.OutSpeed;
         });
 
         setStats({
-          devices: devices.size || tData.links.length ? 3 : 0, // Fallback if no metrics yet
-          links: tData.links.length,
+          devices: tData.nodes.length,
+          links: tData.edges.length,
           speed: `${(totalBps / 1000000).toFixed(1)} Mbps`,
           offline
         });
@@ -133,6 +132,11 @@ function GridView({ metrics }) {
                 <span className={`px-2 py-0.5 rounded-full text-[10px] font-bold uppercase ${m.Status === 'up' ? 'bg-noc-emerald/10 text-noc-emerald' : 'bg-red-500/10 text-red-500'}`}>
                   {m.Status}
                 </span>
+                {(m.STPState === 'blocking' || m.STPState === 'discarding') && (
+                  <span className="ml-2 px-2 py-0.5 rounded-full text-[10px] font-bold uppercase bg-white/10 text-white/60">
+                    STP {m.STPState}
+                  </span>
+                )}
               </td>
               <td className="p-4 text-right font-mono">{(m.InSpeed / 1000000).toFixed(2)} Mbps</td>
               <td className="p-4 text-right font-mono">{(m.OutSpeed / 1000000).toFixed(2)} Mbps</td>
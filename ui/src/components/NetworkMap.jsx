@@ -2,37 +2,62 @@ import React, { useEffect, useState, useRef } from 'react';
 import ForceGraph2D from 'react-force-graph-2d';
 import axios from 'axios';
 
+const FAST_LINKS = ['10g', '25g', '40g', '100g'];
+const STP_BLOCKED = ['blocking', 'discarding'];
+
 const NetworkMap = () => {
     const [data, setData] = useState({ nodes: [], links: [] });
+    const [vlans, setVlans] = useState([]);
+    const [vlan, setVlan] = useState('');
     const fgRef = useRef();
 
     useEffect(() => {
         const fetchData = async () => {
             try {
-                const response = await axios.get('/api/topology');
-                const topo = response.data;
-
-                const nodes = [];
-                const links = [];
-                const deviceSet = new Set();
+                const [topoRes, metricsRes, apsRes] = await Promise.all([
+                    axios.get('/api/topology', { params: vlan ? { vlan } : {} }),
+                    axios.get('/api/metrics/live'),
+                    axios.get('/api/wireless/aps')
+                ]);
+                const topo = topoRes.data;
 
-                topo.links.forEach(l => {
-                    deviceSet.add(l.source_device);
-                    deviceSet.add(l.target_device);
-                    links.push({
-                        source: l.source_device,
-                        target: l.target_device,
-                        type: l.type,
-                        source_int: l.source_interface,
-                        target_int: l.target_interface
-                    });
+                const status = {};
+                (metricsRes.data || []).forEach(m => {
+                    status[`${m.DeviceName}/${m.InterfaceName}`] = m.Status;
                 });
 
-                deviceSet.forEach(d => {
-                    nodes.push({ id: d, name: d });
+                const clients = {};
+                (apsRes.data || []).forEach(ap => {
+                    clients[ap.DeviceName] = (clients[ap.DeviceName] || 0) + ap.Clients;
                 });
 
+                const nodes = topo.nodes.map(n => ({
+                    id: n.id,
+                    name: clients[n.device || n.id] !== undefined ? `${n.name} (${clients[n.device || n.id]} clients)` : n.name,
+                    role: n.role,
+                    icon: n.icon,
+                    polled: n.polled,
+                    powered_by: n.powered_by,
+                    status: n.status,
+                    rtt: n.rtt
+                }));
+                const links = topo.edges.map(l => ({
+                    source: l.source_device,
+                    target: l.target_device,
+                    type: l.type,
+                    stale: l.stale,
+                    stp_state: l.stp_state,
+                    vlan_issues: l.vlan_issues || [],
+                    source_int: l.source_interface,
+                    target_int: l.target_interface,
+                    members: (l.members || []).map(m => ({
+                        ...m,
+                        status: status[`${l.source_device}/${m.source_interface}`] || 'unknown'
+                    }))
+                }));
+
                 setData({ nodes, links });
+                setVlans(topo.vlans || []);
             } catch (err) {
                 console.error("Failed to fetch topology:", err);
             }
@@ -41,33 +66,55 @@ const NetworkMap = () => {
         fetchData();
         const interval = setInterval(fetchData, 30000); // Refresh every 30s
         return () => clearInterval(interval);
-    }, []);
+    }, [vlan]);
 
     return (
         <div className="w-full h-[calc(100vh-80px)] bg-noc-bg rounded-xl overflow-hidden border border-white/5 relative">
             <ForceGraph2D
                 ref={fgRef}
                 graphData={data}
-                nodeLabel="name"
-                nodeColor={() => '#ffffff'}
+                nodeLabel={(node) => {
+                    const lines = [node.name];
+                    if (node.status) lines.push(node.status === 'up' ? `up, ${(node.rtt || 0).toFixed(1)} ms` : node.status);
+                    if (node.powered_by) lines.push(`PoE: ${node.powered_by}`);
+                    return lines.join('<br/>');
+                }}
+                nodeColor={(node) => (node.status === 'down' ? '#ef4444' : node.polled ? '#ffffff' : '#ffffff66')}
                 nodeRelSize={6}
+                linkLabel={(link) => {
+                    const stp = link.stp_state ? ` (STP ${link.stp_state})` : '';
+                    const members = link.members.map(m => `${m.source_interface} ↔ ${m.target_interface}: ${m.status}`);
+                    return [`${link.source_int} ↔ ${link.target_int}${stp}`, ...members, ...link.vlan_issues].join('<br/>');
+                }}
                 linkColor={(link) => {
-                    if (link.type === '10g') return '#00f5ff';
+                    if (STP_BLOCKED.includes(link.stp_state)) return '#4b5563'; // Blocked by STP
+                    if (link.vlan_issues.length) return '#ef4444'; // Trunk/access mismatch
+                    if (link.type === 'lag' && link.members.some(m => m.status !== 'up')) return '#f97316'; // Degraded LAG
+                    if (FAST_LINKS.includes(link.type)) return '#00f5ff';
+                    if (link.type === '2.5g' || link.type === '5g') return '#34d399'; // Multi-gig
                     if (link.type === '1g') return '#10b981'; // SFP Emerald
                     if (link.type === 'ethernet') return '#065f46'; // Forest Green
                     if (link.type === 'wireless') return '#facc15'; // Yellow
+                    if (link.type === 'lag') return '#a78bfa'; // Violet
+                    if (link.type === 'vpn') return '#f472b6'; // Pink
                     return '#666';
                 }}
-                linkWidth={(link) => (link.type === '10g' ? 3 : 1.5)}
-                linkLineDash={(link) => (link.type === 'wireless' ? [2, 2] : null)}
-                linkDirectionalParticles={(link) => (link.type === '10g' || link.type === '1g' ? 2 : 0)}
-                linkDirectionalParticleSpeed={(link) => (link.type === '10g' ? 0.01 : 0.005)}
+                linkWidth={(link) => {
+                    if (link.type === 'lag') return 2 + link.members.length;
+                    return FAST_LINKS.includes(link.type) ? 3 : 1.5;
+                }}
+                linkLineDash={(link) => (STP_BLOCKED.includes(link.stp_state) || link.type === 'wireless' || link.type === 'vpn' || link.type === 'virtual' ? [2, 2] : null)}
+                linkDirectionalParticles={(link) => (!STP_BLOCKED.includes(link.stp_state) && FAST_LINKS.includes(link.type) || link.type === '1g' ? 2 : 0)}
+                linkDirectionalParticleSpeed={(link) => (FAST_LINKS.includes(link.type) ? 0.01 : 0.005)}
                 linkDirectionalParticleWidth={2}
                 backgroundColor="rgba(0,0,0,0)"
             />
             <div className="absolute top-4 left-4 flex flex-col gap-2 bg-black/40 backdrop-blur-sm p-3 rounded-lg border border-white/5">
                 <div className="flex items-center gap-2 text-[10px] uppercase font-bold tracking-widest text-white/60">
-                    <div className="w-2 h-2 bg-noc-cyan rounded-full pulse-glow" /> 10G SFP+
+                    <div className="w-2 h-2 bg-noc-cyan rounded-full pulse-glow" /> 10G+ SFP+
+                </div>
+                <div className="flex items-center gap-2 text-[10px] uppercase font-bold tracking-widest text-white/60">
+                    <div className="w-2 h-2 bg-[#34d399] rounded-full" /> 2.5G / 5G
                 </div>
                 <div className="flex items-center gap-2 text-[10px] uppercase font-bold tracking-widest text-white/60">
                     <div className="w-2 h-2 bg-noc-emerald rounded-full" /> 1G SFP
@@ -78,6 +125,28 @@ const NetworkMap = () => {
                 <div className="flex items-center gap-2 text-[10px] uppercase font-bold tracking-widest text-white/60">
                     <div className="w-2 h-2 border border-dashed border-noc-yellow rounded-full" /> Wireless
                 </div>
+                <div className="flex items-center gap-2 text-[10px] uppercase font-bold tracking-widest text-white/60">
+                    <div className="w-2 h-2 bg-[#a78bfa] rounded-full" /> LAG
+                </div>
+                <div className="flex items-center gap-2 text-[10px] uppercase font-bold tracking-widest text-white/60">
+                    <div className="w-2 h-2 border border-dashed border-[#f472b6] rounded-full" /> VPN
+                </div>
+                <div className="flex items-center gap-2 text-[10px] uppercase font-bold tracking-widest text-white/60">
+                    <div className="w-2 h-2 border border-dashed border-[#4b5563] rounded-full" /> STP Blocked
+                </div>
+                <div className="flex items-center gap-2 text-[10px] uppercase font-bold tracking-widest text-white/60">
+                    <div className="w-2 h-2 bg-[#ef4444] rounded-full" /> VLAN Mismatch
+                </div>
+                <select
+                    value={vlan}
+                    onChange={(e) => setVlan(e.target.value)}
+                    className="mt-1 bg-black/60 border border-white/10 rounded text-[10px] uppercase font-bold tracking-widest text-white/80 p-1"
+                >
+                    <option value="">All VLANs</option>
+                    {vlans.map(v => (
+                        <option key={v.id} value={v.id}>{v.id}{v.name ? ` · ${v.name}` : ''}</option>
+                    ))}
+                </select>
             </div>
         </div>
     );
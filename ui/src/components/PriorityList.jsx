@@ -21,7 +21,8 @@ const PriorityList = () => {
                         interface: m.InterfaceName,
                         speed: `${((m.InSpeed + m.OutSpeed) / 1000000).toFixed(1)} Mbps`,
                         direction: m.InSpeed > m.OutSpeed ? 'down' : 'up',
-                        status: m.Status
+                        status: m.Status,
+                        stale: m.Stale
                     }));
 
                 setMetrics(processed);
@@ -40,7 +41,7 @@ const PriorityList = () => {
             <h2 className="text-xs font-bold text-white/40 uppercase tracking-widest px-1">Active Links</h2>
             <div className="flex flex-col gap-2">
                 {metrics.map((m) => (
-                    <div key={m.id} className={`bg-noc-card border border-white/5 p-3 rounded-xl flex items-center justify-between transition-all hover:border-white/10 ${m.status === 'down' ? 'border-red-500/20 bg-red-500/5' : ''}`}>
+                    <div key={m.id} className={`bg-noc-card border border-white/5 p-3 rounded-xl flex items-center justify-between transition-all hover:border-white/10 ${m.status === 'down' ? 'border-red-500/20 bg-red-500/5' : ''} ${m.stale ? 'opacity-50' : ''}`}>
                         <div className="flex items-center gap-3">
                             <div className={`p-2 rounded-lg ${m.status === 'down' ? 'bg-red-500/20 text-red-500' : 'bg-white/5 text-white/60'}`}>
                                 {m.status === 'down' ? <AlertCircle className="w-4 h-4" /> : m.direction === 'up' ? <ArrowUpRight className="w-4 h-4 text-noc-cyan" /> : <ArrowDownRight className="w-4 h-4 text-noc-emerald" />}
@@ -52,7 +53,7 @@ const PriorityList = () => {
                         </div>
                         <div className="text-right">
                             <p className={`text-sm font-mono font-bold ${m.status === 'down' ? 'text-red-500' : 'text-noc-cyan'}`}>
-                                {m.status === 'down' ? 'OFFLINE' : m.speed}
+                                {m.status === 'down' ? 'OFFLINE' : m.stale ? 'STALE' : m.speed}
                             </p>
                         </div>
                     </div>